@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	goflag "flag"
+	"fmt"
+	"os"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	networkv1 "github.com/openshift/api/network/v1"
+	"github.com/openshift/cluster-machine-approver/pkg/controller"
+	flag "github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+)
+
+// checkCommand is the subcommand printing the authorization decision for a single CSR
+const checkCommand = "check"
+
+// runCheck prints the authorization decision for a single CSR, using the same
+// config as the controller, without approving it. It returns the exit code.
+func runCheck(args []string) int {
+	var cliConfig string
+	var csrName string
+	var apiGroupVersions []string
+	var managementKubeConfigPath string
+	var workloadKubeConfigPath string
+	var machineNamespaces []string
+	var timeout time.Duration
+
+	flagSet := flag.NewFlagSet("cluster-machine-approver check", flag.ExitOnError)
+
+	klogFlags := goflag.NewFlagSet("klog", goflag.ExitOnError)
+	klog.InitFlags(klogFlags)
+	flagSet.AddGoFlagSet(klogFlags)
+
+	flagSet.StringVar(&csrName, "csr", "", "name of the CSR to check")
+	flagSet.StringVar(&cliConfig, "config", "", "CLI config")
+	flagSet.StringSliceVar(&apiGroupVersions, "api-group-version", []string{mapiGroup}, "API group and version for machines in format '<group>/<version' or just '<group>'. This option can be given multiple times.")
+	flagSet.StringVar(&managementKubeConfigPath, "management-cluster-kubeconfig", "", "management kubeconfig path,")
+	flagSet.StringVar(&workloadKubeConfigPath, "workload-cluster-kubeconfig", "", "workload kubeconfig path")
+	flagSet.StringSliceVar(&machineNamespaces, "machine-namespace", nil, "restrict machines to specific namespaces, given as a comma-separated list or multiple times")
+	flagSet.DurationVar(&timeout, "timeout", time.Minute, "maximum duration of the check")
+
+	flagSet.Parse(args)
+
+	if csrName == "" {
+		fmt.Fprintln(os.Stderr, "--csr is required")
+		return 2
+	}
+
+	var parsedAPIGroupVersions []schema.GroupVersion
+	for _, apiGroupVersion := range apiGroupVersions {
+		parsedAPIGroupVersion, err := parseGroupVersion(apiGroupVersion)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid API Group Version value: %s\n", apiGroupVersion)
+			return 2
+		}
+		if err := validateAPIGroup(parsedAPIGroupVersion.Group); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		parsedAPIGroupVersions = append(parsedAPIGroupVersions, parsedAPIGroupVersion)
+	}
+
+	managementConfig, workloadConfig, err := createClientConfigs(managementKubeConfigPath, workloadKubeConfigPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't set client configs: %v\n", err)
+		return 1
+	}
+
+	// The egress IP fallback needs the cluster network and host subnets
+	if err := configv1.Install(scheme.Scheme); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := networkv1.Install(scheme.Scheme); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	managementClient, workloadClient, err := createClients(managementConfig, workloadConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	approver := &controller.CertificateApprover{
+		ManagementClient:  *managementClient,
+		MachineRestCfg:    managementConfig,
+		MachineNamespaces: parseMachineNamespaces(machineNamespaces),
+		WorkloadClient:    *workloadClient,
+		NodeRestCfg:       workloadConfig,
+		Config:            controller.LoadConfig(cliConfig),
+		APIGroupVersions:  parsedAPIGroupVersions,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result, err := approver.Check(ctx, csrName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to check CSR %s: %v\n", csrName, err)
+		return 1
+	}
+
+	if result.Authorized {
+		fmt.Printf("CSR %s would be approved, authorized by %s\n", csrName, result.Method)
+		return 0
+	}
+
+	fmt.Printf("CSR %s would not be approved: %s\n", csrName, result.Reason)
+	return 1
+}
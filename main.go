@@ -18,21 +18,30 @@ package main
 
 import (
 	"context"
+	"errors"
 	goflag "flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	configv1 "github.com/openshift/api/config/v1"
 	networkv1 "github.com/openshift/api/network/v1"
 	"github.com/openshift/cluster-machine-approver/pkg/controller"
+	"github.com/openshift/cluster-machine-approver/pkg/machinehandler"
 	"github.com/openshift/cluster-machine-approver/pkg/metrics"
 	flag "github.com/spf13/pflag"
 	certificatesv1 "k8s.io/api/certificates/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
@@ -50,14 +59,31 @@ const (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == checkCommand {
+		os.Exit(runCheck(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == resolveNodeCommand {
+		os.Exit(runResolveNode(os.Args[2:]))
+	}
+
 	var cliConfig string
 	var apiGroupVersions []string
 	var apiGroup string // deprecated
 	var managementKubeConfigPath string
-	var machineNamespace string
+	var machineNamespaces []string
 	var workloadKubeConfigPath string
 	var disableStatusController bool
 	var maxConcurrentReconciles int
+	var startupDelay time.Duration
+	var startupObservePeriod time.Duration
+	var annotateReconcileOutcome bool
+	var machineListTimeout time.Duration
+	var machineAddressWaitTimeout time.Duration
+	var pendingLimitDegradedAfter time.Duration
+	var maxPendingCSRs int
+	var csrLabelSelector string
+	var auditLogPath string
+	var metricsResyncInterval time.Duration
 
 	var leaderElect bool
 	var leaderElectLeaseDuration time.Duration
@@ -75,12 +101,22 @@ func main() {
 	flagSet.AddGoFlagSet(goflag.CommandLine)
 
 	flagSet.StringVar(&cliConfig, "config", "", "CLI config")
-	flagSet.StringSliceVar(&apiGroupVersions, "api-group-version", nil, "API group and version for machines in format '<group>/<version' or just '<group>'. If version is omitted, it will be set to the latest registered version in the cluster. Defaults to 'machine.openshift.io'. This option can be given multiple times.")
+	flagSet.StringSliceVar(&apiGroupVersions, "api-group-version", nil, "API group and version for machines in format '<group>/<version' or just '<group>'. If version is omitted, it will be set to the preferred version served in the cluster, otherwise it must be served. Defaults to 'machine.openshift.io'. This option can be given multiple times.")
 	flagSet.StringVar(&managementKubeConfigPath, "management-cluster-kubeconfig", "", "management kubeconfig path,")
-	flagSet.StringVar(&machineNamespace, "machine-namespace", "", "restrict machine operations to a specific namespace, if not set, all machines will be observed in approval decisions")
+	flagSet.StringSliceVar(&machineNamespaces, "machine-namespace", nil, "restrict machine operations to specific namespaces, given as a comma-separated list or multiple times, if not set, all machines will be observed in approval decisions")
 	flagSet.StringVar(&workloadKubeConfigPath, "workload-cluster-kubeconfig", "", "workload kubeconfig path")
 	flagSet.BoolVar(&disableStatusController, "disable-status-controller", false, "disable status controller that will update the machine-approver clusteroperator status")
 	flagSet.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1, "maximum number concurrent reconciles for the CSR approving controller")
+	flagSet.DurationVar(&startupDelay, "startup-delay", 0, "duration to hold back CSR approvals after leader election and cache sync, CSRs stay pending until it elapses")
+	flagSet.DurationVar(&startupObservePeriod, "startup-observe-period", 0, "duration to defer CSR approvals after leader election and cache sync, CSRs are reconciled and their decisions logged meanwhile, but authorized CSRs stay pending until it elapses")
+	flagSet.BoolVar(&annotateReconcileOutcome, "annotate-reconcile-outcome", false, "annotate CSRs with the outcome and count of their reconciles, for debugging; this costs an extra write per reconcile")
+	flagSet.DurationVar(&machineListTimeout, "machine-list-timeout", 30*time.Second, "maximum duration to wait for machines to be listed when reconciling a CSR, the CSR is requeued on timeout")
+	flagSet.DurationVar(&machineAddressWaitTimeout, "machine-address-wait-timeout", 0, "maximum duration to poll for the addresses of the machine of a node requesting a serving cert, when the machine has none yet, disabled if not set")
+	flagSet.DurationVar(&metricsResyncInterval, "metrics-resync-interval", time.Minute, "interval to refresh the pending CSRs metrics at while no CSR is reconciled, nothing is approved by the refresh")
+	flagSet.StringVar(&auditLogPath, "audit-log-path", "", "path of a file to append a JSON line to for every authorization decision on a CSR, \"-\" for stdout; no audit log is written if not set")
+	flagSet.StringVar(&csrLabelSelector, "csr-label-selector", "", "label selector restricting the CSRs considered for approval and for the pending CSRs limit, all CSRs are considered if not set")
+	flagSet.IntVar(&maxPendingCSRs, "max-pending-csrs", 0, "limit of recently pending CSRs above which approvals are suppressed, overriding the maxPendingCSRs config field; computed from the machine and node counts if not set")
+	flagSet.DurationVar(&pendingLimitDegradedAfter, "pending-limit-degraded-after", defaultPendingLimitDegradedAfter, "duration the pending CSRs limit can be exceeded, suppressing all approvals, before the clusteroperator is reported Degraded")
 
 	flagSet.BoolVar(&leaderElect, "leader-elect", true, "use leader election when starting the manager.")
 	flagSet.DurationVar(&leaderElectLeaseDuration, "leader-elect-lease-duration", 137*time.Second, "the duration that non-leader candidates will wait to force acquire leadership.")
@@ -99,6 +135,11 @@ func main() {
 		klog.Fatal("Cannot set both --apigroup and --api-group-version options together.")
 	}
 
+	csrSelector, err := labels.Parse(csrLabelSelector)
+	if err != nil {
+		klog.Fatalf("Invalid CSR label selector %q: %v", csrLabelSelector, err)
+	}
+
 	var parsedAPIGroupVersions []schema.GroupVersion
 
 	if len(apiGroupVersions) > 0 {
@@ -106,7 +147,7 @@ func main() {
 		for _, apiGroupVersion := range apiGroupVersions {
 			parsedAPIGroupVersion, err := parseGroupVersion(apiGroupVersion)
 			if err != nil {
-				klog.Fatalf("Invalid API Group Version value: %s", apiGroupVersion)
+				klog.Fatalf("Invalid API Group Version value: %v", err)
 			}
 			parsedAPIGroupVersions = append(parsedAPIGroupVersions, parsedAPIGroupVersion)
 		}
@@ -120,7 +161,7 @@ func main() {
 	if apiGroup != "" {
 		// For backward compatibility with --apigroup option
 		parsedAPIGroupVersions = []schema.GroupVersion{
-			{Group: apiGroup},
+			{Group: strings.TrimSpace(apiGroup)},
 		}
 	}
 
@@ -143,16 +184,66 @@ func main() {
 		metricsPort = fmt.Sprintf(":%d", v)
 	}
 
+	// The hostname of a pod is its name.
+	if metrics.LeaderPod, err = os.Hostname(); err != nil {
+		klog.Fatalf("Error getting hostname: %v", err)
+	}
+
 	managementConfig, workloadConfig, err := createClientConfigs(managementKubeConfigPath, workloadKubeConfigPath)
 	if err != nil {
 		klog.Fatalf("Can't set client configs: %v", err)
 	}
 
+	if sameClusterHost(managementConfig, workloadConfig) {
+		klog.Infof("Management and workload clusters are the same cluster at %s", workloadConfig.Host)
+	} else {
+		klog.Infof("Management cluster at %s is separate from the workload cluster at %s", managementConfig.Host, workloadConfig.Host)
+	}
+
+	if err := validateAPIGroupVersions(managementConfig, parsedAPIGroupVersions); err != nil {
+		klog.Fatalf("Invalid API Group Version: %v", err)
+	}
+
+	if err := validateCertificatesAPI(workloadConfig); err != nil {
+		klog.Fatalf("Unsupported certificates API: %v", err)
+	}
+
+	checkPermissions(context.Background(), managementConfig, workloadConfig, parsedAPIGroupVersions)
+
+	approver := &controller.CertificateApprover{
+		MachineNamespaces:         parseMachineNamespaces(machineNamespaces),
+		Config:                    controller.LoadConfig(cliConfig),
+		ConfigPath:                cliConfig,
+		APIGroupVersions:          parsedAPIGroupVersions,
+		ParseAPIGroupVersion:      parseAndValidateGroupVersion,
+		StartupDelay:              startupDelay,
+		StartupObservePeriod:      startupObservePeriod,
+		AnnotateReconcileOutcome:  annotateReconcileOutcome,
+		CSRSelector:               csrSelector,
+		MetricsResyncInterval:     metricsResyncInterval,
+		MachineListTimeout:        machineListTimeout,
+		MachineAddressWaitTimeout: machineAddressWaitTimeout,
+		MaxPendingCSRs:            maxPendingCSRs,
+	}
+
+	if auditLogPath != "" {
+		auditLog, err := controller.NewAuditLog(auditLogPath)
+		if err != nil {
+			klog.Fatalf("Unable to set up the audit log: %v", err)
+		}
+		defer auditLog.Close()
+		approver.AuditLog = auditLog
+	}
+
 	// Create a new Cmd to provide shared dependencies and start components
 	klog.Info("setting up manager")
 	mgr, err := manager.New(workloadConfig, manager.Options{
 		Metrics: server.Options{
 			BindAddress: metricsPort,
+			ExtraHandlers: map[string]http.Handler{
+				controller.DebugConfigPath: approver.ConfigHandler(),
+				metrics.OpenMetricsPath:    metrics.OpenMetricsHandler(),
+			},
 		},
 		LeaderElectionNamespace:       leaderElectResourceNamespace,
 		LeaderElection:                leaderElect,
@@ -215,15 +306,12 @@ func main() {
 
 	// Setup all Controllers
 	klog.Info("setting up controllers")
-	if err = (&controller.CertificateApprover{
-		ManagementClient: uncachedManagementClient,
-		MachineRestCfg:   managementConfig,
-		MachineNamespace: machineNamespace,
-		WorkloadClient:   uncachedWorkloadClient,
-		NodeRestCfg:      workloadConfig,
-		Config:           controller.LoadConfig(cliConfig),
-		APIGroupVersions: parsedAPIGroupVersions,
-	}).SetupWithManager(mgr, ctrl.Options{
+	approver.ManagementClient = uncachedManagementClient
+	approver.MachineRestCfg = managementConfig
+	approver.WorkloadClient = uncachedWorkloadClient
+	approver.NodeRestCfg = workloadConfig
+	approver.Recorder = mgr.GetEventRecorderFor("machine-approver")
+	if err = approver.SetupWithManager(mgr, ctrl.Options{
 		MaxConcurrentReconciles: maxConcurrentReconciles,
 	}); err != nil {
 		klog.Fatalf("unable to create CSR controller: %v", err)
@@ -231,6 +319,7 @@ func main() {
 
 	if !disableStatusController {
 		statusController := NewStatusController(mgr.GetConfig())
+		statusController.pendingLimitDegradedAfter = pendingLimitDegradedAfter
 		go func() {
 			<-mgr.Elected()
 			statusController.Run(1, stop)
@@ -240,11 +329,54 @@ func main() {
 
 	// Start the Cmd
 	klog.Info("starting the cmd")
-	if err := mgr.Start(control.SetupSignalHandler()); err != nil {
+	ctx := control.SetupSignalHandler()
+	trackingCtx, stopTracking := context.WithCancel(ctx)
+	go trackLeadership(trackingCtx, mgr.Elected(), &metrics.Leader)
+	err = mgr.Start(ctx)
+	stopTracking()
+	if err != nil {
+		if leaderElectionLost(ctx, leaderElect, mgr.Elected(), err) {
+			// Exit cleanly so that the pod is restarted and contends for the lease again.
+			klog.Errorf("Leader election lost, exiting: %v", err)
+			klog.Flush()
+			os.Exit(1)
+		}
 		klog.Fatalf("unable to run the manager: %v", err)
 	}
 }
 
+// trackLeadership sets leader to 1 once elected is closed, and back to 0
+// when ctx is done, i.e. when the manager stops or lost the lease. Standbys
+// keep it at 0 until they are elected.
+func trackLeadership(ctx context.Context, elected <-chan struct{}, leader *uint32) {
+	atomic.StoreUint32(leader, 0)
+	select {
+	case <-elected:
+		klog.Info("Elected as leader")
+		atomic.StoreUint32(leader, 1)
+	case <-ctx.Done():
+		return
+	}
+	<-ctx.Done()
+	atomic.StoreUint32(leader, 0)
+}
+
+// leaderElectionLost returns whether the manager stopped because the leader
+// election lease was lost unexpectedly, rather than because the approver was
+// asked to stop. The manager neither exposes a typed error nor a callback for
+// this, but once elected it only stops on its own when the lease is lost.
+func leaderElectionLost(ctx context.Context, leaderElect bool, elected <-chan struct{}, err error) bool {
+	if !leaderElect || err == nil || ctx.Err() != nil {
+		return false
+	}
+	select {
+	case <-elected:
+		return true
+	default:
+		return false
+	}
+}
+
 // createClientConfigs allow users to provide second config using management-kubeconfig, if specified
 // try to build it from provided path. First returned value is management config used for Machines,
 // second is workload config used for Node/CSRs.
@@ -262,6 +394,41 @@ func createClientConfigs(managementKubeConfigPath, workloadKubeConfigPath string
 	return managementConfig, workloadConfig, nil
 }
 
+// sameClusterHost returns whether both client configs resolve to the same API
+// server host, as in the common case where the cluster manages its own
+// machines. Default ports are taken into account, hosts differing only in
+// case are the same.
+func sameClusterHost(a, b *rest.Config) bool {
+	hostA, err := clusterHost(a)
+	if err != nil {
+		return false
+	}
+	hostB, err := clusterHost(b)
+	if err != nil {
+		return false
+	}
+	return hostA == hostB
+}
+
+// clusterHost returns the scheme, host and port of the API server of the
+// client config.
+func clusterHost(cfg *rest.Config) (string, error) {
+	serverURL, _, err := rest.DefaultServerUrlFor(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	port := serverURL.Port()
+	if port == "" {
+		port = "443"
+		if serverURL.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	return serverURL.Scheme + "://" + net.JoinHostPort(strings.ToLower(serverURL.Hostname()), port), nil
+}
+
 // createClients creates 2 API clients, First returned value is management client used for Machines,
 // second is workload client used for Node/CSRs.
 func createClients(managementConfig, workloadConfig *rest.Config) (*client.Client, *client.Client, error) {
@@ -278,28 +445,147 @@ func createClients(managementConfig, workloadConfig *rest.Config) (*client.Clien
 	return &managementClient, &workloadClient, nil
 }
 
+// validateAPIGroup checks that machines can be listed from the API group. API
+// group names are case sensitive, so they are matched exactly.
 func validateAPIGroup(apiGroup string) error {
 	if apiGroup != capiGroup && apiGroup != mapiGroup {
-		return fmt.Errorf("unsupported APIGroup %s, allowed values %s, %s", apiGroup, capiGroup, mapiGroup)
+		return fmt.Errorf("unsupported APIGroup %q, allowed values are %s and %s", apiGroup, capiGroup, mapiGroup)
+	}
+
+	return nil
+}
+
+// validateAPIGroupVersions checks that explicitly specified versions of API groups are served.
+// API groups which are not served are allowed, as the machine API capability may be disabled.
+func validateAPIGroupVersions(cfg *rest.Config, apiGroupVersions []schema.GroupVersion) error {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("create discovery client failed: %v", err)
+	}
+
+	for _, apiGroupVersion := range apiGroupVersions {
+		resolved, err := machinehandler.ResolveAPIGroupVersion(discoveryClient, apiGroupVersion)
+		switch {
+		case errors.Is(err, machinehandler.ErrApiGroupNotFound):
+			klog.Infof("API group %s is not served, no machines will be listed from it", apiGroupVersion.Group)
+		case errors.Is(err, machinehandler.ErrApiVersionNotServed):
+			return err
+		case err != nil:
+			// Discovery may be transiently unavailable, versions are resolved again when listing machines
+			klog.Errorf("Failed to resolve API group version %s: %v", apiGroupVersion, err)
+		default:
+			klog.Infof("Using API group version %s", resolved)
+		}
+	}
+
+	return nil
+}
+
+// serverResourcesForGroupVersion is the part of the discovery client needed
+// to check the certificates API.
+type serverResourcesForGroupVersion interface {
+	ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error)
+}
+
+// validateCertificatesAPI checks that the workload cluster serves the v1
+// certificates API the approver watches and approves CSRs with.
+func validateCertificatesAPI(cfg *rest.Config) error {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("create discovery client failed: %v", err)
 	}
 
+	return checkCertificatesAPI(discoveryClient)
+}
+
+// checkCertificatesAPI reports an error if CSRs and their approval
+// subresource are not served in certificates.k8s.io/v1. The v1beta1 API,
+// removed in Kubernetes 1.22, is not supported.
+func checkCertificatesAPI(discoveryClient serverResourcesForGroupVersion) error {
+	groupVersion := certificatesv1.SchemeGroupVersion.String()
+
+	resources, err := discoveryClient.ServerResourcesForGroupVersion(groupVersion)
+	switch {
+	case apierrors.IsNotFound(err):
+		return fmt.Errorf("%s is not served, the v1beta1 certificates API is not supported", groupVersion)
+	case err != nil:
+		// Discovery may be transiently unavailable, the manager reports the API missing on start
+		klog.Errorf("Failed to get resources served for %s: %v", groupVersion, err)
+		return nil
+	}
+
+	var csrs, approval bool
+	for _, resource := range resources.APIResources {
+		switch resource.Name {
+		case "certificatesigningrequests":
+			csrs = true
+		case "certificatesigningrequests/approval":
+			approval = true
+		}
+	}
+	if !csrs || !approval {
+		return fmt.Errorf("%s does not serve certificatesigningrequests and their approval subresource", groupVersion)
+	}
+
+	klog.Infof("Using certificates API %s", groupVersion)
 	return nil
 }
 
+// parseMachineNamespaces trims the namespaces given with --machine-namespace,
+// dropping empty and repeated ones, as machines of a namespace listed twice
+// would match nodes ambiguously.
+func parseMachineNamespaces(namespaces []string) []string {
+	var parsed []string
+	seen := map[string]bool{}
+	for _, namespace := range namespaces {
+		namespace = strings.TrimSpace(namespace)
+		if namespace == "" || seen[namespace] {
+			continue
+		}
+		seen[namespace] = true
+		parsed = append(parsed, namespace)
+	}
+	return parsed
+}
+
+// parseAndValidateGroupVersion parses an API group version listed in the API
+// group versions ConfigMap, which must be in a supported API group.
+func parseAndValidateGroupVersion(gv string) (schema.GroupVersion, error) {
+	parsed, err := parseGroupVersion(gv)
+	if err != nil {
+		return schema.GroupVersion{}, err
+	}
+	if err := validateAPIGroup(parsed.Group); err != nil {
+		return schema.GroupVersion{}, err
+	}
+	return parsed, nil
+}
+
 // parseGroupVersion turns "group/version" string into a GroupVersion struct. It reports error
-// if it cannot parse the string.
+// if it cannot parse the string. Whitespace around the group and the version is ignored.
 func parseGroupVersion(gv string) (schema.GroupVersion, error) {
-	if (len(gv) == 0) || (gv == "/") {
-		return schema.GroupVersion{}, nil
+	trimmed := strings.TrimSpace(gv)
+	if len(trimmed) == 0 {
+		return schema.GroupVersion{}, fmt.Errorf("empty API group version %q", gv)
+	}
+
+	segments := strings.Split(trimmed, "/")
+	for i := range segments {
+		segments[i] = strings.TrimSpace(segments[i])
 	}
 
-	switch strings.Count(gv, "/") {
-	case 0:
-		return schema.GroupVersion{Group: gv}, nil
+	switch len(segments) {
 	case 1:
-		i := strings.Index(gv, "/")
-		return schema.GroupVersion{Group: gv[:i], Version: gv[i+1:]}, nil
+		return schema.GroupVersion{Group: segments[0]}, nil
+	case 2:
+		if segments[0] == "" {
+			return schema.GroupVersion{}, fmt.Errorf("empty API group in %q", gv)
+		}
+		if segments[1] == "" {
+			return schema.GroupVersion{}, fmt.Errorf("empty API version in %q, omit the '/' to use the preferred version", gv)
+		}
+		return schema.GroupVersion{Group: segments[0], Version: segments[1]}, nil
 	default:
-		return schema.GroupVersion{}, fmt.Errorf("unexpected GroupVersion string: %v", gv)
+		return schema.GroupVersion{}, fmt.Errorf("unexpected GroupVersion string %q, expected '<group>/<version>' or '<group>'", gv)
 	}
 }
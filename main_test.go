@@ -0,0 +1,574 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openshift/cluster-machine-approver/pkg/machinehandler"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+func TestLeaderElectionLost(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	elected := make(chan struct{})
+	close(elected)
+	notElected := make(chan struct{})
+	managerErr := errors.New("leader election lost")
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		leaderElect bool
+		elected     <-chan struct{}
+		err         error
+		want        bool
+	}{
+		{
+			name:        "lease lost",
+			ctx:         context.Background(),
+			leaderElect: true,
+			elected:     elected,
+			err:         managerErr,
+			want:        true,
+		},
+		{
+			name:        "lease released on shutdown",
+			ctx:         cancelled,
+			leaderElect: true,
+			elected:     elected,
+			err:         managerErr,
+			want:        false,
+		},
+		{
+			name:        "failed before being elected",
+			ctx:         context.Background(),
+			leaderElect: true,
+			elected:     notElected,
+			err:         errors.New("failed to wait for caches to sync"),
+			want:        false,
+		},
+		{
+			name:    "failed without leader election",
+			ctx:     context.Background(),
+			elected: elected,
+			err:     errors.New("failed to wait for caches to sync"),
+			want:    false,
+		},
+		{
+			name:        "stopped cleanly",
+			ctx:         cancelled,
+			leaderElect: true,
+			elected:     elected,
+			want:        false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := leaderElectionLost(tt.ctx, tt.leaderElect, tt.elected, tt.err); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// fakeServerResources is a fake discovery client serving the given resources
+// for certificates.k8s.io/v1
+type fakeServerResources struct {
+	resources []metav1.APIResource
+	err       error
+}
+
+func (f fakeServerResources) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if groupVersion != "certificates.k8s.io/v1" {
+		return nil, apierrors.NewNotFound(schema.GroupResource{}, groupVersion)
+	}
+	return &metav1.APIResourceList{GroupVersion: groupVersion, APIResources: f.resources}, nil
+}
+
+func TestTrackLeadership(t *testing.T) {
+	waitForLeader := func(leader *uint32, want uint32) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for atomic.LoadUint32(leader) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("leader = %d, want %d", atomic.LoadUint32(leader), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	t.Run("elected then stopped", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		elected := make(chan struct{})
+		leader := uint32(1)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			trackLeadership(ctx, elected, &leader)
+		}()
+
+		waitForLeader(&leader, 0)
+		close(elected)
+		waitForLeader(&leader, 1)
+
+		cancel()
+		<-done
+		if got := atomic.LoadUint32(&leader); got != 0 {
+			t.Errorf("leader after stop = %d, want 0", got)
+		}
+	})
+
+	t.Run("stopped while standby", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		leader := uint32(0)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			trackLeadership(ctx, make(chan struct{}), &leader)
+		}()
+
+		cancel()
+		<-done
+		if got := atomic.LoadUint32(&leader); got != 0 {
+			t.Errorf("leader after stop = %d, want 0", got)
+		}
+	})
+}
+
+func TestCheckCertificatesAPI(t *testing.T) {
+	tests := []struct {
+		name            string
+		discoveryClient fakeServerResources
+		wantErr         bool
+	}{
+		{
+			name: "v1 served",
+			discoveryClient: fakeServerResources{resources: []metav1.APIResource{
+				{Name: "certificatesigningrequests"},
+				{Name: "certificatesigningrequests/approval"},
+				{Name: "certificatesigningrequests/status"},
+			}},
+		},
+		{
+			name: "v1 not served",
+			discoveryClient: fakeServerResources{
+				err: apierrors.NewNotFound(schema.GroupResource{}, "certificates.k8s.io/v1"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "approval subresource not served",
+			discoveryClient: fakeServerResources{resources: []metav1.APIResource{
+				{Name: "certificatesigningrequests"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "discovery unavailable",
+			discoveryClient: fakeServerResources{
+				err: apierrors.NewServiceUnavailable("discovery unavailable"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCertificatesAPI(tt.discoveryClient)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseGroupVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		gv      string
+		want    schema.GroupVersion
+		wantErr string
+	}{
+		{
+			name: "group",
+			gv:   "machine.openshift.io",
+			want: schema.GroupVersion{Group: "machine.openshift.io"},
+		},
+		{
+			name: "group and version",
+			gv:   "cluster.x-k8s.io/v1beta1",
+			want: schema.GroupVersion{Group: "cluster.x-k8s.io", Version: "v1beta1"},
+		},
+		{
+			name: "leading and trailing spaces",
+			gv:   " machine.openshift.io ",
+			want: schema.GroupVersion{Group: "machine.openshift.io"},
+		},
+		{
+			name: "spaces around the separator",
+			gv:   "cluster.x-k8s.io / v1beta1",
+			want: schema.GroupVersion{Group: "cluster.x-k8s.io", Version: "v1beta1"},
+		},
+		{
+			name:    "empty",
+			gv:      " ",
+			wantErr: `empty API group version " "`,
+		},
+		{
+			name:    "empty group",
+			gv:      "/v1beta1",
+			wantErr: `empty API group in "/v1beta1"`,
+		},
+		{
+			name:    "empty version",
+			gv:      "cluster.x-k8s.io/ ",
+			wantErr: `empty API version in "cluster.x-k8s.io/ ", omit the '/' to use the preferred version`,
+		},
+		{
+			name:    "separator only",
+			gv:      "/",
+			wantErr: `empty API group in "/"`,
+		},
+		{
+			name:    "too many segments",
+			gv:      "cluster.x-k8s.io/v1beta1/machines",
+			wantErr: `unexpected GroupVersion string "cluster.x-k8s.io/v1beta1/machines", expected '<group>/<version>' or '<group>'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseGroupVersion(tt.gv)
+			gotErr := ""
+			if err != nil {
+				gotErr = err.Error()
+			}
+			if gotErr != tt.wantErr {
+				t.Errorf("expected error %q, got %q", tt.wantErr, gotErr)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateAPIGroup(t *testing.T) {
+	for _, group := range []string{mapiGroup, capiGroup} {
+		if err := validateAPIGroup(group); err != nil {
+			t.Errorf("expected %s to be allowed, got %v", group, err)
+		}
+	}
+
+	for _, group := range []string{"", "Machine.OpenShift.io", "example.com"} {
+		if err := validateAPIGroup(group); err == nil {
+			t.Errorf("expected %q not to be allowed", group)
+		}
+	}
+}
+
+func TestParseMachineNamespaces(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		namespaces []string
+		want       []string
+	}{
+		{
+			name: "no namespaces",
+		},
+		{
+			name:       "distinct namespaces",
+			namespaces: []string{"a", "b"},
+			want:       []string{"a", "b"},
+		},
+		{
+			name:       "repeated namespace",
+			namespaces: []string{"a", "a"},
+			want:       []string{"a"},
+		},
+		{
+			name:       "whitespace around namespaces",
+			namespaces: []string{"a", " a", "b "},
+			want:       []string{"a", "b"},
+		},
+		{
+			name:       "empty namespaces",
+			namespaces: []string{"", " ", "a"},
+			want:       []string{"a"},
+		},
+		{
+			name:       "only empty namespaces list all namespaces",
+			namespaces: []string{"", " "},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMachineNamespaces(tt.namespaces); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected namespaces %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSameClusterHost(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{
+			name: "same host",
+			a:    "https://api.example.com:6443",
+			b:    "https://api.example.com:6443",
+			want: true,
+		},
+		{
+			name: "default port",
+			a:    "https://api.example.com",
+			b:    "https://api.example.com:443",
+			want: true,
+		},
+		{
+			name: "case and trailing slash",
+			a:    "https://API.example.com:6443/",
+			b:    "https://api.example.com:6443",
+			want: true,
+		},
+		{
+			name: "in-cluster service IP",
+			a:    "https://172.30.0.1:443",
+			b:    "https://172.30.0.1",
+			want: true,
+		},
+		{
+			name: "different hosts",
+			a:    "https://api.management.example.com:6443",
+			b:    "https://api.example.com:6443",
+		},
+		{
+			name: "different ports",
+			a:    "https://api.example.com:6443",
+			b:    "https://api.example.com:443",
+		},
+		{
+			name: "different schemes",
+			a:    "http://api.example.com:6443",
+			b:    "https://api.example.com:6443",
+		},
+		{
+			name: "invalid host",
+			a:    "https://api.example.com:6443/%zz",
+			b:    "https://api.example.com:6443/%zz",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameClusterHost(&rest.Config{Host: tt.a}, &rest.Config{Host: tt.b}); got != tt.want {
+				t.Errorf("expected %s and %s to be the same cluster host: %v, got %v", tt.a, tt.b, tt.want, got)
+			}
+		})
+	}
+}
+
+// fakeAuthorizer allows the permissions described in allowed
+type fakeAuthorizer struct {
+	allowed map[string]bool
+	err     error
+}
+
+func (f fakeAuthorizer) Create(_ context.Context, review *authorizationv1.SelfSubjectAccessReview, _ metav1.CreateOptions) (*authorizationv1.SelfSubjectAccessReview, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	review.Status.Allowed = f.allowed[describePermission(*review.Spec.ResourceAttributes)]
+	return review, nil
+}
+
+func TestMissingPermissions(t *testing.T) {
+	allowAll := map[string]bool{}
+	for _, p := range workloadPermissions {
+		allowAll[describePermission(p)] = true
+	}
+	withoutApproval := map[string]bool{}
+	for permission := range allowAll {
+		withoutApproval[permission] = true
+	}
+	delete(withoutApproval, "update certificatesigningrequests.certificates.k8s.io/approval")
+	delete(withoutApproval, "get configmaps csr-controller-ca in namespace openshift-config-managed")
+	withoutEvents := map[string]bool{}
+	for permission := range allowAll {
+		withoutEvents[permission] = true
+	}
+	delete(withoutEvents, "create events")
+	delete(withoutEvents, "patch events")
+	delete(withoutEvents, "watch configmaps")
+
+	tests := []struct {
+		name        string
+		authorizer  fakeAuthorizer
+		permissions []authorizationv1.ResourceAttributes
+		want        []string
+		wantErr     string
+	}{
+		{
+			name:        "all permissions granted",
+			authorizer:  fakeAuthorizer{allowed: allowAll},
+			permissions: workloadPermissions,
+		},
+		{
+			name:        "approval and kubelet CA missing",
+			authorizer:  fakeAuthorizer{allowed: withoutApproval},
+			permissions: workloadPermissions,
+			want: []string{
+				"update certificatesigningrequests.certificates.k8s.io/approval",
+				"get configmaps csr-controller-ca in namespace openshift-config-managed",
+			},
+		},
+		{
+			name:        "events and ConfigMaps watch missing",
+			authorizer:  fakeAuthorizer{allowed: withoutEvents},
+			permissions: workloadPermissions,
+			want: []string{
+				"watch configmaps",
+				"create events",
+				"patch events",
+			},
+		},
+		{
+			name:       "machines missing",
+			authorizer: fakeAuthorizer{allowed: map[string]bool{"list machines.machine.openshift.io": true}},
+			permissions: managementPermissions([]schema.GroupVersion{
+				{Group: "machine.openshift.io"},
+				{Group: "cluster.x-k8s.io", Version: "v1beta1"},
+			}),
+			want: []string{"list machines.cluster.x-k8s.io"},
+		},
+		{
+			name:        "review failed",
+			authorizer:  fakeAuthorizer{err: errors.New("forbidden")},
+			permissions: workloadPermissions,
+			wantErr:     "failed to review list certificatesigningrequests.certificates.k8s.io: forbidden",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := missingPermissions(context.Background(), tt.authorizer, tt.permissions)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected missing permissions %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPrintResolvedMachines(t *testing.T) {
+	machines := []machinehandler.Machine{
+		{
+			APIVersion: "machine.openshift.io/v1beta1",
+			ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-machine-api", Name: "worker-0"},
+			Status: machinehandler.MachineStatus{
+				NodeRef:   &corev1.ObjectReference{Name: "worker-0"},
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalDNS, Address: "worker-0"}},
+			},
+		},
+		{
+			APIVersion: "cluster.x-k8s.io/v1beta1",
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "worker-1"},
+			Status: machinehandler.MachineStatus{
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalDNS, Address: "worker-1.example.com"}},
+			},
+		},
+		{
+			APIVersion: "machine.openshift.io/v1beta1",
+			ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-machine-api", Name: "worker-2-duplicate"},
+			Status: machinehandler.MachineStatus{
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalDNS, Address: "worker-2"}},
+			},
+		},
+		{
+			APIVersion: "machine.openshift.io/v1beta1",
+			ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-machine-api", Name: "worker-2"},
+			Status: machinehandler.MachineStatus{
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalDNS, Address: "worker-2"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		nodeName     string
+		domainSuffix string
+		want         string
+		wantMatched  bool
+	}{
+		{
+			name:     "matched by node ref and internal DNS",
+			nodeName: "worker-0",
+			want: `Node worker-0, 4 machines listed:
+  by node ref: openshift-machine-api/worker-0 (machine.openshift.io)
+  by internal DNS: openshift-machine-api/worker-0 (machine.openshift.io)
+`,
+			wantMatched: true,
+		},
+		{
+			name:         "matched by internal DNS with domain suffix",
+			nodeName:     "worker-1",
+			domainSuffix: "example.com",
+			want: `Node worker-1, 4 machines listed:
+  by node ref: matching machine not found
+  by internal DNS: default/worker-1 (cluster.x-k8s.io)
+`,
+			wantMatched: true,
+		},
+		{
+			name:     "ambiguous internal DNS",
+			nodeName: "worker-2",
+			want: `Node worker-2, 4 machines listed:
+  by node ref: matching machine not found
+  by internal DNS: multiple matching machines found: openshift-machine-api/worker-2-duplicate, openshift-machine-api/worker-2
+`,
+		},
+		{
+			name:     "not matched",
+			nodeName: "worker-3",
+			want: `Node worker-3, 4 machines listed:
+  by node ref: matching machine not found
+  by internal DNS: matching machine not found
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			matched := printResolvedMachines(&out, machines, tt.nodeName, tt.domainSuffix)
+			if matched != tt.wantMatched {
+				t.Errorf("expected matched %v, got %v", tt.wantMatched, matched)
+			}
+			if out.String() != tt.want {
+				t.Errorf("expected output:\n%s\ngot:\n%s", tt.want, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+// workloadPermissions are required on the workload cluster to approve CSRs.
+var workloadPermissions = []authorizationv1.ResourceAttributes{
+	{Verb: "list", Group: "certificates.k8s.io", Resource: "certificatesigningrequests"},
+	{Verb: "watch", Group: "certificates.k8s.io", Resource: "certificatesigningrequests"},
+	{Verb: "patch", Group: "certificates.k8s.io", Resource: "certificatesigningrequests"},
+	{Verb: "update", Group: "certificates.k8s.io", Resource: "certificatesigningrequests", Subresource: "approval"},
+	{Verb: "approve", Group: "certificates.k8s.io", Resource: "signers", Name: "kubernetes.io/kube-apiserver-client-kubelet"},
+	{Verb: "approve", Group: "certificates.k8s.io", Resource: "signers", Name: "kubernetes.io/kubelet-serving"},
+	{Verb: "get", Resource: "configmaps", Namespace: "openshift-config-managed", Name: "csr-controller-ca"},
+	{Verb: "list", Resource: "configmaps"},
+	{Verb: "watch", Resource: "configmaps"},
+	{Verb: "list", Resource: "nodes"},
+	{Verb: "get", Resource: "nodes"},
+	{Verb: "watch", Resource: "nodes"},
+	{Verb: "create", Resource: "events"},
+	{Verb: "patch", Resource: "events"},
+}
+
+// managementPermissions returns the permissions required on the management
+// cluster to list machines in the given API groups.
+func managementPermissions(apiGroupVersions []schema.GroupVersion) []authorizationv1.ResourceAttributes {
+	var permissions []authorizationv1.ResourceAttributes
+	for _, apiGroupVersion := range apiGroupVersions {
+		permissions = append(permissions, authorizationv1.ResourceAttributes{
+			Verb:     "list",
+			Group:    apiGroupVersion.Group,
+			Version:  apiGroupVersion.Version,
+			Resource: "machines",
+		})
+	}
+	return permissions
+}
+
+// selfSubjectAccessReviewCreator creates SelfSubjectAccessReviews
+type selfSubjectAccessReviewCreator interface {
+	Create(ctx context.Context, review *authorizationv1.SelfSubjectAccessReview, opts metav1.CreateOptions) (*authorizationv1.SelfSubjectAccessReview, error)
+}
+
+// checkPermissions logs a summary of the permissions the approver is missing
+// on the management and workload clusters. Missing permissions are not fatal,
+// but otherwise surface as confusing failures of single CSRs.
+func checkPermissions(ctx context.Context, managementConfig, workloadConfig *rest.Config, apiGroupVersions []schema.GroupVersion) {
+	for _, cluster := range []struct {
+		name        string
+		config      *rest.Config
+		permissions []authorizationv1.ResourceAttributes
+	}{
+		{"management", managementConfig, managementPermissions(apiGroupVersions)},
+		{"workload", workloadConfig, workloadPermissions},
+	} {
+		authorizationClient, err := authorizationv1client.NewForConfig(cluster.config)
+		if err != nil {
+			klog.Errorf("Failed to check permissions on the %s cluster: %v", cluster.name, err)
+			continue
+		}
+
+		missing, err := missingPermissions(ctx, authorizationClient.SelfSubjectAccessReviews(), cluster.permissions)
+		switch {
+		case err != nil:
+			klog.Errorf("Failed to check permissions on the %s cluster: %v", cluster.name, err)
+		case len(missing) > 0:
+			klog.Errorf("Missing permissions on the %s cluster, CSRs may not be approved: %s", cluster.name, strings.Join(missing, "; "))
+		default:
+			klog.Infof("All required permissions are granted on the %s cluster", cluster.name)
+		}
+	}
+}
+
+// missingPermissions returns the permissions which are not allowed, as
+// reviewed with reviews.
+func missingPermissions(ctx context.Context, reviews selfSubjectAccessReviewCreator, permissions []authorizationv1.ResourceAttributes) ([]string, error) {
+	var missing []string
+	for i := range permissions {
+		review, err := reviews.Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &permissions[i]},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to review %s: %w", describePermission(permissions[i]), err)
+		}
+		if !review.Status.Allowed {
+			missing = append(missing, describePermission(permissions[i]))
+		}
+	}
+	return missing, nil
+}
+
+// describePermission formats a permission, e.g. as
+// "get configmaps csr-controller-ca in namespace openshift-config-managed".
+func describePermission(p authorizationv1.ResourceAttributes) string {
+	resource := p.Resource
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+
+	description := p.Verb + " " + resource
+	if p.Name != "" {
+		description += " " + p.Name
+	}
+	if p.Namespace != "" {
+		description += " in namespace " + p.Namespace
+	}
+	return description
+}
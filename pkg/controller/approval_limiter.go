@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// approvalLimiter limits the rate of approvals with a token bucket holding up
+// to a minute's worth of approvals, to smooth the load on the API during large
+// scale-ups. The bucket is replaced whenever the configured rate changes.
+type approvalLimiter struct {
+	mu        sync.Mutex
+	perMinute int
+	limiter   *rate.Limiter
+}
+
+// reserve returns how long to wait before an approval is allowed at perMinute
+// approvals per minute, or zero when it is allowed now. The approval is
+// counted only when it is allowed, and cancel gives it back, e.g. when the
+// approval failed. Approvals are unlimited when perMinute is not positive.
+func (l *approvalLimiter) reserve(perMinute int) (delay time.Duration, cancel func()) {
+	if perMinute <= 0 {
+		return 0, func() {}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limiter == nil || l.perMinute != perMinute {
+		l.perMinute = perMinute
+		l.limiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(perMinute)), perMinute)
+	}
+
+	currentTime := now()
+	reservation := l.limiter.ReserveN(currentTime, 1)
+	if delay := reservation.DelayFrom(currentTime); delay > 0 {
+		reservation.CancelAt(currentTime)
+		return delay, func() {}
+	}
+	// The reservation is cancelled as of when it was made, as a reservation
+	// which could act already is not given back later.
+	return 0, func() { reservation.CancelAt(currentTime) }
+}
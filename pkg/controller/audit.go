@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// auditLogStdout is the audit log path writing audit records to stdout.
+const auditLogStdout = "-"
+
+// auditRecord is a single line of the audit log, written for every
+// authorization decision on a CSR.
+type auditRecord struct {
+	Timestamp     time.Time           `json:"timestamp"`
+	CSR           string              `json:"csr"`
+	Node          string              `json:"node,omitempty"`
+	SignerName    string              `json:"signerName"`
+	Decision      reconcileOutcome    `json:"decision"`
+	Reason        string              `json:"reason,omitempty"`
+	Method        authorizationMethod `json:"method,omitempty"`
+	CorrelationID string              `json:"correlationID,omitempty"`
+}
+
+// AuditLog appends audit records as JSON lines to a file, or to stdout.
+// The file is reopened when it was rotated, i.e. moved or removed.
+type AuditLog struct {
+	path string
+
+	mu   sync.Mutex
+	out  io.Writer
+	file *os.File
+}
+
+// NewAuditLog opens the audit log at path, which is created if missing and
+// appended to otherwise. Audit records are written to stdout when path is "-".
+func NewAuditLog(path string) (*AuditLog, error) {
+	if path == auditLogStdout {
+		return &AuditLog{path: path, out: os.Stdout}, nil
+	}
+
+	a := &AuditLog{path: path}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// open (re)opens the audit log file, a.mu must be held unless a is not shared yet.
+func (a *AuditLog) open() error {
+	file, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if a.file != nil {
+		a.file.Close()
+	}
+	a.file = file
+	a.out = file
+	return nil
+}
+
+// rotated returns whether the audit log file is no longer at its path.
+func (a *AuditLog) rotated() bool {
+	if a.file == nil {
+		return false
+	}
+	current, err := a.file.Stat()
+	if err != nil {
+		return true
+	}
+	atPath, err := os.Stat(a.path)
+	return err != nil || !os.SameFile(current, atPath)
+}
+
+// write appends the record as a single JSON line.
+func (a *AuditLog) write(record auditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.rotated() {
+		if err := a.open(); err != nil {
+			return err
+		}
+	}
+	// A single write per record, so that records are never interleaved.
+	if _, err := a.out.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// Close closes the audit log file.
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	a.out = io.Discard
+	return err
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CheckResult is the authorization decision for a CSR
+type CheckResult struct {
+	Authorized bool
+	// Method is the authorization method which authorized the CSR
+	Method string
+	// Reason explains why the CSR is not authorized
+	Reason string
+}
+
+// Check makes the authorization decision for the named CSR, the same way
+// Reconcile would, without approving it or otherwise mutating anything.
+// This helps to reproduce a decision from a CSR and the machines in a cluster.
+func (m *CertificateApprover) Check(ctx context.Context, csrName string) (CheckResult, error) {
+	config := m.config()
+
+	csr := certificatesv1.CertificateSigningRequest{}
+	if err := m.WorkloadClient.Get(ctx, client.ObjectKey{Name: csrName}, &csr); err != nil {
+		return CheckResult{}, fmt.Errorf("failed to get CSR %s: %w", csrName, err)
+	}
+
+	if isApproved(csr) {
+		return CheckResult{Reason: "CSR is already approved"}, nil
+	}
+	if csr.Annotations[skipAnnotation] == "true" {
+		return CheckResult{Reason: fmt.Sprintf("CSR has annotation %s=true", skipAnnotation)}, nil
+	}
+	if !pendingNodeCertFilter(config, &csr) {
+		return CheckResult{Reason: "CSR is not a node CSR handled by the machine approver"}, nil
+	}
+
+	machines, err := m.listMachines(ctx, csrName)
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	parsedCSR, err := parseCSR(&csr)
+	if err != nil {
+		return CheckResult{Reason: fmt.Sprintf("failed to parse CSR: %v", err)}, nil
+	}
+
+	var kubeletCA *x509.CertPool
+	if !config.ServingRenewal.Disabled {
+		kubeletCA = m.getKubeletCA()
+		if kubeletCA == nil {
+			// As when reconciling, only the renewal flow is skipped.
+			klog.Errorf("failed to get kubelet CA")
+		}
+	}
+
+	result, err := authorizeCSR(ctx, m.WorkloadClient, config, machines, &csr, parsedCSR, kubeletCA, nil, nil)
+	if result.Authorized {
+		return CheckResult{Authorized: true, Method: string(result.Method)}, nil
+	}
+	if err != nil {
+		return CheckResult{Reason: err.Error()}, nil
+	}
+	if result.Reason != "" {
+		return CheckResult{Reason: result.Reason}, nil
+	}
+	return CheckResult{Reason: "CSR does not meet the requirements for approval, see the logs for details"}, nil
+}
@@ -1,20 +1,448 @@
 package controller
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"strings"
+	"text/template"
+	"time"
 
+	certificatesv1 "k8s.io/api/certificates/v1"
+	apimachineryvalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	kyaml "k8s.io/apimachinery/pkg/util/yaml"
 
 	"k8s.io/klog/v2"
 )
 
 type ClusterMachineApproverConfig struct {
-	NodeClientCert NodeClientCert `json:"nodeClientCert,omitempty"`
+	NodeClientCert  NodeClientCert  `json:"nodeClientCert,omitempty"`
+	NodeServingCert NodeServingCert `json:"nodeServingCert,omitempty"`
+	ServingRenewal  ServingRenewal  `json:"servingRenewal,omitempty"`
+	// MachineAPIAuthorization configures the authorization of serving CSRs
+	// against the addresses of the node's machine.
+	MachineAPIAuthorization MachineAPIAuthorization `json:"machineAPIAuthorization,omitempty"`
+	MachineAddresses        MachineAddresses        `json:"machineAddresses,omitempty"`
+
+	// AdditionalKubeletCAConfigMap references a CA bundle trusted in addition
+	// to the kubelet CA, e.g. to cover the overlap during a CA rotation.
+	AdditionalKubeletCAConfigMap ConfigMapKeyReference `json:"additionalKubeletCAConfigMap,omitempty"`
+
+	// PreApprovalDelay holds back the approval of a CSR until it is at least
+	// this old, giving external validators a chance to deny it first.
+	PreApprovalDelay metav1.Duration `json:"preApprovalDelay,omitempty"`
+
+	// StaticNodes are nodes managed outside the machine-api, e.g. bare metal
+	// control plane nodes, whose serving CSRs are authorized against their own
+	// node addresses when no machine references them.
+	StaticNodes []string `json:"staticNodes,omitempty"`
+
+	// MaxSANCount is the maximum number of SANs of any type a CSR can
+	// request, defaults to 64. CSRs requesting more are never approved.
+	MaxSANCount int `json:"maxSANCount,omitempty"`
+
+	// MaxPendingCSRs overrides the limit of recently pending CSRs above which
+	// approvals are suppressed, computed from the machine and node counts
+	// when unset.
+	MaxPendingCSRs int `json:"maxPendingCSRs,omitempty"`
+
+	// MaxApprovalsPerMinute limits the rate of approvals, deferring the
+	// approval of authorized CSRs beyond it. Unlimited when unset.
+	MaxApprovalsPerMinute int `json:"maxApprovalsPerMinute,omitempty"`
+
+	// PendingLimitRequeueInterval is how soon CSRs suppressed by the pending
+	// CSRs limit are requeued, to notice capacity freed without any event,
+	// defaults to 1 minute.
+	PendingLimitRequeueInterval metav1.Duration `json:"pendingLimitRequeueInterval,omitempty"`
+
+	// KeyPolicy restricts the public keys CSRs can request certs for.
+	KeyPolicy KeyPolicy `json:"keyPolicy,omitempty"`
+
+	// ExtraAllowedNodeIPsConfigMap references a ConfigMap mapping node names
+	// to extra IP addresses allowed in their serving CSRs, in addition to the
+	// addresses of their machine, e.g. private IPs of nodes behind NAT.
+	ExtraAllowedNodeIPsConfigMap ConfigMapReference `json:"extraAllowedNodeIPsConfigMap,omitempty"`
+
+	// APIGroupVersionsConfigMap references a ConfigMap listing the API group
+	// versions machines are listed from, replacing the --api-group-version
+	// option while it is present, so that groups can be changed without a
+	// restart, e.g. while migrating machines between API groups.
+	APIGroupVersionsConfigMap ConfigMapKeyReference `json:"apiGroupVersionsConfigMap,omitempty"`
+
+	// ApprovalCondition configures the approved condition set on CSRs, e.g.
+	// to tell the approvers of several environments apart.
+	ApprovalCondition ApprovalCondition `json:"approvalCondition,omitempty"`
+
+	// DenyDisabledFlowCSRs denies the CSRs of flows disabled in the config,
+	// i.e. node client CSRs while nodeClientCert.disabled is set, rather than
+	// leaving them pending, so that it shows they were intentionally not
+	// approved.
+	DenyDisabledFlowCSRs bool `json:"denyDisabledFlowCSRs,omitempty"`
+
+	// DisableUncachedLimitsRefresh refreshes the pending CSRs limit and
+	// metrics after each reconcile from the cached CSRs, rather than listing
+	// them from the API server, sparing it on large clusters at the cost of
+	// slightly stale metrics.
+	DisableUncachedLimitsRefresh bool `json:"disableUncachedLimitsRefresh,omitempty"`
+}
+
+// SANCountLimit returns the maximum number of SANs a CSR can request
+func (c ClusterMachineApproverConfig) SANCountLimit() int {
+	if c.MaxSANCount <= 0 {
+		return defaultMaxSANCount
+	}
+	return c.MaxSANCount
+}
+
+// PendingCSRsLimit returns the limit of recently pending CSRs, MaxPendingCSRs
+// when set, otherwise the computed limit.
+func (c ClusterMachineApproverConfig) PendingCSRsLimit(computed int) int {
+	if c.MaxPendingCSRs > 0 {
+		return c.MaxPendingCSRs
+	}
+	return computed
+}
+
+// PendingLimitRequeueAfter returns how soon CSRs suppressed by the pending
+// CSRs limit are requeued
+func (c ClusterMachineApproverConfig) PendingLimitRequeueAfter() time.Duration {
+	if c.PendingLimitRequeueInterval.Duration <= 0 {
+		return defaultPendingLimitRequeueInterval
+	}
+	return c.PendingLimitRequeueInterval.Duration
+}
+
+// IsStaticNode returns whether the node is allowed to be authorized against
+// its own node addresses
+func (c ClusterMachineApproverConfig) IsStaticNode(nodeName string) bool {
+	for _, name := range c.StaticNodes {
+		if name == nodeName {
+			return true
+		}
+	}
+	return false
 }
 
 type NodeClientCert struct {
 	Disabled bool `json:"disabled,omitempty"`
+	// AllowRenewal enables approving client cert renewals requested by a node
+	// for its own identity. The node must exist and be referenced by a machine.
+	AllowRenewal bool `json:"allowRenewal,omitempty"`
+	// BootstrapperUsername is the user requesting client certs for new nodes,
+	// defaults to the machine-config-operator node-bootstrapper service account.
+	BootstrapperUsername string `json:"bootstrapperUsername,omitempty"`
+	// BootstrapperGroups are the exact groups of the bootstrapper user,
+	// defaults to the groups of the node-bootstrapper service account.
+	BootstrapperGroups []string `json:"bootstrapperGroups,omitempty"`
+	// MaxMachineDelta is how long after the creation of its machine a node
+	// client CSR can still be approved, defaults to 2h. This may need to be
+	// raised where machines take long to boot, e.g. bare metal waiting on PXE.
+	MaxMachineDelta metav1.Duration `json:"maxMachineDelta,omitempty"`
+	// AllowClientCertReissueForExistingNode allows approving client certs
+	// requested by the node bootstrapper for a node which already exists,
+	// e.g. when it rejoins with the same name. Its machine must still
+	// reference the node, the CSR must not be older than MaxMachineDelta, and
+	// the node must not be Ready, or its heartbeat must be stale.
+	AllowClientCertReissueForExistingNode bool `json:"allowClientCertReissueForExistingNode,omitempty"`
+	// ReissueHeartbeatStaleAfter is how long the heartbeat of a Ready node
+	// must be stale for its client cert to be reissued, defaults to 10m.
+	ReissueHeartbeatStaleAfter metav1.Duration `json:"reissueHeartbeatStaleAfter,omitempty"`
+	// NodeNameDomainSuffix is a domain node names and the InternalDNS names
+	// of machines are matched with or without, e.g. with example.com, node
+	// node1.example.com matches a machine with InternalDNS node1, and vice versa.
+	NodeNameDomainSuffix string `json:"nodeNameDomainSuffix,omitempty"`
+	// RequireMachinePhases are the phases the machine of a node must be in
+	// for its client CSR to be approved, e.g. Provisioned and Running, so that
+	// machines being deleted are not approved. Any phase is allowed when empty.
+	RequireMachinePhases []string `json:"requireMachinePhases,omitempty"`
+}
+
+// RequiredBootstrapperUsername returns the username required for node client CSRs
+func (c NodeClientCert) RequiredBootstrapperUsername() string {
+	if c.BootstrapperUsername == "" {
+		return nodeBootstrapperUsername
+	}
+	return c.BootstrapperUsername
+}
+
+// MachineDeltaLimit returns how long after the creation of its machine a node
+// client CSR can be approved
+func (c NodeClientCert) MachineDeltaLimit() time.Duration {
+	if c.MaxMachineDelta.Duration <= 0 {
+		return maxMachineDelta
+	}
+	return c.MaxMachineDelta.Duration
+}
+
+// ReissueHeartbeatStaleLimit returns how long the heartbeat of a Ready node
+// must be stale for its client cert to be reissued
+func (c NodeClientCert) ReissueHeartbeatStaleLimit() time.Duration {
+	if c.ReissueHeartbeatStaleAfter.Duration <= 0 {
+		return defaultReissueHeartbeatStaleAfter
+	}
+	return c.ReissueHeartbeatStaleAfter.Duration
+}
+
+// AllowsMachinePhase returns whether client CSRs can be approved for a machine in the phase
+func (c NodeClientCert) AllowsMachinePhase(phase string) bool {
+	return len(c.RequireMachinePhases) == 0 || sets.NewString(c.RequireMachinePhases...).Has(phase)
+}
+
+// RequiredBootstrapperGroups returns the groups required for node client CSRs
+func (c NodeClientCert) RequiredBootstrapperGroups() sets.String {
+	if len(c.BootstrapperGroups) == 0 {
+		return nodeBootstrapperGroups
+	}
+	return sets.NewString(c.BootstrapperGroups...)
+}
+
+type NodeServingCert struct {
+	// RejectDuplicateSANs rejects serving CSRs requesting an address which
+	// belongs to a single, different node.
+	RejectDuplicateSANs bool `json:"rejectDuplicateSANs,omitempty"`
+	// StrictRenewal requires the SANs of a serving cert renewal to still match
+	// the addresses of the node's machine, rather than only the current serving
+	// cert. This does not apply to the egress IP fallback.
+	StrictRenewal bool `json:"strictRenewal,omitempty"`
+	// Group is the group a node serving CSR requester must be in, defaults to
+	// system:nodes. The CSR subject organization must still be system:nodes.
+	Group string `json:"group,omitempty"`
+	// AdditionalUsages are combinations of key usages accepted for node
+	// serving CSRs in addition to the built-in ones. Each combination must
+	// match the usages of a CSR exactly.
+	AdditionalUsages [][]certificatesv1.KeyUsage `json:"additionalUsages,omitempty"`
+	// AllowNodeAddresses authorizes serving CSRs against the addresses of
+	// their existing node when the machine does not authorize them, e.g. when
+	// the machine addresses drifted. Node addresses are reported by the
+	// kubelet itself, so this is weaker than the machine check.
+	AllowNodeAddresses bool `json:"allowNodeAddresses,omitempty"`
+	// AllowMachineAddressCIDRs treats machine IP addresses given in CIDR
+	// notation, e.g. 10.0.0.0/24, as ranges, authorizing any IP within them
+	// which is not an address of another machine or node. Some on-prem
+	// providers advertise a range rather than the node's IPs.
+	AllowMachineAddressCIDRs bool `json:"allowMachineAddressCIDRs,omitempty"`
+	// MaxMachineAddressCIDRHostBits is the number of host bits of the widest
+	// range allowed by AllowMachineAddressCIDRs, defaults to 8, i.e. a /24 for
+	// IPv4 and a /120 for IPv6. Wider ranges are ignored.
+	MaxMachineAddressCIDRHostBits int `json:"maxMachineAddressCIDRHostBits,omitempty"`
+	// AllowHostNameShortNames authorizes the short name of a machine's
+	// Hostname address, e.g. node1 for node1.example.com, as a DNS name.
+	// Some providers only report the fully qualified Hostname, while the
+	// kubelet requests its short name.
+	AllowHostNameShortNames bool `json:"allowHostNameShortNames,omitempty"`
+}
+
+// MachineAddressCIDRHostBitsLimit returns the number of host bits of the
+// widest machine address range allowed
+func (c NodeServingCert) MachineAddressCIDRHostBitsLimit() int {
+	if c.MaxMachineAddressCIDRHostBits <= 0 {
+		return defaultMaxMachineAddressCIDRHostBits
+	}
+	return c.MaxMachineAddressCIDRHostBits
+}
+
+// RequiredGroup returns the group required for node serving CSRs
+func (c NodeServingCert) RequiredGroup() string {
+	if c.Group == "" {
+		return nodeGroup
+	}
+	return c.Group
+}
+
+// AllowsAdditionalUsages returns whether the usages match one of the
+// additional usage combinations exactly
+func (c NodeServingCert) AllowsAdditionalUsages(usages sets.String) bool {
+	for _, combination := range c.AdditionalUsages {
+		allowed := sets.NewString()
+		for _, usage := range combination {
+			allowed.Insert(string(usage))
+		}
+		if allowed.Equal(usages) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServingRenewal configures the renewal flow for node serving certs, which
+// connects to the kubelet to authorize a CSR against its current serving cert.
+type ServingRenewal struct {
+	// Disabled prevents any connection to kubelets. Serving CSRs are then
+	// only authorized against the machine-api, which also disables the
+	// egress IP fallback as it relies on the current serving cert.
+	Disabled bool `json:"disabled,omitempty"`
+	// AllowExternalKubeletAddress allows connecting to a kubelet on the node's
+	// external IP when it has no internal IP, e.g. on some edge deployments.
+	AllowExternalKubeletAddress bool `json:"allowExternalKubeletAddress,omitempty"`
+	// DialFailureBackoff is how long a kubelet which could not be dialed is
+	// not dialed again, authorizing the serving CSRs of its node with
+	// machines right away, e.g. while nodes are down during a rolling reboot.
+	// Disabled when zero.
+	DialFailureBackoff metav1.Duration `json:"dialFailureBackoff,omitempty"`
+	// RenewalWindow only authorizes renewals of a current serving cert which
+	// expires within the window, to reduce churn. Premature renewals are not
+	// approved by any flow until the current cert enters the window. Renewals
+	// are always allowed when zero.
+	RenewalWindow metav1.Duration `json:"renewalWindow,omitempty"`
+	// ServerNameTemplate is a Go template of the TLS server name sent to and
+	// verified against the kubelet, e.g. "{{.NodeName}}.kubelet.example.com"
+	// where kubelets are behind a TLS-terminating proxy routing by SNI. The
+	// template is given the NodeName and the kubelet Address. Defaults to the
+	// kubelet address.
+	ServerNameTemplate string `json:"serverNameTemplate,omitempty"`
+}
+
+// kubeletServerName is the data of ServerNameTemplate
+type kubeletServerName struct {
+	NodeName string
+	Address  string
+}
+
+// ServerName returns the TLS server name of the kubelet of the node at the
+// address, as given by ServerNameTemplate when set.
+func (r ServingRenewal) ServerName(nodeName, address string) (string, error) {
+	if r.ServerNameTemplate == "" {
+		return address, nil
+	}
+
+	tmpl, err := template.New("serverName").Option("missingkey=error").Parse(r.ServerNameTemplate)
+	if err != nil {
+		return "", err
+	}
+	var serverName strings.Builder
+	if err := tmpl.Execute(&serverName, kubeletServerName{NodeName: nodeName, Address: address}); err != nil {
+		return "", err
+	}
+	return serverName.String(), nil
+}
+
+type MachineAPIAuthorization struct {
+	// Disabled skips authorizing serving CSRs against the machine-api, e.g.
+	// when machines are managed out-of-band. Only renewals of the current
+	// serving cert of a kubelet are then approved.
+	Disabled bool `json:"disabled,omitempty"`
+	// MatchProviderID falls back to matching the provider ID of an existing
+	// node with the machines', when no machine matches the node by name.
+	// This helps where node names are overridden and match no machine address.
+	MatchProviderID bool `json:"matchProviderID,omitempty"`
+	// NodeNameLabel falls back to matching the machine whose label with this
+	// key equals the node name, when no machine matches the node otherwise.
+	// This helps where machines are mapped to nodes by a shared label only.
+	NodeNameLabel string `json:"nodeNameLabel,omitempty"`
+}
+
+// KeyPolicy restricts the public keys of CSRs. CSRs with other keys are never
+// approved. Keys are not restricted by default.
+type KeyPolicy struct {
+	// MinRSABits is the minimum size of RSA keys.
+	MinRSABits int `json:"minRSABits,omitempty"`
+	// AllowedCurves are the names of the curves allowed for ECDSA keys,
+	// e.g. P-256. All curves are allowed when empty.
+	AllowedCurves []string `json:"allowedCurves,omitempty"`
+}
+
+// knownCurves are the names of the curves supported for ECDSA keys
+var knownCurves = sets.NewString(
+	elliptic.P224().Params().Name,
+	elliptic.P256().Params().Name,
+	elliptic.P384().Params().Name,
+	elliptic.P521().Params().Name,
+)
+
+// Check returns an error when the public key is not allowed by the policy
+func (p KeyPolicy) Check(publicKey crypto.PublicKey) error {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if bits := key.N.BitLen(); bits < p.MinRSABits {
+			return fmt.Errorf("RSA key size %d is below the minimum of %d", bits, p.MinRSABits)
+		}
+	case *ecdsa.PublicKey:
+		curve := key.Curve.Params().Name
+		if len(p.AllowedCurves) > 0 && !sets.NewString(p.AllowedCurves...).Has(curve) {
+			return fmt.Errorf("ECDSA curve %s is not one of the allowed curves %v", curve, p.AllowedCurves)
+		}
+	}
+	return nil
+}
+
+type MachineAddresses struct {
+	// CacheTTL enables falling back to the last observed addresses of a
+	// machine, for up to the given duration, while its status reports none.
+	CacheTTL metav1.Duration `json:"cacheTTL,omitempty"`
+	// ProviderAddressesPath is the dot separated field path of addresses
+	// reported by the provider of machine-api machines, which are merged into
+	// their status addresses, e.g. status.providerStatus.addresses.
+	ProviderAddressesPath string `json:"providerAddressesPath,omitempty"`
+	// InfrastructureRefAddresses merges the status addresses of the
+	// infrastructure machine referenced by cluster-api machines, e.g. an
+	// AWSMachine, into their status addresses. This costs a get per machine.
+	InfrastructureRefAddresses bool `json:"infrastructureRefAddresses,omitempty"`
+}
+
+// ProviderAddressesFields returns the fields of ProviderAddressesPath, or nil when it is unset.
+func (a MachineAddresses) ProviderAddressesFields() []string {
+	if a.ProviderAddressesPath == "" {
+		return nil
+	}
+	return strings.Split(a.ProviderAddressesPath, ".")
+}
+
+type ApprovalCondition struct {
+	// Reason of the approved condition, defaults to NodeCSRApprove.
+	Reason string `json:"reason,omitempty"`
+	// Message of the approved condition, defaults to a message naming the
+	// cluster-machine-approver. CSRs approved with either message are
+	// recognized as approved by the machine approver.
+	Message string `json:"message,omitempty"`
+	// DenyReason of the denied condition set on the CSRs of disabled flows,
+	// see DenyDisabledFlowCSRs. Defaults to NodeCSRFlowDisabled.
+	DenyReason string `json:"denyReason,omitempty"`
+}
+
+// DisabledFlowDenyReason returns the reason of the denied condition
+func (c ApprovalCondition) DisabledFlowDenyReason() string {
+	if c.DenyReason == "" {
+		return csrConditionDisabledFlowDenyReason
+	}
+	return c.DenyReason
+}
+
+// ApproveReason returns the reason of the approved condition
+func (c ApprovalCondition) ApproveReason() string {
+	if c.Reason == "" {
+		return csrConditionApproveReason
+	}
+	return c.Reason
+}
+
+// ApproveMessage returns the message of the approved condition
+func (c ApprovalCondition) ApproveMessage() string {
+	if c.Message == "" {
+		return csrConditionApproveMessage
+	}
+	return c.Message
+}
+
+type ConfigMapKeyReference struct {
+	// Namespace defaults to openshift-config-managed.
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Key defaults to ca-bundle.crt.
+	Key string `json:"key,omitempty"`
+}
+
+type ConfigMapReference struct {
+	// Namespace defaults to openshift-config-managed.
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
 }
 
 func LoadConfig(cliConfig string) ClusterMachineApproverConfig {
@@ -28,26 +456,115 @@ func LoadConfig(cliConfig string) ClusterMachineApproverConfig {
 		return config
 	}
 
-	content, err := ioutil.ReadFile(cliConfig)
+	loaded, err := readConfig(cliConfig)
 	if err != nil {
-		klog.Infof("using default as failed to load config %s: %v", cliConfig, err)
+		klog.Infof("using default as %v", err)
 		return config
 	}
-	if len(content) == 0 {
-		klog.Infof("using default as config %s is empty", cliConfig)
+	// As on reloads, an invalid config is not applied.
+	if err := loaded.Validate(); err != nil {
+		klog.Errorf("Invalid config %s, using default: %v", cliConfig, err)
 		return config
 	}
 
+	config = loaded
+	return config
+}
+
+// readConfig reads the config from the file at path.
+func readConfig(path string) (ClusterMachineApproverConfig, error) {
+	config := ClusterMachineApproverConfig{}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("failed to load config %s: %v", path, err)
+	}
+	if len(content) == 0 {
+		return config, fmt.Errorf("config %s is empty", path)
+	}
+
 	data, err := kyaml.ToJSON(content)
 	if err != nil {
-		klog.Infof("using default as failed to convert config %s to JSON: %v", cliConfig, err)
-		return config
+		return config, fmt.Errorf("failed to convert config %s to JSON: %v", path, err)
 	}
 
 	if err := json.Unmarshal(data, &config); err != nil {
-		klog.Infof("using default as failed to unmarshal config %s as JSON: %v", cliConfig, err)
-		return config
+		return config, fmt.Errorf("failed to unmarshal config %s as JSON: %v", path, err)
 	}
 
-	return config
+	return config, nil
+}
+
+// Validate checks the config for values which cannot be applied
+func (c ClusterMachineApproverConfig) Validate() error {
+	var errs []error
+
+	for _, duration := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"nodeClientCert.maxMachineDelta", c.NodeClientCert.MaxMachineDelta.Duration},
+		{"nodeClientCert.reissueHeartbeatStaleAfter", c.NodeClientCert.ReissueHeartbeatStaleAfter.Duration},
+		{"machineAddresses.cacheTTL", c.MachineAddresses.CacheTTL.Duration},
+		{"preApprovalDelay", c.PreApprovalDelay.Duration},
+		{"servingRenewal.dialFailureBackoff", c.ServingRenewal.DialFailureBackoff.Duration},
+		{"servingRenewal.renewalWindow", c.ServingRenewal.RenewalWindow.Duration},
+		{"pendingLimitRequeueInterval", c.PendingLimitRequeueInterval.Duration},
+	} {
+		if duration.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %v", duration.name, duration.value))
+		}
+	}
+	if bits := c.NodeServingCert.MaxMachineAddressCIDRHostBits; bits < 0 || bits > 128 {
+		errs = append(errs, fmt.Errorf("nodeServingCert.maxMachineAddressCIDRHostBits must be between 0 and 128, got %d", bits))
+	}
+	if c.MaxSANCount < 0 {
+		errs = append(errs, fmt.Errorf("maxSANCount must not be negative, got %d", c.MaxSANCount))
+	}
+	if c.MaxPendingCSRs < 0 {
+		errs = append(errs, fmt.Errorf("maxPendingCSRs must not be negative, got %d", c.MaxPendingCSRs))
+	}
+	if c.MaxApprovalsPerMinute < 0 {
+		errs = append(errs, fmt.Errorf("maxApprovalsPerMinute must not be negative, got %d", c.MaxApprovalsPerMinute))
+	}
+	if c.KeyPolicy.MinRSABits < 0 {
+		errs = append(errs, fmt.Errorf("keyPolicy.minRSABits must not be negative, got %d", c.KeyPolicy.MinRSABits))
+	}
+	for _, curve := range c.KeyPolicy.AllowedCurves {
+		if !knownCurves.Has(curve) {
+			errs = append(errs, fmt.Errorf("keyPolicy.allowedCurves: unknown curve %q, must be one of %v", curve, knownCurves.List()))
+		}
+	}
+	for _, nodeName := range c.StaticNodes {
+		if err := validateNodeName(nodeName); err != nil {
+			errs = append(errs, fmt.Errorf("staticNodes: %v", err))
+		}
+	}
+	if suffix := strings.ToLower(strings.Trim(c.NodeClientCert.NodeNameDomainSuffix, ".")); suffix != "" {
+		if msgs := apimachineryvalidation.NameIsDNSSubdomain(suffix, false); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("nodeClientCert.nodeNameDomainSuffix %q is not a valid domain: %s", c.NodeClientCert.NodeNameDomainSuffix, strings.Join(msgs, ", ")))
+		}
+	}
+	for _, phase := range c.NodeClientCert.RequireMachinePhases {
+		if phase == "" {
+			errs = append(errs, fmt.Errorf("nodeClientCert.requireMachinePhases must not contain empty phases"))
+			break
+		}
+	}
+	if _, err := c.ServingRenewal.ServerName("node", "127.0.0.1"); err != nil {
+		errs = append(errs, fmt.Errorf("servingRenewal.serverNameTemplate %q is invalid: %v", c.ServingRenewal.ServerNameTemplate, err))
+	}
+	if key := c.MachineAPIAuthorization.NodeNameLabel; key != "" {
+		if msgs := validation.IsQualifiedName(key); len(msgs) > 0 {
+			errs = append(errs, fmt.Errorf("machineAPIAuthorization.nodeNameLabel %q is not a valid label key: %s", key, strings.Join(msgs, ", ")))
+		}
+	}
+	for _, field := range c.MachineAddresses.ProviderAddressesFields() {
+		if field == "" {
+			errs = append(errs, fmt.Errorf("machineAddresses.providerAddressesPath %q must not contain empty fields", c.MachineAddresses.ProviderAddressesPath))
+			break
+		}
+	}
+
+	return kerrors.NewAggregate(errs)
 }
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/klog/v2"
+)
+
+// config returns the current approver config, with MaxPendingCSRs applied.
+func (m *CertificateApprover) config() ClusterMachineApproverConfig {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+	config := m.Config
+	if m.MaxPendingCSRs > 0 {
+		config.MaxPendingCSRs = m.MaxPendingCSRs
+	}
+	return config
+}
+
+// setConfig replaces the approver config.
+func (m *CertificateApprover) setConfig(config ClusterMachineApproverConfig) {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+	m.Config = config
+}
+
+// watchConfig reloads the config whenever the file at ConfigPath changes,
+// until the context is done. The directory is watched rather than the file,
+// as ConfigMap volumes replace their files by swapping a symlink.
+func (m *CertificateApprover) watchConfig(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("unable to create config watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(m.ConfigPath)); err != nil {
+		return fmt.Errorf("unable to watch config %s: %w", m.ConfigPath, err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			klog.V(4).Infof("Config watcher event: %v", event)
+			m.reloadConfig(ctx)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			klog.Errorf("Config watcher error: %v", err)
+		}
+	}
+}
+
+// reloadConfig applies the config at ConfigPath when it changed. The current
+// config is kept when the new one cannot be read or is invalid.
+func (m *CertificateApprover) reloadConfig(ctx context.Context) {
+	config, err := readConfig(m.ConfigPath)
+	if err != nil {
+		klog.Errorf("Failed to reload config, keeping the current config: %v", err)
+		return
+	}
+	if err := config.Validate(); err != nil {
+		klog.Errorf("Invalid config %s, keeping the current config: %v", m.ConfigPath, err)
+		return
+	}
+
+	current := m.config()
+	if reflect.DeepEqual(config, current) {
+		return
+	}
+
+	m.setConfig(config)
+	klog.Infof("Reloaded machine approver config from %s: %+v", m.ConfigPath, config)
+
+	// The API group versions ConfigMap was added, removed or replaced, its
+	// events only apply from now on.
+	if config.APIGroupVersionsConfigMap != current.APIGroupVersionsConfigMap {
+		m.syncAPIGroupVersions(ctx)
+	}
+}
@@ -4,19 +4,34 @@ import (
 	"context"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
+	"unicode"
 
 	machinehandlerpkg "github.com/openshift/cluster-machine-approver/pkg/machinehandler"
 	certificatesv1 "k8s.io/api/certificates/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/wait"
 	certificatesv1client "k8s.io/client-go/kubernetes/typed/certificates/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/pager"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -24,6 +39,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -31,51 +47,375 @@ import (
 const (
 	configNamespace            = "openshift-config-managed"
 	kubeletCAConfigMap         = "csr-controller-ca"
+	kubeletCABundleKey         = "ca-bundle.crt"
+	apiGroupVersionsKey        = "apiGroupVersions"
+	csrConditionApproveReason  = "NodeCSRApprove"
 	csrConditionApproveMessage = "This CSR was approved by the Node CSR Approver (cluster-machine-approver)"
+
+	csrConditionDisabledFlowDenyReason = "NodeCSRFlowDisabled"
+
+	// authorizedByAnnotation records which authorization method approved the CSR.
+	authorizedByAnnotation = "machineapprover.openshift.io/authorized-by"
+	// correlationIDAnnotation traces a CSR across components. It is generated when absent.
+	correlationIDAnnotation = "machineapprover.openshift.io/correlation-id"
+	// skipAnnotation opts a CSR out of automatic approval when set to "true", leaving it for manual approval.
+	skipAnnotation = "machineapprover.openshift.io/skip"
+	// reconcileOutcomeAnnotation records the outcome of the last reconcile of a CSR, when enabled.
+	reconcileOutcomeAnnotation = "machineapprover.openshift.io/reconcile-outcome"
+	// reconcileCountAnnotation counts the reconciles of a CSR, when enabled.
+	reconcileCountAnnotation = "machineapprover.openshift.io/reconcile-count"
+
+	// eventReasonServingCSRNotAuthorized is the reason of events on nodes whose serving CSR could not be authorized.
+	eventReasonServingCSRNotAuthorized = "ServingCSRNotAuthorized"
+
+	// startupDelayRequeueInterval is how often CSRs are requeued while approvals are held back by the startup delay.
+	startupDelayRequeueInterval = 5 * time.Second
+	// startupObserveRequeueInterval is how often authorized CSRs are requeued during the startup observe period.
+	startupObserveRequeueInterval = 5 * time.Second
+
+	// defaultMachineListTimeout bounds listing machines when MachineListTimeout is unset.
+	defaultMachineListTimeout = 30 * time.Second
+	// machineListRequeueInterval is how soon a CSR is requeued after listing machines timed out.
+	machineListRequeueInterval = 10 * time.Second
+
+	// managementRequeueBaseInterval is how soon a CSR is first requeued after the management cluster API failed.
+	managementRequeueBaseInterval = 5 * time.Second
+	// managementRequeueMaxInterval caps the backoff of CSRs requeued while the management cluster API keeps failing.
+	managementRequeueMaxInterval = 5 * time.Minute
+
+	// machineAddressPollInterval is the initial interval between machine lists while waiting for addresses.
+	machineAddressPollInterval = 200 * time.Millisecond
+	// machineAddressPollMaxInterval caps the backoff between machine lists while waiting for addresses.
+	machineAddressPollMaxInterval = 5 * time.Second
+
+	// suppressedCSRsLogInterval rate-limits logging the CSRs suppressed by the pending CSRs limit.
+	suppressedCSRsLogInterval = time.Minute
+	// maxSuppressedCSRsLogged caps the number of suppressed CSR names logged.
+	maxSuppressedCSRsLogged = 20
+	// defaultPendingLimitRequeueInterval is how soon CSRs suppressed by the pending CSRs limit are requeued by default.
+	defaultPendingLimitRequeueInterval = time.Minute
 )
 
+// reconcileOutcome is the outcome of a CSR reconcile, see reconcileOutcomeAnnotation.
+type reconcileOutcome string
+
+const (
+	reconcileOutcomeApproved      reconcileOutcome = "approved"
+	reconcileOutcomeNotAuthorized reconcileOutcome = "not-authorized"
+	reconcileOutcomeDenied        reconcileOutcome = "denied"
+	reconcileOutcomeSkipped       reconcileOutcome = "skipped"
+	reconcileOutcomeError         reconcileOutcome = "error"
+)
+
+// lastSuppressedCSRsLog is when the suppressed CSRs were last logged, in Unix nanoseconds.
+var lastSuppressedCSRsLog atomic.Int64
+
+// errMachineListTimeout is returned when machines could not be listed within MachineListTimeout
+var errMachineListTimeout = errors.New("timed out listing machines")
+
+// errDecidedConcurrently is returned when a CSR was approved or denied by
+// another actor while it was being approved or denied, whose decision is kept
+var errDecidedConcurrently = errors.New("CSR was approved or denied concurrently")
+
+// managementClusterError is a failure of the management cluster API, which
+// machines are listed from. With a separate management cluster, e.g. in
+// HyperShift, it may be unavailable while the workload cluster is not.
+type managementClusterError struct {
+	err error
+}
+
+func (e *managementClusterError) Error() string {
+	return fmt.Sprintf("management cluster: %v", e.err)
+}
+
+func (e *managementClusterError) Unwrap() error {
+	return e.err
+}
+
+// managementRequeueInterval returns how soon a CSR is requeued after the
+// given number of consecutive management cluster API failures, doubling from
+// managementRequeueBaseInterval up to managementRequeueMaxInterval.
+func managementRequeueInterval(failures int32) time.Duration {
+	interval := managementRequeueBaseInterval
+	for i := int32(1); i < failures && interval < managementRequeueMaxInterval; i++ {
+		interval *= 2
+	}
+	if interval > managementRequeueMaxInterval {
+		return managementRequeueMaxInterval
+	}
+	return interval
+}
+
+// machineLister lists machines in an API group, see machinehandler.MachineHandler
+type machineLister interface {
+	ListMachines(apiGroupVersion schema.GroupVersion) ([]machinehandlerpkg.Machine, error)
+}
+
 // MachineApproverReconciler reconciles a machine-approver  object
 type CertificateApprover struct {
 	WorkloadClient client.Client
 	NodeRestCfg    *rest.Config
 
-	ManagementClient client.Client
-	MachineRestCfg   *rest.Config
-	MachineNamespace string
+	ManagementClient  client.Client
+	MachineRestCfg    *rest.Config
+	MachineNamespaces []string
+
+	// Config is the approver config. It is replaced whenever the file at
+	// ConfigPath changes, read it with config().
+	Config ClusterMachineApproverConfig
 
-	Config           ClusterMachineApproverConfig
+	// APIGroupVersions are the API group versions machines are listed from.
+	// They are replaced whenever the ConfigMap referenced by the config's
+	// apiGroupVersionsConfigMap changes, read them with apiGroupVersions().
 	APIGroupVersions []schema.GroupVersion
+
+	// ParseAPIGroupVersion parses and validates an API group version listed
+	// in the API group versions ConfigMap, in the format of the
+	// --api-group-version option. The ConfigMap is ignored when nil.
+	ParseAPIGroupVersion func(string) (schema.GroupVersion, error)
+
+	// ConfigPath is the path Config was loaded from. When set, the config is
+	// reloaded whenever the file changes.
+	ConfigPath string
+
+	// StartupDelay holds back approvals for the given duration once the
+	// instance has been elected leader and its caches have synced.
+	StartupDelay time.Duration
+
+	// StartupObservePeriod defers approvals for the given duration once the
+	// instance has been elected leader and its caches have synced. Unlike
+	// StartupDelay, CSRs are reconciled meanwhile, updating the metrics and
+	// logging the decisions, but authorized CSRs are requeued instead of
+	// approved.
+	StartupObservePeriod time.Duration
+
+	// MachineListTimeout bounds how long a reconcile waits for machines to be
+	// listed before requeueing the CSR. Defaults to 30s.
+	MachineListTimeout time.Duration
+
+	// MaxPendingCSRs overrides the pending CSRs limit of Config when set,
+	// taking precedence over the config file across reloads.
+	MaxPendingCSRs int
+
+	// MachineAddressWaitTimeout bounds how long a reconcile polls for the
+	// addresses of the machine of a serving CSR's node to be populated, when
+	// the machine has none yet. Disabled when zero.
+	MachineAddressWaitTimeout time.Duration
+
+	// CSRSelector restricts the CSRs considered, e.g. to those labeled by
+	// some bootstrap tooling when several approvers share a cluster. All CSRs
+	// are considered when nil.
+	CSRSelector labels.Selector
+
+	// MetricsResyncInterval is how often the pending CSRs metrics are
+	// refreshed while no CSR is reconciled. Defaults to 60s.
+	MetricsResyncInterval time.Duration
+
+	// AnnotateReconcileOutcome records the outcome and count of reconciles on
+	// each CSR, to help debugging CSRs reconciled repeatedly. This costs an
+	// extra write per reconcile.
+	AnnotateReconcileOutcome bool
+
+	// AuditLog records every authorization decision on a CSR, separately
+	// from the logs. Disabled when nil.
+	AuditLog *AuditLog
+
+	// Recorder records events, e.g. on nodes whose serving CSRs cannot be
+	// authorized. No events are recorded when nil.
+	Recorder record.EventRecorder
+
+	// newMachineLister overrides how machines are listed, for testing.
+	newMachineLister func(ctx context.Context) machineLister
+
+	// listCSRsUncached overrides how CSRs are listed bypassing the cache, for testing.
+	listCSRsUncached func(ctx context.Context) ([]certificatesv1.CertificateSigningRequest, error)
+
+	// approvalsAllowed is set once the startup delay has elapsed.
+	approvalsAllowed atomic.Bool
+
+	// observing is set until the startup observe period has elapsed.
+	observing atomic.Bool
+
+	// managementFailures counts the consecutive reconciles failed by the
+	// management cluster API, to back off requeues while it is unavailable.
+	managementFailures atomic.Int32
+
+	// machineAddresses remembers the last observed machine addresses.
+	machineAddresses machineAddressCache
+
+	// kubeletDialFailures remembers the nodes whose kubelet recently could
+	// not be dialed. It is reset whenever the kubelet CA changes.
+	kubeletDialFailures kubeletDialFailureCache
+
+	// externalApprovals remembers the CSRs counted in ExternallyApprovedCSRs.
+	externalApprovals externalApprovalCache
+
+	// approvals limits the rate of approvals to MaxApprovalsPerMinute.
+	approvals approvalLimiter
+
+	// kubeletCA caches the parsed kubelet CA bundle. It is reset whenever
+	// the kubelet CA ConfigMap changes.
+	kubeletCA   *x509.CertPool
+	kubeletCAMu sync.Mutex
+
+	// configMu guards Config against reloads.
+	configMu sync.RWMutex
+
+	// apiGroupVersionsMu guards APIGroupVersions against updates from the
+	// API group versions ConfigMap.
+	apiGroupVersionsMu sync.RWMutex
+
+	// optionAPIGroupVersions are the API group versions given by the
+	// --api-group-version option, restored once APIGroupVersions are no
+	// longer read from a ConfigMap.
+	optionAPIGroupVersions   []schema.GroupVersion
+	apiGroupVersionsReplaced bool
 }
 
 func (m *CertificateApprover) SetupWithManager(mgr ctrl.Manager, options controller.Options) error {
+	if m.StartupDelay > 0 {
+		// Runnables require leader election by default, so this only starts once elected.
+		if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			return m.waitForStartupDelay(ctx, mgr.GetCache())
+		})); err != nil {
+			return fmt.Errorf("unable to add startup delay runnable: %w", err)
+		}
+	} else {
+		m.approvalsAllowed.Store(true)
+	}
+
+	if m.StartupObservePeriod > 0 {
+		m.observing.Store(true)
+		if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			return m.waitForStartupObservePeriod(ctx, mgr.GetCache())
+		})); err != nil {
+			return fmt.Errorf("unable to add startup observe period runnable: %w", err)
+		}
+	}
+
+	if err := mgr.Add(manager.RunnableFunc(m.resyncMetrics)); err != nil {
+		return fmt.Errorf("unable to add metrics resync runnable: %w", err)
+	}
+
+	if m.ConfigPath != "" {
+		if err := mgr.Add(manager.RunnableFunc(m.watchConfig)); err != nil {
+			return fmt.Errorf("unable to add config watcher runnable: %w", err)
+		}
+	}
+
 	return m.buildWithManager(mgr, options, m)
 }
 
+// cacheSyncWaiter is satisfied by the manager cache.
+type cacheSyncWaiter interface {
+	WaitForCacheSync(ctx context.Context) bool
+}
+
+// waitForStartupDelay allows approvals once the caches have synced and the
+// startup delay has elapsed.
+func (m *CertificateApprover) waitForStartupDelay(ctx context.Context, cache cacheSyncWaiter) error {
+	if !cache.WaitForCacheSync(ctx) {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	klog.Infof("Caches synced, holding back approvals for %v", m.StartupDelay)
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(m.StartupDelay):
+	}
+
+	klog.Info("Startup delay elapsed, approving CSRs")
+	m.approvalsAllowed.Store(true)
+
+	return nil
+}
+
+// waitForStartupObservePeriod ends the startup observe period once the caches
+// have synced and the period has elapsed.
+func (m *CertificateApprover) waitForStartupObservePeriod(ctx context.Context, cache cacheSyncWaiter) error {
+	if !cache.WaitForCacheSync(ctx) {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	klog.Infof("Caches synced, observing CSRs without approving them for %v", m.StartupObservePeriod)
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(m.StartupObservePeriod):
+	}
+
+	klog.Info("Startup observe period elapsed, approving CSRs")
+	m.observing.Store(false)
+
+	return nil
+}
+
 func (m *CertificateApprover) buildWithManager(mgr ctrl.Manager, options controller.Options, c reconcile.Reconciler) error {
+	caRefs := m.watchedConfigMaps
 	return ctrl.NewControllerManagedBy(mgr).
 		WithOptions(options).
 		For(&certificatesv1.CertificateSigningRequest{}, builder.WithPredicates(predicate.Funcs{
-			CreateFunc:  func(e event.CreateEvent) bool { return pendingNodeCertFilter(e.Object) },
-			UpdateFunc:  func(e event.UpdateEvent) bool { return pendingNodeCertFilter(e.ObjectNew) },
-			GenericFunc: func(e event.GenericEvent) bool { return pendingNodeCertFilter(e.Object) },
-			DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+			CreateFunc: func(e event.CreateEvent) bool {
+				return m.csrSelected(e.Object) && pendingNodeCertFilter(m.config(), e.Object)
+			},
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				return !onlyReconcileOutcomeChanged(e.ObjectOld, e.ObjectNew) && m.csrSelected(e.ObjectNew) && pendingNodeCertFilter(m.config(), e.ObjectNew)
+			},
+			GenericFunc: func(e event.GenericEvent) bool {
+				return m.csrSelected(e.Object) && pendingNodeCertFilter(m.config(), e.Object)
+			},
+			DeleteFunc: func(e event.DeleteEvent) bool { return false },
 		})).
 		Watches(
 			&corev1.ConfigMap{},
 			handler.EnqueueRequestsFromMapFunc(m.toCSRs),
 			builder.WithPredicates(predicate.Funcs{
-				CreateFunc:  func(e event.CreateEvent) bool { return caConfigMapFilter(e.Object, nil) },
-				UpdateFunc:  func(e event.UpdateEvent) bool { return caConfigMapFilter(e.ObjectOld, e.ObjectNew) },
-				GenericFunc: func(e event.GenericEvent) bool { return caConfigMapFilter(e.Object, nil) },
+				CreateFunc:  func(e event.CreateEvent) bool { return caConfigMapFilter(caRefs(), e.Object, nil) },
+				UpdateFunc:  func(e event.UpdateEvent) bool { return caConfigMapFilter(caRefs(), e.ObjectOld, e.ObjectNew) },
+				GenericFunc: func(e event.GenericEvent) bool { return caConfigMapFilter(caRefs(), e.Object, nil) },
 				DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+			})).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(m.apiGroupVersionsConfigMapDeleted),
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc:  func(e event.CreateEvent) bool { return false },
+				UpdateFunc:  func(e event.UpdateEvent) bool { return false },
+				GenericFunc: func(e event.GenericEvent) bool { return false },
+				DeleteFunc:  func(e event.DeleteEvent) bool { return m.isAPIGroupVersionsConfigMap(e.Object) },
+			})).
+		Watches(
+			&corev1.Node{},
+			handler.EnqueueRequestsFromMapFunc(m.nodeToCSRs),
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc:  func(e event.CreateEvent) bool { return true },
+				UpdateFunc:  func(e event.UpdateEvent) bool { return false },
+				GenericFunc: func(e event.GenericEvent) bool { return false },
+				DeleteFunc:  func(e event.DeleteEvent) bool { return true },
 			})).Complete(c)
 }
 
+// csrSelector returns the selector CSRs must match to be considered.
+func (m *CertificateApprover) csrSelector() labels.Selector {
+	if m.CSRSelector == nil {
+		return labels.Everything()
+	}
+	return m.CSRSelector
+}
+
+// csrSelected returns whether the CSR matches the CSR selector.
+func (m *CertificateApprover) csrSelected(obj client.Object) bool {
+	return m.csrSelector().Matches(labels.Set(obj.GetLabels()))
+}
+
 // pendingNodeCertFilter filters CSRs that need to be reconciled
-func pendingNodeCertFilter(obj runtime.Object) bool {
+func pendingNodeCertFilter(config ClusterMachineApproverConfig, obj runtime.Object) bool {
 	cert, ok := obj.(*certificatesv1.CertificateSigningRequest)
 	// Reconcile unapproved or approved by another controller to update our metrics
-	reconcileRequired := ok && (!isApproved(*cert) || (isRecentlyApproved(*cert) && !isApprovedByCMA(*cert)))
+	reconcileRequired := ok && (!isApproved(*cert) || (isRecentlyApproved(*cert) && !isApprovedByCMA(config, *cert)))
 
 	if !reconcileRequired {
 		return false
@@ -84,14 +424,16 @@ func pendingNodeCertFilter(obj runtime.Object) bool {
 	switch cert.Spec.SignerName {
 	case certificatesv1.KubeletServingSignerName:
 		groupSet := sets.NewString(cert.Spec.Groups...)
-		// Reconcile kubernetes.io/kubelet-serving when it has the system:nodes group
-		if !groupSet.Has(nodeGroup) {
-			klog.V(3).Infof("%s: Ignoring csr because it does not have the system:nodes group", cert.Name)
+		// Reconcile kubernetes.io/kubelet-serving when it has the group required
+		// for node serving certs, which replaces system:nodes when configured
+		if requiredGroup := config.NodeServingCert.RequiredGroup(); !groupSet.Has(requiredGroup) {
+			klog.V(3).Infof("%s: Ignoring csr because it does not have the %s group", cert.Name, requiredGroup)
 			return false
 		}
 	case certificatesv1.KubeAPIServerClientKubeletSignerName:
-		// Reconcile kubernetes.io/kube-apiserver-client-kubelet when it is created by the node bootstrapper
-		if cert.Spec.Username != nodeBootstrapperUsername {
+		// Reconcile kubernetes.io/kube-apiserver-client-kubelet when it is created by the node bootstrapper,
+		// or by a node renewing its client cert if client cert renewals are allowed
+		if cert.Spec.Username != config.NodeClientCert.RequiredBootstrapperUsername() && !(config.NodeClientCert.AllowRenewal && isRequestFromNodeUser(*cert)) {
 			klog.V(3).Infof("%s: Ignoring csr because it is not from the node bootstrapper", cert.Name)
 			return false
 		}
@@ -105,8 +447,55 @@ func pendingNodeCertFilter(obj runtime.Object) bool {
 }
 
 func (m *CertificateApprover) toCSRs(ctx context.Context, obj client.Object) []reconcile.Request {
+	if m.isAPIGroupVersionsConfigMap(obj) {
+		// The API group versions changed, CSRs are re-evaluated against the
+		// machines listed from the new groups.
+		if cm, ok := obj.(*corev1.ConfigMap); ok {
+			ref, _ := m.apiGroupVersionsConfigMap()
+			m.updateAPIGroupVersions(cm, ref)
+		}
+		return m.pendingCSRRequests(ctx)
+	}
+
+	// The kubelet CA ConfigMap changed, drop the cached pool so the next
+	// reconcile picks up the new bundle.
+	m.resetKubeletCA()
+
+	return m.pendingCSRRequests(ctx)
+}
+
+// apiGroupVersionsConfigMapDeleted restores the API group versions given by
+// the --api-group-version option once the API group versions ConfigMap was
+// deleted, and re-evaluates the pending CSRs against them.
+func (m *CertificateApprover) apiGroupVersionsConfigMapDeleted(ctx context.Context, obj client.Object) []reconcile.Request {
+	klog.Infof("API group versions ConfigMap %s/%s was deleted", obj.GetNamespace(), obj.GetName())
+	m.resetAPIGroupVersions()
+	return m.pendingCSRRequests(ctx)
+}
+
+// nodeToCSRs re-evaluates the pending CSRs once a node was created or deleted,
+// while the pending CSRs limit is exceeded. Their reconciles recompute the
+// limit, which depends on the count of nodes, and approve them once it frees up.
+func (m *CertificateApprover) nodeToCSRs(ctx context.Context, obj client.Object) []reconcile.Request {
+	if !pendingLimitExceeded() {
+		return nil
+	}
+
+	klog.Infof("Node %s was created or deleted while the pending CSRs limit is exceeded, re-evaluating pending CSRs", obj.GetName())
+	return m.pendingCSRRequests(ctx)
+}
+
+// pendingLimitExceeded returns whether the pending CSRs exceeded the limit
+// when it was last computed.
+func pendingLimitExceeded() bool {
+	return atomic.LoadUint32(&PendingCSRs) > atomic.LoadUint32(&MaxPendingCSRs)
+}
+
+// pendingCSRRequests returns requests for the node CSRs which are pending, or
+// were recently approved by another controller.
+func (m *CertificateApprover) pendingCSRRequests(ctx context.Context) []reconcile.Request {
 	requests := []reconcile.Request{}
-	csrs, err := listNodeCSRs(ctx, m.WorkloadClient)
+	csrs, err := listNodeCSRs(ctx, m.WorkloadClient, m.csrSelector())
 	if err != nil {
 		klog.Errorf("Unable to list CSRs: %v", err)
 		return nil
@@ -114,7 +503,7 @@ func (m *CertificateApprover) toCSRs(ctx context.Context, obj client.Object) []r
 
 	for _, csr := range csrs {
 		// Only reconcile pending or recently approved by another controller
-		if pendingNodeCertFilter(&csr) {
+		if pendingNodeCertFilter(m.config(), &csr) {
 			requests = append(requests, reconcile.Request{
 				NamespacedName: client.ObjectKey{Name: csr.Name},
 			})
@@ -124,36 +513,176 @@ func (m *CertificateApprover) toCSRs(ctx context.Context, obj client.Object) []r
 	return requests
 }
 
-func caConfigMapFilter(obj runtime.Object, new runtime.Object) bool {
+func caConfigMapFilter(refs []ConfigMapKeyReference, obj runtime.Object, new runtime.Object) bool {
 	cm, ok := obj.(*corev1.ConfigMap)
-	if !ok || cm.Name != kubeletCAConfigMap || cm.Namespace != configNamespace {
+	if !ok {
 		return false
 	}
-	cmData, foundDataOld := cm.Data["ca-bundle.crt"]
+	ref, ok := findConfigMapReference(refs, cm)
+	if !ok {
+		return false
+	}
+	cmData, foundDataOld := cm.Data[ref.Key]
 	if new == nil {
-		return cm.Name == kubeletCAConfigMap &&
-			cm.Namespace == configNamespace &&
-			foundDataOld
+		return foundDataOld
 	}
 	cmNew, ok := new.(*corev1.ConfigMap)
-	cmDataNew, foundDataNew := cmNew.Data["ca-bundle.crt"]
-	return ok &&
-		cm.Name == kubeletCAConfigMap &&
-		cm.Namespace == configNamespace &&
-		foundDataNew &&
+	if !ok {
+		return false
+	}
+	cmDataNew, foundDataNew := cmNew.Data[ref.Key]
+	return foundDataNew &&
 		cmData != cmDataNew
 }
 
-func listNodeCSRs(ctx context.Context, ctrlClient client.Client) ([]certificatesv1.CertificateSigningRequest, error) {
+// findConfigMapReference returns the reference matching the given ConfigMap.
+func findConfigMapReference(refs []ConfigMapKeyReference, cm *corev1.ConfigMap) (ConfigMapKeyReference, bool) {
+	for _, ref := range refs {
+		if cm.Name == ref.Name && cm.Namespace == ref.Namespace {
+			return ref, true
+		}
+	}
+	return ConfigMapKeyReference{}, false
+}
+
+// kubeletCAConfigMaps returns the ConfigMaps the kubelet CA is read from,
+// starting with the kubelet CA managed by the cluster.
+func (m *CertificateApprover) kubeletCAConfigMaps() []ConfigMapKeyReference {
+	refs := []ConfigMapKeyReference{{
+		Namespace: configNamespace,
+		Name:      kubeletCAConfigMap,
+		Key:       kubeletCABundleKey,
+	}}
+
+	if additional := m.config().AdditionalKubeletCAConfigMap; additional.Name != "" {
+		if additional.Namespace == "" {
+			additional.Namespace = configNamespace
+		}
+		if additional.Key == "" {
+			additional.Key = kubeletCABundleKey
+		}
+		refs = append(refs, additional)
+	}
+
+	return refs
+}
+
+// apiGroupVersionsConfigMap returns the ConfigMap the API group versions are
+// read from, if the config references one.
+func (m *CertificateApprover) apiGroupVersionsConfigMap() (ConfigMapKeyReference, bool) {
+	ref := m.config().APIGroupVersionsConfigMap
+	if ref.Name == "" || m.ParseAPIGroupVersion == nil {
+		return ConfigMapKeyReference{}, false
+	}
+	if ref.Namespace == "" {
+		ref.Namespace = configNamespace
+	}
+	if ref.Key == "" {
+		ref.Key = apiGroupVersionsKey
+	}
+	return ref, true
+}
+
+// isAPIGroupVersionsConfigMap returns whether obj is the ConfigMap the API
+// group versions are read from.
+func (m *CertificateApprover) isAPIGroupVersionsConfigMap(obj client.Object) bool {
+	ref, ok := m.apiGroupVersionsConfigMap()
+	return ok && obj.GetNamespace() == ref.Namespace && obj.GetName() == ref.Name
+}
+
+// watchedConfigMaps returns the ConfigMaps whose changes re-evaluate the
+// pending CSRs, i.e. the kubelet CA and API group versions ConfigMaps.
+func (m *CertificateApprover) watchedConfigMaps() []ConfigMapKeyReference {
+	refs := m.kubeletCAConfigMaps()
+	if ref, ok := m.apiGroupVersionsConfigMap(); ok {
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// apiGroupVersions returns the API group versions machines are listed from.
+func (m *CertificateApprover) apiGroupVersions() []schema.GroupVersion {
+	m.apiGroupVersionsMu.RLock()
+	defer m.apiGroupVersionsMu.RUnlock()
+	return m.APIGroupVersions
+}
+
+// updateAPIGroupVersions replaces the API group versions with those listed in
+// the ConfigMap, separated by commas or whitespace. The current API group
+// versions are kept when the list is empty or any entry is invalid.
+func (m *CertificateApprover) updateAPIGroupVersions(cm *corev1.ConfigMap, ref ConfigMapKeyReference) {
+	var apiGroupVersions []schema.GroupVersion
+	for _, entry := range strings.FieldsFunc(cm.Data[ref.Key], func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		apiGroupVersion, err := m.ParseAPIGroupVersion(entry)
+		if err != nil {
+			klog.Errorf("Ignoring API group versions in %s/%s: invalid API group version %q: %v", ref.Namespace, ref.Name, entry, err)
+			return
+		}
+		apiGroupVersions = append(apiGroupVersions, apiGroupVersion)
+	}
+	if len(apiGroupVersions) == 0 {
+		klog.Errorf("Ignoring API group versions in %s/%s: no API group version listed under %s", ref.Namespace, ref.Name, ref.Key)
+		return
+	}
+
+	m.apiGroupVersionsMu.Lock()
+	defer m.apiGroupVersionsMu.Unlock()
+	klog.Infof("Updating API group versions from %s/%s: %v", ref.Namespace, ref.Name, apiGroupVersions)
+	if !m.apiGroupVersionsReplaced {
+		m.optionAPIGroupVersions = m.APIGroupVersions
+		m.apiGroupVersionsReplaced = true
+	}
+	m.APIGroupVersions = apiGroupVersions
+}
+
+// resetAPIGroupVersions restores the API group versions given by the
+// --api-group-version option, replaced by those of the API group versions
+// ConfigMap.
+func (m *CertificateApprover) resetAPIGroupVersions() {
+	m.apiGroupVersionsMu.Lock()
+	defer m.apiGroupVersionsMu.Unlock()
+	if !m.apiGroupVersionsReplaced {
+		return
+	}
+	klog.Infof("Restoring API group versions: %v", m.optionAPIGroupVersions)
+	m.APIGroupVersions = m.optionAPIGroupVersions
+	m.apiGroupVersionsReplaced = false
+}
+
+// syncAPIGroupVersions reads the API group versions from the ConfigMap the
+// config references, restoring those of the --api-group-version option when
+// it references none or the ConfigMap does not exist.
+func (m *CertificateApprover) syncAPIGroupVersions(ctx context.Context) {
+	ref, ok := m.apiGroupVersionsConfigMap()
+	if !ok {
+		m.resetAPIGroupVersions()
+		return
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := m.WorkloadClient.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, cm); apierrors.IsNotFound(err) {
+		m.resetAPIGroupVersions()
+		return
+	} else if err != nil {
+		klog.Errorf("Unable to get API group versions ConfigMap %s/%s, keeping the current API group versions: %v", ref.Namespace, ref.Name, err)
+		return
+	}
+	m.updateAPIGroupVersions(cm, ref)
+}
+
+// listNodeCSRs lists the node CSRs with ctrlClient. It is not paginated, as
+// the cache already holds all CSRs in memory and ignores continue tokens, so
+// that a limit would silently truncate the list.
+func listNodeCSRs(ctx context.Context, ctrlClient client.Client, selector labels.Selector) ([]certificatesv1.CertificateSigningRequest, error) {
 	csrList := &certificatesv1.CertificateSigningRequestList{}
 	csrs := []certificatesv1.CertificateSigningRequest{}
 
-	if err := ctrlClient.List(ctx, csrList, &client.ListOptions{FieldSelector: fields.OneTermEqualSelector(signerNameField, certificatesv1.KubeAPIServerClientKubeletSignerName)}); err != nil {
+	if err := ctrlClient.List(ctx, csrList, &client.ListOptions{FieldSelector: fields.OneTermEqualSelector(signerNameField, certificatesv1.KubeAPIServerClientKubeletSignerName), LabelSelector: selector}); err != nil {
 		return nil, fmt.Errorf("failed to get CSRs: %w", err)
 	}
 	csrs = append(csrs, csrList.Items...)
 
-	if err := ctrlClient.List(ctx, csrList, &client.ListOptions{FieldSelector: fields.OneTermEqualSelector(signerNameField, certificatesv1.KubeletServingSignerName)}); err != nil {
+	if err := ctrlClient.List(ctx, csrList, &client.ListOptions{FieldSelector: fields.OneTermEqualSelector(signerNameField, certificatesv1.KubeletServingSignerName), LabelSelector: selector}); err != nil {
 		return nil, fmt.Errorf("failed to get CSRs: %w", err)
 	}
 	csrs = append(csrs, csrList.Items...)
@@ -163,54 +692,112 @@ func listNodeCSRs(ctx context.Context, ctrlClient client.Client) ([]certificates
 
 func (m *CertificateApprover) Reconcile(ctx context.Context, req ctrl.Request) (reconcile.Result, error) {
 	klog.Infof("Reconciling CSR: %v", req.Name)
+	defer observeReconcileStage(ReconcileStageTotal, now())
+
+	config := m.config()
+
+	if !m.approvalsAllowed.Load() {
+		klog.Infof("%v: Startup delay has not elapsed yet, requeueing", req.Name)
+		return reconcile.Result{RequeueAfter: startupDelayRequeueInterval}, nil
+	}
 
-	csrs, err := listNodeCSRs(ctx, m.WorkloadClient)
+	csrs, err := listNodeCSRs(ctx, m.WorkloadClient, m.csrSelector())
 	if err != nil {
 		klog.Errorf("%v: failed to list CSRs: %v", req.Name, err)
 		return reconcile.Result{}, fmt.Errorf("%v: failed to list CSRs: %w", req.Name, err)
 	}
 
-	machineHandler := &machinehandlerpkg.MachineHandler{
-		Client:    m.ManagementClient,
-		Config:    m.MachineRestCfg,
-		Ctx:       ctx,
-		Namespace: m.MachineNamespace,
-	}
+	// A CSR approved externally only needs the pending CSRs metrics updated,
+	// which does not take listing machines and nodes.
+	var found bool
+	var servingCert *fetchedServingCert
+	for _, csr := range csrs {
+		if csr.Name != req.Name {
+			continue
+		}
+		found = true
+		if isApproved(csr) {
+			recordPendingCSRs(config, csrs)
+			if _, err := m.reconcileCSR(ctx, csr, nil); err != nil {
+				return reconcile.Result{}, fmt.Errorf("could not reconcile CSR: %v", err)
+			}
+			return reconcile.Result{}, nil
+		}
 
-	var machines []machinehandlerpkg.Machine
+		// Steady state serving cert rotations are authorized against the
+		// current serving cert of the kubelet alone, which does not take
+		// listing machines. The pending CSRs limit still applies, and is
+		// checked before the kubelet is dialed.
+		if isServingRenewalCandidate(config, csr) {
+			offLimits, err := m.reconcileLimitsWithoutMachines(ctx, config, csr.Name, csrs)
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+			if offLimits {
+				return reconcile.Result{RequeueAfter: config.PendingLimitRequeueAfter()}, nil
+			}
 
-	for _, apiGroupVersion := range m.APIGroupVersions {
-		newMachines, err := machineHandler.ListMachines(apiGroupVersion)
-		if err != nil {
-			klog.Errorf("%v: Failed to list machines in API group %v: %v", req.Name, apiGroupVersion, err)
-			return reconcile.Result{}, fmt.Errorf("Failed to list machines: %w", err)
+			var authorized bool
+			if authorized, servingCert = m.authorizedAsServingRenewal(ctx, config, csr); authorized {
+				return m.reconcileServingRenewal(ctx, config, csr)
+			}
 		}
-		machines = append(machines, newMachines...)
+	}
+	// The CSR was deleted, or it does not match the CSR selector
+	if !found {
+		klog.Infof("%v: CSR not found among the node CSRs considered, ignoring", req.Name)
+		return reconcile.Result{}, nil
 	}
 
+	listStart := now()
+	machines, err := m.listMachines(ctx, req.Name)
+	observeReconcileStage(ReconcileStageListMachines, listStart)
+	var managementErr *managementClusterError
+	if errors.Is(err, errMachineListTimeout) {
+		return reconcile.Result{RequeueAfter: machineListRequeueInterval}, nil
+	} else if errors.As(err, &managementErr) {
+		requeueAfter := managementRequeueInterval(m.managementFailures.Add(1))
+		klog.Errorf("%v: Management cluster API failed, requeueing in %v: %v", req.Name, requeueAfter, err)
+		return reconcile.Result{RequeueAfter: requeueAfter}, nil
+	} else if err != nil {
+		return reconcile.Result{}, err
+	}
+	m.managementFailures.Store(0)
+
+	machines = m.waitForMachineAddresses(ctx, req.Name, csrs, machines)
+
 	nodes := &corev1.NodeList{}
 	if err := m.WorkloadClient.List(ctx, nodes); err != nil {
 		klog.Errorf("%v: Failed to list Nodes: %v", req.Name, err)
 		return reconcile.Result{}, fmt.Errorf("Failed to get Nodes: %w", err)
 	}
 
-	if offLimits := reconcileLimits(req.Name, machines, nodes, csrs); offLimits {
-		// Stop all reconciliation
-		return reconcile.Result{}, nil
+	if offLimits := reconcileLimits(config, req.Name, machines, nodes, csrs); offLimits {
+		// Stop all reconciliation, but check again later as capacity may be
+		// freed without any event, e.g. when pending CSRs age out.
+		return reconcile.Result{RequeueAfter: config.PendingLimitRequeueAfter()}, nil
 	}
 
 	for _, csr := range csrs {
 		if csr.Name == req.Name {
-			if err := m.reconcileCSR(csr, machines); err != nil {
+			result, err := m.reconcileCSRFetched(ctx, csr, machines, servingCert)
+			if err != nil {
 				return reconcile.Result{}, fmt.Errorf("could not reconcile CSR: %v", err)
 			}
+			if result.RequeueAfter > 0 {
+				return result, nil
+			}
 
 			// Reconcile the limits at the end of a reconcile so that the currently
 			// pending CSRs metric has an up to date value if we approved a CSR.
 			// When an error occurs, we requeue and so update the limits on the
 			// next reconcile.
-			// Don't use a cached client here else we may not have up to date CSRs.
-			return reconcile.Result{}, reconcileLimitsUncached(m.NodeRestCfg, csr.Name, machines, nodes)
+			// Don't use a cached client here else we may not have up to date CSRs,
+			// unless the uncached refresh is disabled to spare the API server.
+			if config.DisableUncachedLimitsRefresh {
+				return reconcile.Result{}, m.reconcileLimitsCached(ctx, config, csr.Name, machines, nodes)
+			}
+			return reconcile.Result{}, m.reconcileLimitsUncached(ctx, config, csr.Name, machines, nodes)
 		}
 	}
 
@@ -219,118 +806,770 @@ func (m *CertificateApprover) Reconcile(ctx context.Context, req ctrl.Request) (
 	return reconcile.Result{}, nil
 }
 
+// isServingRenewalCandidate returns whether a pending CSR may be authorized as
+// the renewal of the current serving cert of its kubelet.
+func isServingRenewalCandidate(config ClusterMachineApproverConfig, csr certificatesv1.CertificateSigningRequest) bool {
+	if csr.Spec.SignerName != certificatesv1.KubeletServingSignerName || !isRequestFromNodeUser(csr) {
+		return false
+	}
+	return !isDenied(csr) && csr.Annotations[skipAnnotation] != "true" && !config.ServingRenewal.Disabled
+}
+
+// authorizedAsServingRenewal returns whether a pending serving CSR is
+// authorized as the renewal of the current serving cert of its kubelet, and
+// the serving cert once fetched, see authorizeServingRenewalOnly.
+func (m *CertificateApprover) authorizedAsServingRenewal(ctx context.Context, config ClusterMachineApproverConfig, csr certificatesv1.CertificateSigningRequest) (bool, *fetchedServingCert) {
+	if !isServingRenewalCandidate(config, csr) {
+		return false, nil
+	}
+
+	parsedCSR, err := parseCSR(&csr)
+	if err != nil {
+		return false, nil
+	}
+	kubeletCA := m.getKubeletCA()
+	if kubeletCA == nil {
+		return false, nil
+	}
+
+	return authorizeServingRenewalOnly(ctx, m.WorkloadClient, config, &csr, parsedCSR, kubeletCA, &m.kubeletDialFailures)
+}
+
+// reconcileServingRenewal approves a serving CSR already authorized as a
+// renewal, and updates the pending CSRs metrics without listing machines and
+// nodes.
+func (m *CertificateApprover) reconcileServingRenewal(ctx context.Context, config ClusterMachineApproverConfig, csr certificatesv1.CertificateSigningRequest) (reconcile.Result, error) {
+	klog.Infof("%v: CSR is authorized as a serving cert renewal, skipping machine listing", csr.Name)
+	result, err := m.reconcileCSRWith(ctx, csr, func(ClusterMachineApproverConfig, *certificatesv1.CertificateSigningRequest, *x509.CertificateRequest, *x509.CertPool) (authorizationResult, error) {
+		return authorizationResult{Authorized: true, Method: authorizedByRenewal}, nil
+	})
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("could not reconcile CSR: %v", err)
+	}
+	if result.RequeueAfter > 0 {
+		return result, nil
+	}
+
+	// Don't use a cached client here else we may not have up to date CSRs,
+	// unless the uncached refresh is disabled to spare the API server.
+	var csrs []certificatesv1.CertificateSigningRequest
+	if config.DisableUncachedLimitsRefresh {
+		csrs, err = listNodeCSRs(ctx, m.WorkloadClient, m.csrSelector())
+	} else {
+		csrs, err = m.getCSRsUncached(ctx)
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	recordPendingCSRs(config, csrs)
+	return reconcile.Result{}, nil
+}
+
 // reconcileLimits will short circut logic if number of pending CSRs is exceeding limit
-func reconcileLimits(csrName string, machines []machinehandlerpkg.Machine, nodes *corev1.NodeList, csrs []certificatesv1.CertificateSigningRequest) bool {
-	maxPending := getMaxPending(machines, nodes)
+func reconcileLimits(config ClusterMachineApproverConfig, csrName string, machines []machinehandlerpkg.Machine, nodes *corev1.NodeList, csrs []certificatesv1.CertificateSigningRequest) bool {
+	pendingNames, maxPending := recordLimits(config, machines, nodes, csrs)
+	return suppressPendingCSRs(csrName, pendingNames, maxPending)
+}
+
+// reconcileLimitsWithoutMachines checks the pending CSRs limit like
+// reconcileLimits, but counts the machines seen in the last reconcile instead
+// of listing them. Nodes are listed from the cache.
+func (m *CertificateApprover) reconcileLimitsWithoutMachines(ctx context.Context, config ClusterMachineApproverConfig, csrName string, csrs []certificatesv1.CertificateSigningRequest) (bool, error) {
+	nodes := &corev1.NodeList{}
+	if err := m.WorkloadClient.List(ctx, nodes); err != nil {
+		klog.Errorf("%v: Failed to list Nodes: %v", csrName, err)
+		return false, fmt.Errorf("Failed to get Nodes: %w", err)
+	}
+	atomic.StoreUint32(&NodesCount, uint32(len(nodes.Items)))
+
+	machines := int(atomic.LoadUint32(&MachinesCount))
+	maxPending := config.PendingCSRsLimit(max(machines, len(nodes.Items)) + maxDiffBetweenPendingCSRsAndMachinesCount)
 	atomic.StoreUint32(&MaxPendingCSRs, uint32(maxPending))
-	pending := recentlyPendingNodeCSRs(csrs)
-	atomic.StoreUint32(&PendingCSRs, uint32(pending))
+	return suppressPendingCSRs(csrName, recordPendingCSRs(config, csrs), maxPending), nil
+}
+
+// suppressPendingCSRs returns whether more CSRs are pending than allowed, in
+// which case they are all suppressed.
+func suppressPendingCSRs(csrName string, pendingNames []string, maxPending int) bool {
+	pending := len(pendingNames)
 	if pending > maxPending {
 		klog.Errorf("%v: Pending CSRs: %d; Max pending allowed: %d. Difference between pending CSRs and machines > %v. Ignoring all CSRs as too many recent pending CSRs seen", csrName, pending, maxPending, maxDiffBetweenPendingCSRsAndMachinesCount)
+		atomic.AddUint32(&SuppressedCSRs, 1)
+		logSuppressedCSRs(pendingNames)
 		return true
 	}
 
 	return false
 }
 
+// recordLimits updates the metrics of the pending CSRs limit, and returns
+// the names of the recently pending CSRs and the maximum allowed.
+func recordLimits(config ClusterMachineApproverConfig, machines []machinehandlerpkg.Machine, nodes *corev1.NodeList, csrs []certificatesv1.CertificateSigningRequest) ([]string, int) {
+	atomic.StoreUint32(&MachinesCount, uint32(len(machines)))
+	atomic.StoreUint32(&MachinesWithoutNodeRefCount, uint32(countMachinesWithoutNodeRef(machines)))
+	atomic.StoreUint32(&NodesCount, uint32(len(nodes.Items)))
+	maxPending := config.PendingCSRsLimit(getMaxPending(machines, nodes))
+	atomic.StoreUint32(&MaxPendingCSRs, uint32(maxPending))
+	return recordPendingCSRs(config, csrs), maxPending
+}
+
+// recordPendingCSRs updates the metrics of the recently pending CSRs and
+// returns their names.
+func recordPendingCSRs(config ClusterMachineApproverConfig, csrs []certificatesv1.CertificateSigningRequest) []string {
+	pendingNames := recentlyPendingNodeCSRNames(config, csrs)
+	atomic.StoreUint32(&PendingCSRs, uint32(len(pendingNames)))
+	oldestPendingAge := oldestRecentlyPendingNodeCSRAge(config, csrs)
+	atomic.StoreUint32(&OldestPendingCSRAgeSeconds, uint32(oldestPendingAge.Seconds()))
+	return pendingNames
+}
+
+// logSuppressedCSRs logs the pending CSRs suppressed by the pending CSRs limit,
+// at most once per suppressedCSRsLogInterval as every reconcile trips the limit.
+func logSuppressedCSRs(pendingNames []string) {
+	last := lastSuppressedCSRsLog.Load()
+	current := now().UnixNano()
+	if last != 0 && time.Duration(current-last) < suppressedCSRsLogInterval {
+		return
+	}
+	if !lastSuppressedCSRsLog.CompareAndSwap(last, current) {
+		// Logged by a concurrent reconcile
+		return
+	}
+
+	names := pendingNames
+	if len(names) > maxSuppressedCSRsLogged {
+		names = names[:maxSuppressedCSRsLogged]
+	}
+	klog.Infof("Approvals are suppressed by the pending CSRs limit, %d pending CSRs are withheld: %s", len(pendingNames), strings.Join(names, ", "))
+}
+
 // reconcileLimitsUncached is used to update the limits using an uncached certificates list.
 // This is used at the end of the approval process to ensure that the limits (and therefore)
 // the metrics are always up to date.
-func reconcileLimitsUncached(cfg *rest.Config, csrName string, machines []machinehandlerpkg.Machine, nodes *corev1.NodeList) error {
-	certClient, err := certificatesv1client.NewForConfig(cfg)
+func (m *CertificateApprover) reconcileLimitsUncached(ctx context.Context, config ClusterMachineApproverConfig, csrName string, machines []machinehandlerpkg.Machine, nodes *corev1.NodeList) error {
+	csrs, err := m.getCSRsUncached(ctx)
 	if err != nil {
-		return fmt.Errorf("could not initialise certificates client: %v", err)
+		return err
 	}
 
-	clientCertificates, err := certClient.CertificateSigningRequests().List(context.Background(), metav1.ListOptions{FieldSelector: clientKubeletFieldSelector})
+	reconcileLimits(config, csrName, machines, nodes, csrs)
+	return nil
+}
+
+// reconcileLimitsCached updates the limits like reconcileLimitsUncached, but
+// from the cached certificates list, which may not reflect the latest
+// approvals yet. The metrics catch up on the next reconcile or resync.
+func (m *CertificateApprover) reconcileLimitsCached(ctx context.Context, config ClusterMachineApproverConfig, csrName string, machines []machinehandlerpkg.Machine, nodes *corev1.NodeList) error {
+	csrs, err := listNodeCSRs(ctx, m.WorkloadClient, m.csrSelector())
 	if err != nil {
-		return fmt.Errorf("could not list CSRs: %v", err)
+		return err
 	}
 
-	servingCertificates, err := certClient.CertificateSigningRequests().List(context.Background(), metav1.ListOptions{FieldSelector: kubeletServingFieldSelector})
+	reconcileLimits(config, csrName, machines, nodes, csrs)
+	return nil
+}
+
+// csrListPageSize bounds the CSRs returned by each list request to the API
+// server, so that clusters with many stale CSRs are not listed at once.
+const csrListPageSize = 500
+
+// listNodeCSRsUncached lists the node CSRs from the API server, bypassing the cache.
+func listNodeCSRsUncached(ctx context.Context, cfg *rest.Config, selector labels.Selector) ([]certificatesv1.CertificateSigningRequest, error) {
+	certClient, err := certificatesv1client.NewForConfig(cfg)
 	if err != nil {
-		return fmt.Errorf("could not list CSRs: %v", err)
+		return nil, fmt.Errorf("could not initialise certificates client: %v", err)
 	}
 
-	csrs := clientCertificates.Items
-	csrs = append(csrs, servingCertificates.Items...)
-	reconcileLimits(csrName, machines, nodes, csrs)
-	return nil
+	csrs := []certificatesv1.CertificateSigningRequest{}
+	for _, fieldSelector := range []string{clientKubeletFieldSelector, kubeletServingFieldSelector} {
+		opts := metav1.ListOptions{FieldSelector: fieldSelector, LabelSelector: selector.String()}
+		if err := listCSRPages(ctx, certClient.CertificateSigningRequests(), opts, func(csr certificatesv1.CertificateSigningRequest) {
+			csrs = append(csrs, csr)
+		}); err != nil {
+			return nil, fmt.Errorf("could not list CSRs: %v", err)
+		}
+	}
+	return csrs, nil
 }
 
-func (m *CertificateApprover) reconcileCSR(csr certificatesv1.CertificateSigningRequest, machines []machinehandlerpkg.Machine) error {
+// csrPageLister lists CSRs from the API server
+type csrPageLister interface {
+	List(ctx context.Context, opts metav1.ListOptions) (*certificatesv1.CertificateSigningRequestList, error)
+}
+
+// listCSRPages lists the CSRs matching opts in pages of csrListPageSize,
+// following continue tokens, and passes each CSR to fn.
+func listCSRPages(ctx context.Context, lister csrPageLister, opts metav1.ListOptions, fn func(certificatesv1.CertificateSigningRequest)) error {
+	csrPager := pager.New(pager.SimplePageFunc(func(opts metav1.ListOptions) (runtime.Object, error) {
+		return lister.List(ctx, opts)
+	}))
+	csrPager.PageSize = csrListPageSize
+
+	return csrPager.EachListItem(ctx, opts, func(obj runtime.Object) error {
+		csr, ok := obj.(*certificatesv1.CertificateSigningRequest)
+		if !ok {
+			return fmt.Errorf("unexpected object %T in CSR list", obj)
+		}
+		fn(*csr)
+		return nil
+	})
+}
+
+// listMachines lists machines in all API groups, bounded by MachineListTimeout.
+// Missing addresses are filled in from the machine address cache.
+func (m *CertificateApprover) listMachines(ctx context.Context, csrName string) ([]machinehandlerpkg.Machine, error) {
+	timeout := m.MachineListTimeout
+	if timeout <= 0 {
+		timeout = defaultMachineListTimeout
+	}
+	listCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	machineHandler := m.getMachineLister(listCtx)
+
+	var machines []machinehandlerpkg.Machine
+
+	for _, apiGroupVersion := range m.apiGroupVersions() {
+		newMachines, err := machineHandler.ListMachines(apiGroupVersion)
+		if err != nil && errors.Is(listCtx.Err(), context.DeadlineExceeded) {
+			klog.Errorf("%v: Timed out after %v listing machines in API group %v, requeueing: %v", csrName, timeout, apiGroupVersion, err)
+			return nil, errMachineListTimeout
+		} else if err != nil {
+			klog.Errorf("%v: Failed to list machines in API group %v: %v", csrName, apiGroupVersion, err)
+			return nil, &managementClusterError{err: fmt.Errorf("Failed to list machines: %w", err)}
+		}
+		machines = append(machines, newMachines...)
+	}
+
+	return m.machineAddresses.apply(machines, m.config().MachineAddresses.CacheTTL.Duration), nil
+}
+
+// waitForMachineAddresses polls the machines, for up to MachineAddressWaitTimeout,
+// while the machine of the node requesting a serving cert has no addresses yet.
+// This covers serving CSRs created before the machine-api populated the addresses.
+// The latest machines are returned, whether or not the addresses appeared.
+func (m *CertificateApprover) waitForMachineAddresses(ctx context.Context, csrName string, csrs []certificatesv1.CertificateSigningRequest, machines []machinehandlerpkg.Machine) []machinehandlerpkg.Machine {
+	if m.MachineAddressWaitTimeout <= 0 {
+		return machines
+	}
+
+	nodeName := servingCSRNodeName(csrs, csrName)
+	if nodeName == "" || !machineMissingAddresses(machines, nodeName) {
+		return machines
+	}
+
+	klog.Infof("%v: Machine for node %s has no addresses yet, waiting up to %v", csrName, nodeName, m.MachineAddressWaitTimeout)
+
+	waitCtx, cancel := context.WithTimeout(ctx, m.MachineAddressWaitTimeout)
+	defer cancel()
+
+	backoff := wait.Backoff{
+		Duration: machineAddressPollInterval,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    math.MaxInt32,
+		Cap:      machineAddressPollMaxInterval,
+	}
+	err := wait.ExponentialBackoffWithContext(waitCtx, backoff, func(ctx context.Context) (bool, error) {
+		newMachines, err := m.listMachines(ctx, csrName)
+		if err != nil {
+			// Keep the machines we have, and retry until the wait times out.
+			return false, nil
+		}
+		machines = newMachines
+		return !machineMissingAddresses(machines, nodeName), nil
+	})
+	if err != nil {
+		klog.Errorf("%v: Gave up waiting for the addresses of the machine for node %s after %v: %v", csrName, nodeName, m.MachineAddressWaitTimeout, err)
+	} else {
+		klog.Infof("%v: Addresses of the machine for node %s appeared", csrName, nodeName)
+	}
+
+	return machines
+}
+
+// servingCSRNodeName returns the name of the node requesting the named serving
+// CSR, or an empty string if it is not a node serving CSR
+func servingCSRNodeName(csrs []certificatesv1.CertificateSigningRequest, csrName string) string {
+	for _, csr := range csrs {
+		if csr.Name != csrName {
+			continue
+		}
+		if csr.Spec.SignerName != certificatesv1.KubeletServingSignerName || !isRequestFromNodeUser(csr) || isApproved(csr) {
+			return ""
+		}
+		return strings.TrimPrefix(csr.Spec.Username, nodeUserPrefix)
+	}
+	return ""
+}
+
+// machineMissingAddresses returns true if a single machine references the
+// node, and it has no addresses
+func machineMissingAddresses(machines []machinehandlerpkg.Machine, nodeName string) bool {
+	machine, err := machinehandlerpkg.FindMatchingMachineFromNodeRef(machines, nodeName)
+	return err == nil && len(machine.Status.Addresses) == 0
+}
+
+// getMachineLister returns the lister used to list machines within ctx
+func (m *CertificateApprover) getMachineLister(ctx context.Context) machineLister {
+	if m.newMachineLister != nil {
+		return m.newMachineLister(ctx)
+	}
+	config := m.config()
+	return &machinehandlerpkg.MachineHandler{
+		Client:                     m.ManagementClient,
+		Config:                     m.MachineRestCfg,
+		Ctx:                        ctx,
+		Namespaces:                 m.MachineNamespaces,
+		ProviderAddressesPath:      config.MachineAddresses.ProviderAddressesFields(),
+		InfrastructureRefAddresses: config.MachineAddresses.InfrastructureRefAddresses,
+	}
+}
+
+func (m *CertificateApprover) reconcileCSR(ctx context.Context, csr certificatesv1.CertificateSigningRequest, machines []machinehandlerpkg.Machine) (reconcile.Result, error) {
+	return m.reconcileCSRFetched(ctx, csr, machines, nil)
+}
+
+// reconcileCSRFetched reconciles the CSR like reconcileCSR, reusing the
+// current serving cert of the kubelet when it was already fetched.
+func (m *CertificateApprover) reconcileCSRFetched(ctx context.Context, csr certificatesv1.CertificateSigningRequest, machines []machinehandlerpkg.Machine, servingCert *fetchedServingCert) (reconcile.Result, error) {
+	return m.reconcileCSRWith(ctx, csr, func(config ClusterMachineApproverConfig, req *certificatesv1.CertificateSigningRequest, parsedCSR *x509.CertificateRequest, kubeletCA *x509.CertPool) (authorizationResult, error) {
+		return authorizeCSR(ctx, m.WorkloadClient, config, machines, req, parsedCSR, kubeletCA, &m.kubeletDialFailures, servingCert)
+	})
+}
+
+// reconcileCSRWith reconciles the CSR, authorizing it with authorize.
+func (m *CertificateApprover) reconcileCSRWith(ctx context.Context, csr certificatesv1.CertificateSigningRequest, authorize func(ClusterMachineApproverConfig, *certificatesv1.CertificateSigningRequest, *x509.CertificateRequest, *x509.CertPool) (authorizationResult, error)) (reconcile.Result, error) {
+	correlationID := getCorrelationID(&csr)
+	// A generated ID is set on the CSR, so that the authorization logs and
+	// the decision all carry the same ID. The annotations are copied, as they
+	// are shared with the cache.
+	if csr.Annotations[correlationIDAnnotation] == "" {
+		annotations := make(map[string]string, len(csr.Annotations)+1)
+		for key, value := range csr.Annotations {
+			annotations[key] = value
+		}
+		annotations[correlationIDAnnotation] = correlationID
+		csr.Annotations = annotations
+	}
+	config := m.config()
+
+	outcome := reconcileOutcomeSkipped
+	if m.AnnotateReconcileOutcome {
+		defer func() { m.annotateReconcileOutcome(ctx, &csr, outcome) }()
+	}
+
 	// If a CSR is approved after being added to the queue, but before we reconcile it,
 	// it may have already been approved. If it has already been approved, trying to
 	// approve it again will result in an error and cause a loop.
 	// Return early if the CSR has been approved externally.
 	if isApproved(csr) {
-		klog.Infof("%v: CSR is already approved", csr.Name)
-		return nil
+		klog.Infof("%v: CSR is already approved (correlation ID %s)", csr.Name, correlationID)
+		// Another approver may be fighting with the machine approver
+		if isRecentlyApproved(csr) && !isApprovedByCMA(config, csr) && m.externalApprovals.firstSeen(csr) {
+			klog.Infof("%v: CSR was approved by another approver (correlation ID %s)", csr.Name, correlationID)
+			atomic.AddUint32(&ExternallyApprovedCSRs, 1)
+		}
+		return reconcile.Result{}, nil
 	}
 
+	// The CSR is left pending, not denied, so that it can be approved manually.
+	if csr.Annotations[skipAnnotation] == "true" {
+		klog.Infof("%v: CSR has annotation %s=true, skipping automatic approval (correlation ID %s)", csr.Name, skipAnnotation, correlationID)
+		return reconcile.Result{}, nil
+	}
+
+	// The CSR may have been denied, e.g. by an external validator during the pre-approval delay.
+	if isDenied(csr) {
+		klog.Infof("%v: CSR is denied (correlation ID %s)", csr.Name, correlationID)
+		return reconcile.Result{}, nil
+	}
+
+	// Give external validators a chance to deny the CSR before approving it.
+	if delay := config.PreApprovalDelay.Duration; delay > 0 {
+		if remaining := delay - now().Sub(csr.CreationTimestamp.Time); remaining > 0 {
+			klog.Infof("%v: Pre-approval delay has not elapsed yet, requeueing in %v (correlation ID %s)", csr.Name, remaining, correlationID)
+			return reconcile.Result{RequeueAfter: remaining}, nil
+		}
+	}
+
+	// A CSR which cannot be parsed never will be, so it is not requeued.
 	parsedCSR, err := parseCSR(&csr)
 	if err != nil {
-		klog.Errorf("%v: Failed to parse csr: %v", csr.Name, err)
-		return fmt.Errorf("error parsing request CSR: %v", err)
+		klog.Errorf("%v: Failed to parse csr, cannot approve (correlation ID %s): %v", csr.Name, correlationID, err)
+		outcome = reconcileOutcomeError
+		atomic.AddUint32(&UnparseableCSRs, 1)
+		return reconcile.Result{}, nil
 	}
 
-	kubeletCA := m.getKubeletCA()
-	if kubeletCA == nil {
-		// This is not a fatal error.  The renewal authorization flow
-		// depending on the existing serving cert will be skipped.
-		klog.Errorf("failed to get kubelet CA")
+	var kubeletCA *x509.CertPool
+	if !config.ServingRenewal.Disabled {
+		kubeletCA = m.getKubeletCA()
+		if kubeletCA == nil {
+			// This is not a fatal error.  The renewal authorization flow
+			// depending on the existing serving cert will be skipped.
+			klog.Errorf("failed to get kubelet CA")
+		}
 	}
 
-	if authorize, err := authorizeCSR(m.WorkloadClient, m.Config, machines, &csr, parsedCSR, kubeletCA); !authorize {
+	klog.Infof("%v: Authorizing CSR (correlation ID %s)", csr.Name, correlationID)
+	authorizeStart := now()
+	result, err := authorize(config, &csr, parsedCSR, kubeletCA)
+	observeReconcileStage(ReconcileStageAuthorize, authorizeStart)
+	// CSRs of disabled flows are denied when configured, as they would
+	// never be approved otherwise.
+	if !result.Authorized && config.DenyDisabledFlowCSRs && errors.Is(err, errFlowDisabled) {
+		message := fmt.Sprintf("This CSR was denied by the Node CSR Approver (cluster-machine-approver): %v", err)
+		if err := deny(ctx, m.NodeRestCfg, &csr, map[string]string{correlationIDAnnotation: correlationID}, config.ApprovalCondition.DisabledFlowDenyReason(), message); err != nil {
+			if errors.Is(err, errDecidedConcurrently) {
+				return reconcile.Result{}, nil
+			}
+			outcome = reconcileOutcomeError
+			return reconcile.Result{}, fmt.Errorf("Unable to deny CSR %s (correlation ID %s): %w", csr.Name, correlationID, err)
+		}
+		klog.Infof("CSR %s denied for signer %s as the flow is disabled (correlation ID %s)", csr.Name, csr.Spec.SignerName, correlationID)
+		outcome = reconcileOutcomeDenied
+		countDecision(csr.Spec.SignerName, outcome, "", correlationID)
+		m.audit(&csr, parsedCSR, outcome, result.Method, err.Error(), correlationID)
+		return reconcile.Result{}, nil
+	}
+
+	if !result.Authorized {
 		// Don't deny since it might be someone else's CSR
-		klog.Infof("%s: CSR not authorized", csr.Name)
-		return err
+		klog.Infof("%s: CSR not authorized for signer %s (correlation ID %s)", csr.Name, csr.Spec.SignerName, correlationID)
+		outcome = reconcileOutcomeNotAuthorized
+		countDecision(csr.Spec.SignerName, outcome, result.machineAPIGroup(), correlationID)
+		reason := result.Reason
+		if reason == "" && err != nil {
+			reason = err.Error()
+		}
+		m.audit(&csr, parsedCSR, outcome, result.Method, reason, correlationID)
+		if result.RequeueAfter > 0 {
+			// The CSR is expected to be authorized later, nothing is wrong
+			// with the node yet.
+			return reconcile.Result{RequeueAfter: result.RequeueAfter}, nil
+		}
+		m.recordServingCSRNotAuthorized(ctx, &csr, reason, correlationID)
+		return reconcile.Result{}, err
 	}
 
-	if err := approve(m.NodeRestCfg, &csr); err != nil {
-		return fmt.Errorf("Unable to approve CSR %s: %w", csr.Name, err)
+	// The CSR is authorized, but its approval is deferred until the startup
+	// observe period has elapsed, as the caches may not be consistent yet.
+	if m.observing.Load() {
+		klog.Infof("%v: CSR would be approved by %s, but approvals are deferred during the startup observe period, requeueing in %v (correlation ID %s)", csr.Name, result.Method, startupObserveRequeueInterval, correlationID)
+		return reconcile.Result{RequeueAfter: startupObserveRequeueInterval}, nil
 	}
-	klog.Infof("CSR %s approved", csr.Name)
 
-	return nil
+	// The CSR is authorized, but its approval is deferred while the rate limit is reached.
+	delay, cancelApproval := m.approvals.reserve(config.MaxApprovalsPerMinute)
+	if delay > 0 {
+		klog.Infof("%v: Approvals are limited to %d per minute, requeueing in %v (correlation ID %s)", csr.Name, config.MaxApprovalsPerMinute, delay, correlationID)
+		return reconcile.Result{RequeueAfter: delay}, nil
+	}
+
+	annotations := map[string]string{
+		authorizedByAnnotation:  string(result.Method),
+		correlationIDAnnotation: correlationID,
+	}
+	if err := approve(ctx, m.NodeRestCfg, &csr, annotations, config.ApprovalCondition); err != nil {
+		// Only actual approvals count against the rate limit.
+		cancelApproval()
+		if errors.Is(err, errDecidedConcurrently) {
+			return reconcile.Result{}, nil
+		}
+		outcome = reconcileOutcomeError
+		return reconcile.Result{}, fmt.Errorf("Unable to approve CSR %s (correlation ID %s): %w", csr.Name, correlationID, err)
+	}
+	klog.Infof("CSR %s approved by %s for signer %s (correlation ID %s)", csr.Name, result.Method, csr.Spec.SignerName, correlationID)
+	outcome = reconcileOutcomeApproved
+	countDecision(csr.Spec.SignerName, outcome, result.machineAPIGroup(), correlationID)
+	atomic.AddUint32(&ApprovedCSRs, 1)
+	m.audit(&csr, parsedCSR, outcome, result.Method, "", correlationID)
+
+	return reconcile.Result{}, nil
+}
+
+// recordServingCSRNotAuthorized records a warning event on the node requesting
+// the serving CSR, if it exists, as the node is more likely to be looked at
+// than the CSR. Nothing is recorded without a reason.
+func (m *CertificateApprover) recordServingCSRNotAuthorized(ctx context.Context, csr *certificatesv1.CertificateSigningRequest, reason, correlationID string) {
+	if m.Recorder == nil || reason == "" || csr.Spec.SignerName != certificatesv1.KubeletServingSignerName || !isRequestFromNodeUser(*csr) {
+		return
+	}
+
+	node := &corev1.Node{}
+	if err := m.WorkloadClient.Get(ctx, client.ObjectKey{Name: strings.TrimPrefix(csr.Spec.Username, nodeUserPrefix)}, node); err != nil {
+		if !apierrors.IsNotFound(err) {
+			klog.Errorf("%v: Failed to get node to record an event on: %v", csr.Name, err)
+		}
+		return
+	}
+
+	m.Recorder.Eventf(node, corev1.EventTypeWarning, eventReasonServingCSRNotAuthorized, "Serving CSR %s could not be authorized (correlation ID %s): %s", csr.Name, correlationID, reason)
+}
+
+// audit records an authorization decision on the CSR in the audit log, if
+// enabled. Failures are only logged, as the decision was already made.
+func (m *CertificateApprover) audit(csr *certificatesv1.CertificateSigningRequest, parsedCSR *x509.CertificateRequest, decision reconcileOutcome, method authorizationMethod, reason, correlationID string) {
+	if m.AuditLog == nil {
+		return
+	}
+
+	var node string
+	if strings.HasPrefix(parsedCSR.Subject.CommonName, nodeUserPrefix) {
+		node = strings.TrimPrefix(parsedCSR.Subject.CommonName, nodeUserPrefix)
+	}
+	if err := m.AuditLog.write(auditRecord{
+		Timestamp:     now().UTC(),
+		CSR:           csr.Name,
+		Node:          node,
+		SignerName:    csr.Spec.SignerName,
+		Decision:      decision,
+		Reason:        reason,
+		Method:        method,
+		CorrelationID: correlationID,
+	}); err != nil {
+		klog.Errorf("%v: Failed to audit %s decision: %v", csr.Name, decision, err)
+	}
 }
 
-// getKubeletCA fetches the kubelet CA from the ConfigMap in the
-// openshift-config-managed namespace.
+// annotateReconcileOutcome records the outcome of a reconcile on the CSR and
+// increments its reconcile count. Failures are only logged.
+func (m *CertificateApprover) annotateReconcileOutcome(ctx context.Context, csr *certificatesv1.CertificateSigningRequest, outcome reconcileOutcome) {
+	count, _ := strconv.Atoi(csr.Annotations[reconcileCountAnnotation])
+
+	base := csr.DeepCopy()
+	setAnnotations(csr, map[string]string{
+		reconcileOutcomeAnnotation: string(outcome),
+		reconcileCountAnnotation:   strconv.Itoa(count + 1),
+	})
+	if err := m.WorkloadClient.Patch(ctx, csr, client.MergeFrom(base)); err != nil {
+		klog.Errorf("%v: Failed to annotate reconcile outcome %s: %v", csr.Name, outcome, err)
+	}
+}
+
+// onlyReconcileOutcomeChanged returns whether a CSR update only changed the
+// reconcile outcome annotations, which must not trigger another reconcile.
+func onlyReconcileOutcomeChanged(oldObj, newObj runtime.Object) bool {
+	oldCSR, ok := oldObj.(*certificatesv1.CertificateSigningRequest)
+	if !ok {
+		return false
+	}
+	newCSR, ok := newObj.(*certificatesv1.CertificateSigningRequest)
+	if !ok {
+		return false
+	}
+
+	strip := func(csr *certificatesv1.CertificateSigningRequest) *certificatesv1.CertificateSigningRequest {
+		csr = csr.DeepCopy()
+		delete(csr.Annotations, reconcileOutcomeAnnotation)
+		delete(csr.Annotations, reconcileCountAnnotation)
+		if len(csr.Annotations) == 0 {
+			csr.Annotations = nil
+		}
+		csr.ResourceVersion = ""
+		csr.ManagedFields = nil
+		return csr
+	}
+
+	return oldCSR.Annotations[reconcileCountAnnotation] != newCSR.Annotations[reconcileCountAnnotation] &&
+		equality.Semantic.DeepEqual(strip(oldCSR), strip(newCSR))
+}
+
+// getCorrelationID returns the ID used to trace the CSR across components.
+// It is read from the correlation ID annotation when present, otherwise it is
+// derived from the CSR UID so that it stays stable across reconciles.
+func getCorrelationID(csr *certificatesv1.CertificateSigningRequest) string {
+	if id := csr.Annotations[correlationIDAnnotation]; id != "" {
+		return id
+	}
+
+	if csr.UID != "" {
+		return string(csr.UID)
+	}
+
+	return string(uuid.NewUUID())
+}
+
+// csrLogName identifies the CSR in the authorization logs, with its
+// correlation ID so that they can be traced to the reconcile decision.
+func csrLogName(csr *certificatesv1.CertificateSigningRequest) string {
+	return fmt.Sprintf("%s (correlation ID %s)", csr.Name, getCorrelationID(csr))
+}
+
+// getKubeletCA returns the kubelet CA, fetching it from the ConfigMap in the
+// openshift-config-managed namespace if it is not cached yet.
 func (m *CertificateApprover) getKubeletCA() *x509.CertPool {
+	m.kubeletCAMu.Lock()
+	defer m.kubeletCAMu.Unlock()
+
+	if m.kubeletCA == nil {
+		m.kubeletCA = m.fetchKubeletCA()
+	}
+
+	return m.kubeletCA
+}
+
+// resetKubeletCA drops the cached kubelet CA, and the kubelet dial failures
+// which may have been caused by the previous CA.
+func (m *CertificateApprover) resetKubeletCA() {
+	m.kubeletCAMu.Lock()
+	defer m.kubeletCAMu.Unlock()
+
+	m.kubeletCA = nil
+	m.kubeletDialFailures.reset()
+}
+
+// fetchKubeletCA fetches the kubelet CA from the ConfigMap in the
+// openshift-config-managed namespace. Certificates from the additional
+// kubelet CA ConfigMap, when configured, are trusted as well.
+func (m *CertificateApprover) fetchKubeletCA() *x509.CertPool {
+	certPool := x509.NewCertPool()
+
+	for i, ref := range m.kubeletCAConfigMaps() {
+		if err := m.appendKubeletCA(certPool, ref); err != nil {
+			if i == 0 {
+				klog.Errorf("failed to get kubelet CA: %v", err)
+				return nil
+			}
+			// The additional CA is best effort, the kubelet CA alone is still usable.
+			klog.Errorf("failed to get additional kubelet CA: %v", err)
+		}
+	}
+
+	return certPool
+}
+
+// appendKubeletCA adds the certificates found in the referenced ConfigMap to the pool.
+func (m *CertificateApprover) appendKubeletCA(certPool *x509.CertPool, ref ConfigMapKeyReference) error {
 	configMap := &corev1.ConfigMap{}
 	key := client.ObjectKey{
-		Namespace: configNamespace,
-		Name:      kubeletCAConfigMap,
+		Namespace: ref.Namespace,
+		Name:      ref.Name,
 	}
 	if err := m.WorkloadClient.Get(context.Background(), key, configMap); err != nil {
-		klog.Errorf("failed to get kubelet CA: %v", err)
-		return nil
+		return err
 	}
 
-	caBundle, ok := configMap.Data["ca-bundle.crt"]
+	caBundle, ok := configMap.Data[ref.Key]
 	if !ok {
-		klog.Errorf("no ca-bundle.crt in %s", kubeletCAConfigMap)
-		return nil
+		return fmt.Errorf("no %s in %s", ref.Key, ref.Name)
 	}
 
-	certPool := x509.NewCertPool()
+	blocks, appended := appendCertsFromPEM(certPool, []byte(caBundle))
+	if appended == 0 {
+		return fmt.Errorf("failed to parse %s in %s", ref.Key, ref.Name)
+	}
+	if appended != blocks {
+		klog.Warningf("Only %d of %d certificates could be parsed from %s in %s, the others are not trusted", appended, blocks, ref.Key, ref.Name)
+	}
 
-	if ok := certPool.AppendCertsFromPEM([]byte(caBundle)); !ok {
-		klog.Errorf("failed to parse ca-bundle.crt in %s", kubeletCAConfigMap)
-		return nil
+	return nil
+}
+
+// appendCertsFromPEM adds the certificates of a PEM bundle to the pool, like
+// AppendCertsFromPEM, and returns the number of CERTIFICATE blocks in the
+// bundle and of certificates added. Other blocks are ignored, while
+// CERTIFICATE blocks with headers or which cannot be parsed are skipped.
+func appendCertsFromPEM(certPool *x509.CertPool, pemCerts []byte) (blocks, appended int) {
+	for len(pemCerts) > 0 {
+		var block *pem.Block
+		block, pemCerts = pem.Decode(pemCerts)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		blocks++
+		if len(block.Headers) != 0 {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		certPool.AddCert(cert)
+		appended++
 	}
+	return blocks, appended
+}
 
-	return certPool
+// approve sets the approved condition on the CSR. The CSR is also annotated
+// with the given annotations, such as the authorization method, for auditing purposes.
+func approve(ctx context.Context, rest *rest.Config, csr *certificatesv1.CertificateSigningRequest, annotations map[string]string, approval ApprovalCondition) error {
+	return updateApproval(ctx, rest, csr, annotations, func(csr *certificatesv1.CertificateSigningRequest) bool {
+		return setApprovedCondition(csr, approval)
+	})
+}
+
+// deny sets the denied condition on the CSR, with the given reason and
+// message. The CSR is also annotated with the given annotations.
+func deny(ctx context.Context, rest *rest.Config, csr *certificatesv1.CertificateSigningRequest, annotations map[string]string, reason, message string) error {
+	return updateApproval(ctx, rest, csr, annotations, func(csr *certificatesv1.CertificateSigningRequest) bool {
+		return setCondition(csr, certificatesv1.CertificateDenied, reason, message)
+	})
+}
+
+// updateApproval updates the approval subresource of the CSR with the
+// annotations and the condition set by setCondition, which returns whether
+// the condition was changed. errDecidedConcurrently is returned when another
+// actor approved or denied the CSR meanwhile.
+func updateApproval(ctx context.Context, rest *rest.Config, csr *certificatesv1.CertificateSigningRequest, annotations map[string]string, setCondition func(*certificatesv1.CertificateSigningRequest) bool) error {
+	certClient, err := certificatesv1client.NewForConfig(rest)
+	if err != nil {
+		return err
+	}
+
+	refetch := false
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// The CSR was modified concurrently, re-apply the approval on its latest version.
+		if refetch {
+			latest, err := certClient.CertificateSigningRequests().Get(ctx, csr.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			*csr = *latest
+			// Another actor decided on the CSR meanwhile, its decision is kept.
+			if isApproved(*csr) || isDenied(*csr) {
+				klog.Infof("%v: CSR was approved or denied concurrently, leaving it as is", csr.Name)
+				return errDecidedConcurrently
+			}
+		}
+		refetch = true
+
+		needsupdate := setAnnotations(csr, annotations)
+		if setCondition(csr) {
+			needsupdate = true
+		}
+		if !needsupdate {
+			return nil
+		}
+
+		_, err := certClient.CertificateSigningRequests().
+			UpdateApproval(ctx, csr.Name, csr, metav1.UpdateOptions{})
+		if apierrors.IsConflict(err) {
+			klog.Infof("%v: Conflict updating CSR approval, retrying: %v", csr.Name, err)
+		}
+		return err
+	})
+}
+
+// setApprovedCondition sets the approved condition on the CSR, with the
+// reason and message of approval. It returns true if the condition was changed.
+func setApprovedCondition(csr *certificatesv1.CertificateSigningRequest, approval ApprovalCondition) bool {
+	return setCondition(csr, certificatesv1.CertificateApproved, approval.ApproveReason(), approval.ApproveMessage())
 }
 
-func approve(rest *rest.Config, csr *certificatesv1.CertificateSigningRequest) error {
-	needsupdate := false
+// setCondition sets the condition of the given type on the CSR, with the
+// reason and message. It returns true if the condition was changed.
+func setCondition(csr *certificatesv1.CertificateSigningRequest, conditionType certificatesv1.RequestConditionType, reason, message string) bool {
 	now := metav1.Now()
 	condition := certificatesv1.CertificateSigningRequestCondition{
-		Type:               certificatesv1.CertificateApproved,
-		Reason:             "NodeCSRApprove",
-		Message:            csrConditionApproveMessage,
+		Type:               conditionType,
+		Reason:             reason,
+		Message:            message,
 		LastUpdateTime:     now,
 		LastTransitionTime: now,
 		Status:             "True",
@@ -338,37 +1577,40 @@ func approve(rest *rest.Config, csr *certificatesv1.CertificateSigningRequest) e
 
 	// Check if the new condition already exists, and change it only if there is a status
 	// transition (otherwise we should preserve the current last transition time).
-	exists := false
 	for i := range csr.Status.Conditions {
 		existingCondition := csr.Status.Conditions[i]
 		if existingCondition.Type == condition.Type {
-			exists = true
 			if !hasSameState(existingCondition, condition) {
 				csr.Status.Conditions[i] = condition
-				needsupdate = true
+				return true
 			}
-			break
+			return false
 		}
 	}
 
 	// If the condition does not exist, set the last transition time and add it.
-	if !exists {
-		csr.Status.Conditions = append(csr.Status.Conditions, condition)
-		needsupdate = true
-	}
+	csr.Status.Conditions = append(csr.Status.Conditions, condition)
+	return true
+}
 
-	if needsupdate {
-		certClient, err := certificatesv1client.NewForConfig(rest)
-		if err != nil {
-			return err
+// setAnnotations sets the given annotations on the CSR, skipping empty values.
+// It returns true if any annotation was changed.
+func setAnnotations(csr *certificatesv1.CertificateSigningRequest, annotations map[string]string) bool {
+	changed := false
+
+	for key, value := range annotations {
+		if value == "" || csr.Annotations[key] == value {
+			continue
 		}
-		if _, err := certClient.CertificateSigningRequests().
-			UpdateApproval(context.Background(), csr.Name, csr, metav1.UpdateOptions{}); err != nil {
-			return err
+
+		if csr.Annotations == nil {
+			csr.Annotations = map[string]string{}
 		}
+		csr.Annotations[key] = value
+		changed = true
 	}
 
-	return nil
+	return changed
 }
 
 // parseCSR extracts the CSR from the API object and decodes it.
@@ -381,6 +1623,18 @@ func parseCSR(obj *certificatesv1.CertificateSigningRequest) (*x509.CertificateR
 	return x509.ParseCertificateRequest(block.Bytes)
 }
 
+// countMachinesWithoutNodeRef returns the number of machines which have no
+// node yet. Their nodes are expected to request client certs.
+func countMachinesWithoutNodeRef(machines []machinehandlerpkg.Machine) int {
+	var count int
+	for _, machine := range machines {
+		if machine.Status.NodeRef == nil {
+			count++
+		}
+	}
+	return count
+}
+
 func getMaxPending(machines []machinehandlerpkg.Machine, nodes *corev1.NodeList) int {
 	return max(len(machines), len(nodes.Items)) + maxDiffBetweenPendingCSRsAndMachinesCount
 }
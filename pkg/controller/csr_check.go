@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
 	"net/url"
@@ -11,14 +12,18 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+	"unicode"
 
 	configv1 "github.com/openshift/api/config/v1"
 	networkv1 "github.com/openshift/api/network/v1"
 	machinehandlerpkg "github.com/openshift/cluster-machine-approver/pkg/machinehandler"
+	"github.com/prometheus/client_golang/prometheus"
 	certificatesv1 "k8s.io/api/certificates/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimachineryvalidation "k8s.io/apimachinery/pkg/api/validation"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
@@ -41,6 +46,16 @@ const (
 	maxMachineClockSkew = 10 * time.Second
 	maxMachineDelta     = 2 * time.Hour
 
+	// defaultReissueHeartbeatStaleAfter is how long the heartbeat of an
+	// existing node must be stale for its client cert to be reissued.
+	defaultReissueHeartbeatStaleAfter = 10 * time.Minute
+
+	defaultMaxSANCount = 64
+
+	// defaultMaxMachineAddressCIDRHostBits bounds machine address ranges to
+	// a /24 for IPv4 and a /120 for IPv6 when unset.
+	defaultMaxMachineAddressCIDRHostBits = 8
+
 	networkTypeOpenShiftSDN = "OpenShiftSDN"
 	networkClusterName      = "cluster"
 )
@@ -59,32 +74,194 @@ var nodeServingGroups = sets.NewString(
 	"system:nodes",
 )
 
+// authorizationMethod identifies which authorization flow approved a CSR.
+type authorizationMethod string
+
+const (
+	// authorizedByRenewal means the CSR was authorized against the kubelet's current serving cert.
+	authorizedByRenewal authorizationMethod = "renewal"
+	// authorizedByMachine means the CSR was authorized against a Machine from the machine-api.
+	authorizedByMachine authorizationMethod = "machine"
+	// authorizedByEgress means the CSR was authorized against the current serving cert and the node's egress IPs.
+	authorizedByEgress authorizationMethod = "egress"
+	// authorizedByClientRenewal means the CSR was authorized as a node renewing its own client cert.
+	authorizedByClientRenewal authorizationMethod = "client-renewal"
+	// authorizedByStaticNode means the CSR was authorized against the addresses of a statically allowed node without a Machine.
+	authorizedByStaticNode authorizationMethod = "static-node"
+	// authorizedByNode means the CSR was authorized against the addresses of an existing node, after its Machine did not authorize it.
+	authorizedByNode authorizationMethod = "node"
+)
+
+// authorizationResult is the outcome of authorizeCSR.
+type authorizationResult struct {
+	// Authorized is true when the CSR may be approved.
+	Authorized bool
+	// Method is the authorization flow which succeeded, empty if not authorized.
+	Method authorizationMethod
+	// Reason explains why the CSR is not authorized, when it is rejected
+	// before any authorization flow is attempted.
+	Reason string
+	// Machine is the machine which authorized the CSR, nil unless authorized
+	// by a machine.
+	Machine *machinehandlerpkg.Machine
+	// RequeueAfter is set when the CSR is not authorized yet, but may be
+	// authorized once it has elapsed.
+	RequeueAfter time.Duration
+}
+
+// machineAPIGroup returns the API group of the machine which authorized the
+// CSR, empty unless authorized by a machine.
+func (r authorizationResult) machineAPIGroup() string {
+	if r.Machine == nil {
+		return ""
+	}
+	return r.Machine.APIGroup()
+}
+
+// reasonAmbiguousUsages means the CSR requests both client and server auth
+// usages, so that it is neither a node client nor a node serving cert.
+const reasonAmbiguousUsages = "CSR requests both client auth and server auth usages"
+
+// Reasons for CSRs requesting usages that do not fit their signer.
+const (
+	reasonServingSignerWithoutServerAuth = "CSR for the " + certificatesv1.KubeletServingSignerName + " signer does not request server auth usage"
+	reasonClientSignerWithoutClientAuth  = "CSR for the " + certificatesv1.KubeAPIServerClientKubeletSignerName + " signer does not request client auth usage"
+)
+
 var now = time.Now
 
+// Metrics are shared between concurrent reconciles, and must only be accessed atomically.
 var MaxPendingCSRs uint32
 var PendingCSRs uint32
+var OldestPendingCSRAgeSeconds uint32
+var MachinesCount uint32
+var NodesCount uint32
+
+// MachinesWithoutNodeRefCount counts the machines seen in the last reconcile
+// which have no node yet, i.e. the provisioning backlog.
+var MachinesWithoutNodeRefCount uint32
+
+// ApprovedCSRs counts the CSRs approved by the machine approver.
+var ApprovedCSRs uint32
+
+// ExternallyApprovedCSRs counts recently approved CSRs that were approved by another approver.
+var ExternallyApprovedCSRs uint32
+
+// AmbiguousUsageCSRs counts CSRs rejected for requesting both client and server auth usages.
+var AmbiguousUsageCSRs uint32
+
+// FreshIssuanceWhenRenewalPossible counts serving CSRs authorized by the
+// machine-api although the node presented a valid serving cert, which it
+// could have renewed.
+var FreshIssuanceWhenRenewalPossible uint32
+
+// SuppressedCSRs counts CSR reconciles suppressed because too many CSRs were pending.
+var SuppressedCSRs uint32
+
+// UnparseableCSRs counts CSRs which could not be parsed, and are never approved.
+var UnparseableCSRs uint32
+
+// Categories of failures to retrieve the serving cert of a kubelet
+const (
+	KubeletConnectFailureDialTimeout  = "dial_timeout"
+	KubeletConnectFailureTLSVerify    = "tls_verify"
+	KubeletConnectFailureNodeNotFound = "node_not_found"
+	KubeletConnectFailureNoInternalIP = "no_internal_ip"
+	KubeletConnectFailureOther        = "other"
+)
+
+// KubeletConnectFailures counts failures to retrieve the serving cert of a
+// kubelet by category. Only the counters are updated, atomically.
+var KubeletConnectFailures = map[string]*uint32{
+	KubeletConnectFailureDialTimeout:  new(uint32),
+	KubeletConnectFailureTLSVerify:    new(uint32),
+	KubeletConnectFailureNodeNotFound: new(uint32),
+	KubeletConnectFailureNoInternalIP: new(uint32),
+	KubeletConnectFailureOther:        new(uint32),
+}
+
+var errNoInternalAddresses = errors.New("no internal addresses")
+
+// errFlowDisabled means the CSR belongs to a flow disabled in the config.
+var errFlowDisabled = errors.New("rejected as the flow is disabled")
+
+// Stages of a reconcile, reported as the stage label of ReconcileDuration
+const (
+	ReconcileStageListMachines   = "list_machines"
+	ReconcileStageGetServingCert = "get_serving_cert"
+	ReconcileStageAuthorize      = "authorize"
+	ReconcileStageTotal          = "total"
+)
+
+// ReconcileDuration observes how long the stages of a reconcile take, to tell
+// slow kubelet connections apart from slow machine lists. It is registered
+// with the other metrics.
+var ReconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "machineapprover_reconcile_duration_seconds",
+	Help:    "Duration in seconds of the stages of a CSR reconcile",
+	Buckets: prometheus.ExponentialBuckets(0.005, 4, 8),
+}, []string{"stage"})
+
+// CSRDecisions counts the authorization decisions made for CSRs, by the
+// signer name of the CSR, the decision, either approved, not-authorized or
+// denied, and the API group of the machine which authorized the CSR, if any. It is
+// registered with the other metrics.
+var CSRDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "machineapprover_csr_decisions_total",
+	Help: "Count of authorization decisions made for CSRs, by signer name, decision and API group of the authorizing machine",
+}, []string{"signer_name", "decision", "machine_api_group"})
+
+// countDecision counts a decision made for a CSR in CSRDecisions, with the
+// correlation ID of the CSR as exemplar to trace the count to the decision.
+func countDecision(signerName string, decision reconcileOutcome, machineAPIGroup, correlationID string) {
+	counter := CSRDecisions.WithLabelValues(signerName, string(decision), machineAPIGroup)
+	if adder, ok := counter.(prometheus.ExemplarAdder); ok {
+		adder.AddWithExemplar(1, prometheus.Labels{"correlation_id": correlationID})
+		return
+	}
+	counter.Inc()
+}
+
+// ClientCSRMachineAge observes how long after the creation of its machine the
+// client CSR of a new node was created, for approved CSRs, to tell how close
+// provisioning gets to the maxMachineDelta window. It is registered with the
+// other metrics.
+var ClientCSRMachineAge = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:    "machineapprover_client_csr_machine_age_seconds",
+	Help:    "Age in seconds of the machine when the client CSR of its node was created, for approved CSRs",
+	Buckets: []float64{60, 120, 300, 600, 900, 1200, 1800, 2700, 3600, 5400, 7200, 10800, 14400},
+})
+
+// observeReconcileStage observes the duration of a reconcile stage started at start.
+func observeReconcileStage(stage string, start time.Time) {
+	ReconcileDuration.WithLabelValues(stage).Observe(now().Sub(start).Seconds())
+}
 
-func validateCSRContents(req *certificatesv1.CertificateSigningRequest, csr *x509.CertificateRequest) (string, error) {
+func validateCSRContents(config ClusterMachineApproverConfig, req *certificatesv1.CertificateSigningRequest, csr *x509.CertificateRequest) (string, error) {
 	if !strings.HasPrefix(req.Spec.Username, nodeUserPrefix) {
-		klog.Infof("%v: CSR does not appear to be a node serving cert", req.Name)
+		klog.Infof("%v: CSR does not appear to be a node serving cert", csrLogName(req))
 		return "", nil
 	}
 
 	nodeAsking := strings.TrimPrefix(req.Spec.Username, nodeUserPrefix)
 	if len(nodeAsking) == 0 {
-		klog.Infof("%v: CSR does not appear to be a node serving cert", req.Name)
+		klog.Infof("%v: CSR does not appear to be a node serving cert", csrLogName(req))
 		return "", nil
 	}
+	if err := validateNodeName(nodeAsking); err != nil {
+		return "", err
+	}
 
 	// Check groups, we need at least:
-	// - system:nodes
+	// - system:nodes, unless configured otherwise
 	// - system:authenticated
 	if len(req.Spec.Groups) < 2 {
 		return "", fmt.Errorf("Too few groups")
 	}
+	requiredGroup := config.NodeServingCert.RequiredGroup()
 	groupSet := sets.NewString(req.Spec.Groups...)
-	if !groupSet.HasAll(nodeGroup, "system:authenticated") {
-		return "", fmt.Errorf("%q not in %q and %q", groupSet, "system:authenticated", nodeGroup)
+	if !groupSet.HasAll(requiredGroup, "system:authenticated") {
+		return "", fmt.Errorf("%q not in %q and %q", groupSet, "system:authenticated", requiredGroup)
 	}
 
 	validationUsageSetLegacy := []string{
@@ -97,22 +274,25 @@ func validateCSRContents(req *certificatesv1.CertificateSigningRequest, csr *x50
 		string(certificatesv1.UsageServerAuth),
 	}
 
-	// Check usages, we need only:
-	// - digital signature
-	// - key encipherment
-	if len(req.Spec.Usages) != len(validationUsageSetLegacy) && len(req.Spec.Usages) != len(validationUsageSet) {
-		// - server auth
-		return "", fmt.Errorf("Too few usages")
-	}
-
 	usages := make([]string, len(req.Spec.Usages))
 	for i := range req.Spec.Usages {
 		usages[i] = string(req.Spec.Usages[i])
 	}
-
 	usageSet := sets.NewString(usages...)
-	if !usageSet.HasAll(validationUsageSet...) && !usageSet.HasAll(validationUsageSetLegacy...) {
-		return "", fmt.Errorf("%q is missing usages", usageSet)
+
+	// Check usages, we need only:
+	// - digital signature
+	// - key encipherment
+	// - server auth
+	// unless they match a configured combination.
+	if !config.NodeServingCert.AllowsAdditionalUsages(usageSet) {
+		if len(req.Spec.Usages) != len(validationUsageSetLegacy) && len(req.Spec.Usages) != len(validationUsageSet) {
+			return "", fmt.Errorf("Too few usages")
+		}
+
+		if !usageSet.HasAll(validationUsageSet...) && !usageSet.HasAll(validationUsageSetLegacy...) {
+			return "", fmt.Errorf("%q is missing usages", usageSet)
+		}
 	}
 
 	// Check subject: O = system:nodes, CN = system:node:ip-10-0-152-205.ec2.internal
@@ -147,39 +327,83 @@ func validateCSRContents(req *certificatesv1.CertificateSigningRequest, csr *x50
 // 5. CSR creation timestamp is very close to machine creation timestamp
 // 6. CSR is meant for node client auth based on usage, CN, etc
 //
+// Client certificate renewals requested by a node for its own identity are only
+// authorized when allowed by the config, see authorizeNodeClientRenewal.
+//
 // For server certificates:
 // Names contained in the CSR are checked against addresses in the corresponding node's machine status.
+// The current serving cert of the kubelet is only fetched when fetched is nil.
 func authorizeCSR(
+	ctx context.Context,
 	c client.Client,
 	config ClusterMachineApproverConfig,
 	machines []machinehandlerpkg.Machine,
 	req *certificatesv1.CertificateSigningRequest,
 	csr *x509.CertificateRequest,
 	ca *x509.CertPool,
-) (bool, error) {
+	dialFailures *kubeletDialFailureCache,
+	fetched *fetchedServingCert,
+) (authorizationResult, error) {
 	if req == nil || csr == nil {
 		klog.Errorf("authorizeCSR invalid request")
-		return false, nil
+		return authorizationResult{}, nil
+	}
+
+	if message, reason := rejectBeforeAuthorization(config, req, csr); message != "" {
+		//TODO: set annotation/emit event here.
+		klog.Errorf("%v: %s, cannot approve", csrLogName(req), message)
+		if reason == reasonAmbiguousUsages {
+			atomic.AddUint32(&AmbiguousUsageCSRs, 1)
+		}
+		return authorizationResult{Reason: reason}, nil
 	}
 
 	if isNodeClientCert(req, csr) {
 		if config.NodeClientCert.Disabled {
-			klog.Errorf("%v: CSR rejected as the flow is disabled", req.Name)
-			return false, fmt.Errorf("CSR %s for node client cert rejected as the flow is disabled", req.Name)
+			klog.Errorf("%v: CSR rejected as the flow is disabled", csrLogName(req))
+			// Only CSRs of the node bootstrapper are denied, renewals by
+			// existing nodes are left pending, as denying them would take the
+			// nodes down once their current certs expire.
+			if !isReqFromNodeBootstrapper(config, req) {
+				return authorizationResult{}, fmt.Errorf("CSR %s for node client cert %v", req.Name, errFlowDisabled)
+			}
+			return authorizationResult{}, fmt.Errorf("CSR %s for node client cert %w", req.Name, errFlowDisabled)
+		}
+		if isRequestFromNodeUser(*req) {
+			if !config.NodeClientCert.AllowRenewal {
+				klog.Infof("%v: CSR appears to be a node client cert renewal, which is not allowed", csrLogName(req))
+				return authorizationResult{}, nil
+			}
+			authorized, err := authorizeNodeClientRenewal(c, machines, req, csr)
+			if !authorized {
+				return authorizationResult{}, err
+			}
+			return authorizationResult{Authorized: true, Method: authorizedByClientRenewal}, nil
+		}
+		nodeMachine, err := authorizeNodeClientCSR(c, config, machines, req, csr)
+		if nodeMachine == nil {
+			return authorizationResult{}, err
 		}
-		return authorizeNodeClientCSR(c, machines, req, csr)
+		return authorizationResult{Authorized: true, Method: authorizedByMachine, Machine: nodeMachine}, err
 	}
 
-	klog.Infof("%v: CSR does not appear to be client csr", req.Name)
+	klog.Infof("%v: CSR does not appear to be client csr", csrLogName(req))
 	// node serving cert validation after this point
 
-	nodeAsking, err := validateCSRContents(req, csr)
+	nodeAsking, err := validateCSRContents(config, req, csr)
 	if nodeAsking == "" || err != nil {
 		if err != nil {
 			//TODO: set annotation/emit event here.
-			klog.Errorf("%v: Unrecoverable serving cert error, cannot approve: %v", req.Name, err)
+			klog.Errorf("%v: Unrecoverable serving cert error, cannot approve: %v", csrLogName(req), err)
+		}
+		return authorizationResult{}, nil
+	}
+
+	if config.NodeServingCert.RejectDuplicateSANs {
+		if err := validateUniqueSANs(buildAddressIndex(machines), nodeAsking, csr); err != nil {
+			klog.Errorf("%v: Serving cert requests an address of another node, cannot approve: %v", csrLogName(req), err)
+			return authorizationResult{}, err
 		}
-		return false, nil
 	}
 
 	var approvalErrors []error
@@ -189,115 +413,306 @@ func authorizeCSR(
 	// the presented cert against the current Kubelet CA, will result in
 	// fallback to the original flow relying on the machine-api.
 	//
-	// This is only supported if we were given a CA to verify against.
+	// This is only supported if we were given a CA to verify against, and
+	// the renewal flow has not been disabled.
 	var servingCert *x509.Certificate
-	if ca != nil {
+	if config.ServingRenewal.Disabled {
+		klog.Infof("%v: Serving cert renewal flow is disabled", csrLogName(req))
+	} else if ca != nil {
 		var err error
-		servingCert, err = getServingCert(c, nodeAsking, ca)
+		if fetched != nil {
+			servingCert, err = fetched.cert, fetched.err
+		} else {
+			servingCert, err = getServingCert(ctx, c, config, nodeAsking, ca, dialFailures)
+		}
 		if err != nil {
-			klog.Infof("Failed to retrieve current serving cert: %v", err)
+			klog.Infof("%v: Failed to retrieve current serving cert: %v", csrLogName(req), err)
 		}
 	}
 
 	x509VerificationOpts := x509.VerifyOptions{Roots: ca}
 	if servingCert != nil {
-		klog.Infof("Found existing serving cert for %s", nodeAsking)
-
-		if err := authorizeServingRenewal(nodeAsking, csr, servingCert, x509VerificationOpts); err != nil {
+		klog.Infof("%v: Found existing serving cert for %s", csrLogName(req), nodeAsking)
+
+		if err := authorizeServingRenewal(nodeAsking, csr, servingCert, x509VerificationOpts, config.ServingRenewal.RenewalWindow.Duration); errors.Is(err, errPrematureRenewal) {
+			// Falling back to the machine-api would approve it anyway. The
+			// CSR is requeued until the current cert enters the window.
+			requeueAfter := servingCert.NotAfter.Add(-config.ServingRenewal.RenewalWindow.Duration).Sub(now())
+			if requeueAfter < minPrematureRenewalRequeue {
+				requeueAfter = minPrematureRenewalRequeue
+			}
+			klog.Infof("%v: Premature serving cert renewal, cannot approve yet, requeueing in %v: %v", csrLogName(req), requeueAfter.Round(time.Second), err)
+			return authorizationResult{Reason: err.Error(), RequeueAfter: requeueAfter}, nil
+		} else if err != nil {
 			approvalErrors = append(approvalErrors, err)
-			klog.Infof("Could not use current serving cert for renewal: %v", err)
-			klog.Infof("Current SAN Values: %v, CSR SAN Values: %v",
+			klog.Infof("%v: Could not use current serving cert for renewal: %v", csrLogName(req), err)
+			klog.Infof("%v: Current SAN Values: %v, CSR SAN Values: %v", csrLogName(req),
 				certSANs(servingCert), csrSANs(csr))
+		} else if err := authorizeStrictServingRenewal(c, config, machines, req, nodeAsking, csr); err != nil {
+			approvalErrors = append(approvalErrors, err)
+			klog.Infof("%v: Could not use current serving cert for strict renewal: %v", csrLogName(req), err)
 		} else {
 			// No error, the renewal is authorized.
-			return true, nil
+			return authorizationResult{Authorized: true, Method: authorizedByRenewal}, nil
 		}
 	}
 
-	// Fall back to the original machine-api based authorization scheme.
-	klog.Infof("Falling back to machine-api authorization for %s", nodeAsking)
-	if err := authorizeServingCertWithMachine(machines, req, nodeAsking, csr); err != nil {
-		approvalErrors = append(approvalErrors, err)
-		klog.Infof("Could not use Machine for serving cert authorization: %v", err)
+	// Fall back to the original machine-api based authorization scheme,
+	// unless only renewals are allowed.
+	if config.MachineAPIAuthorization.Disabled {
+		klog.Infof("%v: Machine-api authorization is disabled, only serving cert renewals can be approved", csrLogName(req))
+		approvalErrors = append(approvalErrors, fmt.Errorf("machine-api authorization is disabled, only serving cert renewals can be approved"))
+	} else if config.IsStaticNode(nodeAsking) && !hasMachineForNode(machines, nodeAsking) {
+		klog.Infof("%v: Falling back to node addresses authorization for static node %s", csrLogName(req), nodeAsking)
+		if err := authorizeServingCertWithNode(c, req, nodeAsking, csr); err != nil {
+			approvalErrors = append(approvalErrors, err)
+			klog.Infof("%v: Could not use Node for serving cert authorization: %v", csrLogName(req), err)
+		} else {
+			// No error means the node addresses were able to authorize the cert
+			return authorizationResult{Authorized: true, Method: authorizedByStaticNode}, nil
+		}
 	} else {
-		// No error means the machine was able to authorize the cert
-		return true, nil
+		klog.Infof("%v: Falling back to machine-api authorization for %s", csrLogName(req), nodeAsking)
+		if targetMachine, err := authorizeServingCertWithMachine(c, config, machines, req, nodeAsking, csr); err != nil {
+			approvalErrors = append(approvalErrors, err)
+			klog.Infof("%v: Could not use Machine for serving cert authorization: %v", csrLogName(req), err)
+
+			if config.NodeServingCert.AllowNodeAddresses {
+				klog.Infof("%v: Falling back to node addresses authorization for %s", csrLogName(req), nodeAsking)
+				if err := authorizeServingCertWithNode(c, req, nodeAsking, csr); err != nil {
+					approvalErrors = append(approvalErrors, err)
+					klog.Infof("%v: Could not use Node for serving cert authorization: %v", csrLogName(req), err)
+				} else {
+					// No error means the node addresses were able to authorize the cert
+					return authorizationResult{Authorized: true, Method: authorizedByNode}, nil
+				}
+			}
+		} else {
+			if servingCert != nil {
+				// The kubelet requested a cert which differs from its valid
+				// current one, instead of renewing it.
+				klog.Warningf("%v: Node %s has a valid serving cert, but requested a different cert instead of renewing it", csrLogName(req), nodeAsking)
+				atomic.AddUint32(&FreshIssuanceWhenRenewalPossible, 1)
+			}
+			// No error means the machine was able to authorize the cert
+			return authorizationResult{Authorized: true, Method: authorizedByMachine, Machine: targetMachine}, nil
+		}
 	}
 
 	egressEnabled, err := needsEgressCheck(c)
 	if err != nil {
-		klog.Infof("Could not determine if egress enabled: %v", err)
-		return false, fmt.Errorf("could not determine if egress enabled: %v", err)
+		klog.Infof("%v: Could not determine if egress enabled: %v", csrLogName(req), err)
+		return authorizationResult{}, fmt.Errorf("could not determine if egress enabled: %v", err)
 	}
 
 	if servingCert != nil && egressEnabled {
-		klog.Infof("Falling back to serving cert renewal with Egress IP checks")
+		klog.Infof("%v: Falling back to serving cert renewal with Egress IP checks", csrLogName(req))
 		if err := authorizeServingRenewalWithEgressIPs(c, nodeAsking, csr, servingCert, x509VerificationOpts); err != nil {
 			approvalErrors = append(approvalErrors, err)
-			klog.Infof("Could not use current serving cert and egress IPs for renewal: %v", err)
+			klog.Infof("%v: Could not use current serving cert and egress IPs for renewal: %v", csrLogName(req), err)
 		} else {
 			// No error means the machine was able to authorize the cert
-			return true, nil
+			return authorizationResult{Authorized: true, Method: authorizedByEgress}, nil
 		}
 	}
 
-	return false, fmt.Errorf("could not authorize CSR: exhausted all authorization methods: %v", kerrors.NewAggregate(approvalErrors))
+	return authorizationResult{}, fmt.Errorf("could not authorize CSR: exhausted all authorization methods: %v", kerrors.NewAggregate(approvalErrors))
 }
 
-func authorizeNodeClientCSR(c client.Client, machines []machinehandlerpkg.Machine, req *certificatesv1.CertificateSigningRequest, csr *x509.CertificateRequest) (bool, error) {
-	if !isReqFromNodeBootstrapper(req) {
-		klog.Infof("%v: CSR does not appear to be a valid node bootstrapper client cert request", req.Name)
-		return false, nil
+// validateNodeName checks that a node name requested in a CSR is a valid
+// object name, rejecting e.g. control characters before it is used in lookups
+// and logs.
+func validateNodeName(nodeName string) error {
+	if errs := apimachineryvalidation.NameIsDNSSubdomain(nodeName, false); len(errs) > 0 {
+		return fmt.Errorf("Invalid node name %q: %s", nodeName, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// authorizeNodeClientCSR returns the machine of the node when the client CSR
+// of the node is authorized, nil otherwise.
+func authorizeNodeClientCSR(c client.Client, config ClusterMachineApproverConfig, machines []machinehandlerpkg.Machine, req *certificatesv1.CertificateSigningRequest, csr *x509.CertificateRequest) (*machinehandlerpkg.Machine, error) {
+	if !isReqFromNodeBootstrapper(config, req) {
+		klog.Infof("%v: CSR does not appear to be a valid node bootstrapper client cert request", csrLogName(req))
+		return nil, nil
 	}
 
 	nodeName := strings.TrimPrefix(csr.Subject.CommonName, nodeUserPrefix)
 	if len(nodeName) == 0 {
 		//TODO: set annotation/emit event here.
-		klog.Errorf("%v: CSR does not appear to be a valid node bootstrapper client cert request", req.Name)
-		return false, nil
+		klog.Errorf("%v: CSR does not appear to be a valid node bootstrapper client cert request", csrLogName(req))
+		return nil, nil
+	}
+	if err := validateNodeName(nodeName); err != nil {
+		klog.Errorf("%v: %v, cannot approve", csrLogName(req), err)
+		return nil, nil
 	}
 
-	if err := c.Get(context.Background(), client.ObjectKey{Name: nodeName}, &corev1.Node{}); err != nil && !apierrors.IsNotFound(err) {
+	var nodeExists bool
+	node := &corev1.Node{}
+	if err := c.Get(context.Background(), client.ObjectKey{Name: nodeName}, node); err != nil && !apierrors.IsNotFound(err) {
 		// possible transient API error, requeue
-		klog.Errorf("%v: unable to get node %s error: %v", req.Name, nodeName, err)
-		return false, fmt.Errorf("failed get existing nodes %s", nodeName)
+		klog.Errorf("%v: unable to get node %s error: %v", csrLogName(req), nodeName, err)
+		return nil, fmt.Errorf("failed get existing nodes %s", nodeName)
 	} else if err == nil {
+		if !config.NodeClientCert.AllowClientCertReissueForExistingNode {
+			//TODO: set annotation/emit event here.
+			klog.Errorf("%v: node %s already exists, cannot approve", csrLogName(req), nodeName)
+			return nil, nil
+		}
+		nodeExists = true
+	}
+
+	nodeMachine, err := machinehandlerpkg.FindMatchingMachineFromInternalDNSWithDomainSuffix(machines, nodeName, config.NodeClientCert.NodeNameDomainSuffix)
+	if err != nil && nodeExists && !errors.Is(err, machinehandlerpkg.ErrAmbiguousMachineMatch) {
+		nodeMachine, err = findMatchingMachineFromProviderID(c, config, machines, nodeName, err)
+	}
+	if errors.Is(err, machinehandlerpkg.ErrAmbiguousMachineMatch) {
+		klog.Errorf("%v: ambiguous machine match for node %s, cannot approve: %v", csrLogName(req), nodeName, err)
+		return nil, nil
+	} else if err != nil {
 		//TODO: set annotation/emit event here.
-		klog.Errorf("%v: node %s already exists, cannot approve", req.Name, nodeName)
-		return false, nil
+		klog.Errorf("%v: failed to find machine for node %s, cannot approve", csrLogName(req), nodeName)
+		return nil, fmt.Errorf("failed to find machine for node %s", nodeName)
 	}
 
-	nodeMachine, err := machinehandlerpkg.FindMatchingMachineFromInternalDNS(machines, nodeName)
-	if err != nil {
+	if !config.NodeClientCert.AllowsMachinePhase(nodeMachine.Status.Phase) {
 		//TODO: set annotation/emit event here.
-		klog.Errorf("%v: failed to find machine for node %s, cannot approve", req.Name, nodeName)
-		return false, fmt.Errorf("failed to find machine for node %s", nodeName)
+		klog.Errorf("%v: machine %s for node %s is in phase %q, not one of %v, cannot approve", csrLogName(req), nodeMachine.Name, nodeName, nodeMachine.Status.Phase, config.NodeClientCert.RequireMachinePhases)
+		return nil, nil
+	}
+
+	if nodeExists {
+		if !authorizeNodeClientReissue(config, req, node, nodeMachine) {
+			return nil, nil
+		}
+		return nodeMachine, nil
 	}
 
 	if nodeMachine.Status.NodeRef != nil {
 		//TODO: set annotation/emit event here.
-		klog.Errorf("%v: machine for node %v already has node ref, cannot approve", req.Name, nodeMachine.Status.NodeRef)
-		return false, nil
+		klog.Errorf("%v: machine %s for node %s already has node ref %s, cannot approve", csrLogName(req), nodeMachine.Name, nodeName, nodeMachine.Status.NodeRef.Name)
+		return nil, nil
 	}
 
 	start := nodeMachine.ObjectMeta.CreationTimestamp.Add(-maxMachineClockSkew)
-	end := nodeMachine.ObjectMeta.CreationTimestamp.Add(maxMachineDelta)
+	end := nodeMachine.ObjectMeta.CreationTimestamp.Add(config.NodeClientCert.MachineDeltaLimit())
+	if !inTimeSpan(start, end, req.CreationTimestamp.Time) {
+		//TODO: set annotation/emit event here.
+		klog.Errorf("%v: CSR creation time %s not in range (%s, %s)", csrLogName(req), req.CreationTimestamp.Time, start, end)
+		return nil, nil
+	}
+
+	ClientCSRMachineAge.Observe(req.CreationTimestamp.Sub(nodeMachine.CreationTimestamp.Time).Seconds())
+	return nodeMachine, nil // approve node client cert
+}
+
+// authorizeNodeClientReissue authorizes reissuing a client cert to a node
+// which already exists, e.g. when it is re-bootstrapped with the same name.
+// The machine must still reference the node, the CSR must be recent, and the
+// node must be unhealthy, as a healthy kubelet renews its own client cert
+// rather than bootstrapping again.
+func authorizeNodeClientReissue(config ClusterMachineApproverConfig, req *certificatesv1.CertificateSigningRequest, node *corev1.Node, nodeMachine *machinehandlerpkg.Machine) bool {
+	nodeName := node.Name
+	if nodeMachine.Status.NodeRef == nil || nodeMachine.Status.NodeRef.Name != nodeName {
+		//TODO: set annotation/emit event here.
+		klog.Errorf("%v: node %s already exists and its machine does not reference it, cannot approve", csrLogName(req), nodeName)
+		return false
+	}
+
+	currentTime := now()
+	start := currentTime.Add(-config.NodeClientCert.MachineDeltaLimit())
+	end := currentTime.Add(maxMachineClockSkew)
 	if !inTimeSpan(start, end, req.CreationTimestamp.Time) {
 		//TODO: set annotation/emit event here.
-		klog.Errorf("%v: CSR creation time %s not in range (%s, %s)", req.Name, req.CreationTimestamp.Time, start, end)
+		klog.Errorf("%v: CSR creation time %s not in range (%s, %s)", csrLogName(req), req.CreationTimestamp.Time, start, end)
+		return false
+	}
+
+	if healthy, heartbeat := nodeHealthy(node, currentTime, config.NodeClientCert.ReissueHeartbeatStaleLimit()); healthy {
+		//TODO: set annotation/emit event here.
+		klog.Errorf("%v: node %s already exists and is Ready, last heartbeat at %s, cannot approve", csrLogName(req), nodeName, heartbeat)
+		return false
+	}
+
+	klog.Infof("%v: Reissuing client cert for existing node %s", csrLogName(req), nodeName)
+	return true
+}
+
+// nodeHealthy returns whether the node is Ready with a heartbeat more recent
+// than staleAfter, and the time of its last heartbeat.
+func nodeHealthy(node *corev1.Node, currentTime time.Time, staleAfter time.Duration) (bool, time.Time) {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type != corev1.NodeReady {
+			continue
+		}
+		heartbeat := condition.LastHeartbeatTime.Time
+		return condition.Status == corev1.ConditionTrue && currentTime.Sub(heartbeat) <= staleAfter, heartbeat
+	}
+	return false, time.Time{}
+}
+
+// authorizeNodeClientRenewal will authorize the renewal of a kubelet's client
+// certificate, requested using its current client certificate.
+//
+// 1. User is the node the certificate is requested for
+// 2. User is in the system:nodes and system:authenticated groups
+// 3. Node exists
+// 4. A machine references the node
+func authorizeNodeClientRenewal(c client.Client, machines []machinehandlerpkg.Machine, req *certificatesv1.CertificateSigningRequest, csr *x509.CertificateRequest) (bool, error) {
+	nodeName := strings.TrimPrefix(csr.Subject.CommonName, nodeUserPrefix)
+	if len(nodeName) == 0 || req.Spec.Username != csr.Subject.CommonName {
+		klog.Errorf("%v: client cert renewal requested by %q for %q, cannot approve", csrLogName(req), req.Spec.Username, csr.Subject.CommonName)
+		return false, nil
+	}
+	if err := validateNodeName(nodeName); err != nil {
+		klog.Errorf("%v: %v, cannot approve client cert renewal", csrLogName(req), err)
+		return false, nil
+	}
+
+	if !sets.NewString(req.Spec.Groups...).HasAll(nodeGroup, "system:authenticated") {
+		klog.Errorf("%v: client cert renewal is missing groups %s and system:authenticated, cannot approve", csrLogName(req), nodeGroup)
 		return false, nil
 	}
 
-	return true, nil // approve node client cert
+	if err := c.Get(context.Background(), client.ObjectKey{Name: nodeName}, &corev1.Node{}); apierrors.IsNotFound(err) {
+		klog.Errorf("%v: node %s does not exist, cannot approve client cert renewal", csrLogName(req), nodeName)
+		return false, nil
+	} else if err != nil {
+		// possible transient API error, requeue
+		klog.Errorf("%v: unable to get node %s error: %v", csrLogName(req), nodeName, err)
+		return false, fmt.Errorf("failed get existing nodes %s", nodeName)
+	}
+
+	if _, err := machinehandlerpkg.FindMatchingMachineFromNodeRef(machines, nodeName); errors.Is(err, machinehandlerpkg.ErrAmbiguousMachineMatch) {
+		klog.Errorf("%v: ambiguous machine match for node %s, cannot approve client cert renewal: %v", csrLogName(req), nodeName, err)
+		return false, nil
+	} else if err != nil {
+		// Return error so we requeue in case we're racing with node linker.
+		klog.Errorf("%v: failed to find machine for node %s, cannot approve client cert renewal", csrLogName(req), nodeName)
+		return false, fmt.Errorf("failed to find machine for node %s", nodeName)
+	}
+
+	return true, nil
 }
 
+// errPrematureRenewal is returned for renewals of a serving cert which does
+// not expire within the renewal window yet.
+var errPrematureRenewal = errors.New("premature serving cert renewal")
+
+// minPrematureRenewalRequeue is the minimum delay before a premature serving
+// cert renewal is reconciled again.
+const minPrematureRenewalRequeue = time.Second
+
 // authorizeServingRenewal will authorize the renewal of a kubelet's serving
 // certificate.
 //
 // The current certificate must be signed by the current CA and not expired.
 // The common name on the current certificate must match the expected value.
 // All Subject Alternate Name values must match between CSR and current cert.
-func authorizeServingRenewal(nodeName string, csr *x509.CertificateRequest, currentCert *x509.Certificate, options x509.VerifyOptions) error {
+// When renewalWindow is set, the current certificate must expire within it,
+// otherwise an errPrematureRenewal error is returned.
+func authorizeServingRenewal(nodeName string, csr *x509.CertificateRequest, currentCert *x509.Certificate, options x509.VerifyOptions, renewalWindow time.Duration) error {
 	if err := verifyCertificateCommonName(nodeName, csr, currentCert, options); err != nil {
 		return err
 	}
@@ -312,9 +727,101 @@ func authorizeServingRenewal(nodeName string, csr *x509.CertificateRequest, curr
 		return fmt.Errorf("CSR Subject Alternate Name values do not match current certificate")
 	}
 
+	if renewalWindow > 0 {
+		currentTime := options.CurrentTime
+		if currentTime.IsZero() {
+			currentTime = now()
+		}
+		if remaining := currentCert.NotAfter.Sub(currentTime); remaining > renewalWindow {
+			return fmt.Errorf("%w: current serving cert expires in %v, beyond the renewal window of %v", errPrematureRenewal, remaining.Round(time.Second), renewalWindow)
+		}
+	}
+
+	return nil
+}
+
+// authorizeStrictServingRenewal checks, when strict renewal is enabled, that the
+// names requested in a renewed serving cert are still assigned to the node's
+// machine. The current serving cert may have been issued for addresses which
+// have since been removed from the machine.
+func authorizeStrictServingRenewal(c client.Client, config ClusterMachineApproverConfig, machines []machinehandlerpkg.Machine, req *certificatesv1.CertificateSigningRequest, nodeAsking string, csr *x509.CertificateRequest) error {
+	if !config.NodeServingCert.StrictRenewal {
+		return nil
+	}
+
+	if _, err := authorizeServingCertWithMachine(c, config, machines, req, nodeAsking, csr); err != nil {
+		return fmt.Errorf("strict renewal: %v", err)
+	}
+
 	return nil
 }
 
+// rejectBeforeAuthorization returns why the CSR is rejected before any
+// authorization flow is attempted, empty if it is not. The reason is set for
+// the rejections reported in the authorization result.
+func rejectBeforeAuthorization(config ClusterMachineApproverConfig, req *certificatesv1.CertificateSigningRequest, csr *x509.CertificateRequest) (message, reason string) {
+	// Reject pathological CSRs before any SAN is compared or logged
+	if count, limit := countSANs(csr), config.SANCountLimit(); count > limit {
+		return fmt.Sprintf("CSR requests %d SANs, more than the maximum of %d", count, limit), ""
+	}
+
+	if err := config.KeyPolicy.Check(csr.PublicKey); err != nil {
+		return fmt.Sprintf("CSR public key is not allowed by the key policy: %v", err), ""
+	}
+
+	// Neither flow could authorize such a CSR, it is rejected explicitly to
+	// tell a misconfigured kubelet apart from other failures.
+	if hasClientAndServerUsages(req) {
+		return fmt.Sprintf("%s, which is ambiguous: %v", reasonAmbiguousUsages, req.Spec.Usages), reasonAmbiguousUsages
+	}
+
+	if reason := signerUsagesMismatch(req); reason != "" {
+		return fmt.Sprintf("%s: %v", reason, req.Spec.Usages), reason
+	}
+
+	return "", ""
+}
+
+// fetchedServingCert is the current serving cert of a kubelet, or the error
+// fetching it, so that it is fetched once per reconcile.
+type fetchedServingCert struct {
+	cert *x509.Certificate
+	err  error
+}
+
+// authorizeServingRenewalOnly returns whether a serving CSR is authorized as
+// the renewal of the current serving cert of its kubelet, without the
+// machines authorizeCSR may take. It applies the same checks as authorizeCSR
+// ahead of the renewal, but without logging or counting rejections, as a CSR
+// not authorized here is authorized by authorizeCSR as usual. Strict renewals
+// and duplicate SAN checks take machines, so are never authorized here.
+//
+// The serving cert is returned once fetched, for authorizeCSR to reuse it.
+func authorizeServingRenewalOnly(ctx context.Context, c client.Client, config ClusterMachineApproverConfig, req *certificatesv1.CertificateSigningRequest, csr *x509.CertificateRequest, ca *x509.CertPool, dialFailures *kubeletDialFailureCache) (bool, *fetchedServingCert) {
+	if config.ServingRenewal.Disabled || config.NodeServingCert.StrictRenewal || config.NodeServingCert.RejectDuplicateSANs {
+		return false, nil
+	}
+	if message, _ := rejectBeforeAuthorization(config, req, csr); message != "" || isNodeClientCert(req, csr) {
+		return false, nil
+	}
+
+	nodeAsking, err := validateCSRContents(config, req, csr)
+	if nodeAsking == "" || err != nil {
+		return false, nil
+	}
+	servingCert, err := getServingCert(ctx, c, config, nodeAsking, ca, dialFailures)
+	fetched := &fetchedServingCert{cert: servingCert, err: err}
+	if err != nil {
+		klog.V(2).Infof("%v: Failed to retrieve current serving cert, authorizing CSR with machines: %v", csrLogName(req), err)
+		return false, fetched
+	}
+	if err := authorizeServingRenewal(nodeAsking, csr, servingCert, x509.VerifyOptions{Roots: ca}, config.ServingRenewal.RenewalWindow.Duration); err != nil {
+		klog.V(2).Infof("%v: Could not use current serving cert for renewal, authorizing CSR with machines: %v", csrLogName(req), err)
+		return false, fetched
+	}
+	return true, fetched
+}
+
 // authorizeServingRenewal will authorize the renewal of a kubelet's serving
 // certificate.
 //
@@ -348,9 +855,16 @@ func authorizeServingRenewalWithEgressIPs(c client.Client, nodeName string, csr
 		return fmt.Errorf("could not fetch hostsubnet: %v", err)
 	}
 
-	allowedIPAddresses := currentCert.IPAddresses
+	// Egress IPs of both families are parsed, so that they are compared in
+	// canonical form by subsetIPAddresses however they are written, as
+	// dual-stack host subnets may mix them. Unparsable egress IPs allow
+	// nothing. The current cert addresses are copied so that they are not
+	// modified.
+	allowedIPAddresses := append([]net.IP{}, currentCert.IPAddresses...)
 	for _, ipAddr := range hostSubnet.EgressIPs {
-		allowedIPAddresses = append(allowedIPAddresses, net.ParseIP(string(ipAddr)))
+		if ip := net.ParseIP(strings.TrimSpace(string(ipAddr))); ip != nil {
+			allowedIPAddresses = append(allowedIPAddresses, ip)
+		}
 	}
 
 	allowedCIDRs := []*net.IPNet{}
@@ -369,26 +883,290 @@ func authorizeServingRenewalWithEgressIPs(c client.Client, nodeName string, csr
 	return nil
 }
 
-func authorizeServingCertWithMachine(machines []machinehandlerpkg.Machine, req *certificatesv1.CertificateSigningRequest, nodeAsking string, csr *x509.CertificateRequest) error {
+// authorizeServingCertWithMachine returns the machine of the node when the
+// serving CSR SANs match the machine addresses, or an error otherwise.
+func authorizeServingCertWithMachine(c client.Client, config ClusterMachineApproverConfig, machines []machinehandlerpkg.Machine, req *certificatesv1.CertificateSigningRequest, nodeAsking string, csr *x509.CertificateRequest) (*machinehandlerpkg.Machine, error) {
 	// Check that we have a registered node with the request name
 	targetMachine, err := machinehandlerpkg.FindMatchingMachineFromNodeRef(machines, nodeAsking)
-	if err != nil {
-		klog.Errorf("%v: Serving Cert: No target machine for node %q", req.Name, nodeAsking)
+	if err != nil && !errors.Is(err, machinehandlerpkg.ErrAmbiguousMachineMatch) {
+		targetMachine, err = findMatchingMachineFromProviderID(c, config, machines, nodeAsking, err)
+	}
+	if err != nil && !errors.Is(err, machinehandlerpkg.ErrAmbiguousMachineMatch) {
+		targetMachine, err = findMatchingMachineFromLabel(config, machines, nodeAsking, err)
+	}
+	if errors.Is(err, machinehandlerpkg.ErrAmbiguousMachineMatch) {
+		klog.Errorf("%v: Serving Cert: Ambiguous target machine for node %q: %v", csrLogName(req), nodeAsking, err)
+		return nil, fmt.Errorf("Ambiguous machine for node: %v", err)
+	} else if err != nil {
+		klog.Errorf("%v: Serving Cert: No target machine for node %q", csrLogName(req), nodeAsking)
 		//TODO: set annotation/emit event here.
 		// Return error so we requeue in case we're racing with node linker.
-		return fmt.Errorf("Unable to find machine for node")
+		return nil, fmt.Errorf("Unable to find machine for node")
 	}
 
+	extraIPs, err := extraAllowedNodeIPs(c, config, nodeAsking)
+	if err != nil {
+		klog.Errorf("%v: Serving Cert: Unable to get extra allowed IPs for node %q: %v", csrLogName(req), nodeAsking, err)
+		return nil, fmt.Errorf("Unable to get extra allowed IPs for node: %v", err)
+	}
+
+	// The machine addresses are copied so that they are not modified.
+	addresses := uniqueAddresses(append(append([]corev1.NodeAddress{}, targetMachine.Status.Addresses...), extraIPs...))
+	if config.NodeServingCert.AllowMachineAddressCIDRs {
+		taken, err := otherAddressIPs(c, machines, targetMachine, nodeAsking)
+		if err != nil {
+			klog.Errorf("%v: Serving Cert: Unable to get the addresses of other nodes: %v", csrLogName(req), err)
+			return nil, fmt.Errorf("Unable to get the addresses of other nodes: %v", err)
+		}
+		addresses = append(addresses, csrAddressesInCIDRs(csrLogName(req), addresses, csr, config.NodeServingCert.MachineAddressCIDRHostBitsLimit(), taken)...)
+	}
+	if config.NodeServingCert.AllowHostNameShortNames {
+		addresses = uniqueAddresses(append(addresses, hostNameShortNames(addresses)...))
+	}
+	if err := validateSANsMatchAddresses(req, addresses, csr, "machine"); err != nil {
+		if v := klog.V(2); v.Enabled() {
+			missing, unrequested := sanAddressDiff(csr, addresses)
+			v.Infof("%v: Serving Cert: SANs not in machine addresses: %v, machine addresses not requested: %v", req.Name, missing, unrequested)
+		}
+		return nil, err
+	}
+
+	return targetMachine, nil
+}
+
+// hostNameShortNames returns the short names of the Hostname addresses, i.e.
+// their first label, as Hostname addresses. Hostnames which are not
+// qualified, or are IP addresses, have no short name.
+func hostNameShortNames(addresses []corev1.NodeAddress) []corev1.NodeAddress {
+	var shortNames []corev1.NodeAddress
+	for _, addr := range addresses {
+		if addr.Type != corev1.NodeHostName || net.ParseIP(addr.Address) != nil {
+			continue
+		}
+		if shortName, _, qualified := strings.Cut(addr.Address, "."); qualified && shortName != "" {
+			shortNames = append(shortNames, corev1.NodeAddress{Type: corev1.NodeHostName, Address: shortName})
+		}
+	}
+	return shortNames
+}
+
+// csrAddressesInCIDRs returns the IP addresses of the CSR which are within
+// the IP addresses given in CIDR notation, as addresses of the same type.
+// Ranges with more than maxHostBits host bits are ignored, and so are the
+// taken IPs, i.e. the addresses of other machines and nodes, so that a range
+// never authorizes the IPs of the node's neighbours.
+func csrAddressesInCIDRs(csrName string, addresses []corev1.NodeAddress, csr *x509.CertificateRequest, maxHostBits int, taken sets.String) []corev1.NodeAddress {
+	var inCIDRs []corev1.NodeAddress
+	for _, addr := range addresses {
+		if addr.Type != corev1.NodeInternalIP && addr.Type != corev1.NodeExternalIP {
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(addr.Address)
+		if err != nil {
+			continue
+		}
+		if ones, bits := cidr.Mask.Size(); bits-ones > maxHostBits {
+			klog.Errorf("%v: Serving Cert: Ignoring machine address range %s wider than %d host bits", csrName, addr.Address, maxHostBits)
+			continue
+		}
+		for _, ipAddr := range csr.IPAddresses {
+			if taken.Has(ipAddr.String()) {
+				klog.Errorf("%v: Serving Cert: IP address %s in machine address range %s belongs to another machine or node", csrName, ipAddr, addr.Address)
+				continue
+			}
+			if ipInSet([]*net.IPNet{cidr}, nil, ipAddr) {
+				inCIDRs = append(inCIDRs, corev1.NodeAddress{Type: addr.Type, Address: ipAddr.String()})
+			}
+		}
+	}
+	return inCIDRs
+}
+
+// otherAddressIPs returns the IP addresses of the machines other than the
+// target machine, and of the nodes other than the given node, in canonical
+// form. Addresses in CIDR notation are not IPs and are left out.
+func otherAddressIPs(c client.Client, machines []machinehandlerpkg.Machine, targetMachine *machinehandlerpkg.Machine, nodeName string) (sets.String, error) {
+	taken := sets.NewString()
+	insert := func(addresses []corev1.NodeAddress) {
+		for _, addr := range addresses {
+			if ip := net.ParseIP(addr.Address); ip != nil {
+				taken.Insert(ip.String())
+			}
+		}
+	}
+
+	for _, machine := range machines {
+		if machine.Namespace == targetMachine.Namespace && machine.Name == targetMachine.Name {
+			continue
+		}
+		insert(machine.Status.Addresses)
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := c.List(context.Background(), nodes); err != nil {
+		return nil, err
+	}
+	for _, node := range nodes.Items {
+		if node.Name == nodeName {
+			continue
+		}
+		insert(node.Status.Addresses)
+	}
+
+	return taken, nil
+}
+
+// sanAddressDiff returns the SANs of the CSR which are not in the addresses,
+// and the addresses which are not requested by the CSR, both sorted. Names
+// are compared as in validateSANsMatchAddresses, ignoring case and a trailing dot.
+func sanAddressDiff(csr *x509.CertificateRequest, addresses []corev1.NodeAddress) ([]string, []string) {
+	normalize := func(name string) string {
+		if ip := net.ParseIP(name); ip != nil {
+			return ip.String()
+		}
+		return strings.ToLower(strings.TrimSuffix(name, "."))
+	}
+
+	sans := sets.NewString()
+	for _, san := range csrSANs(csr) {
+		sans.Insert(normalize(san))
+	}
+	names := sets.NewString()
+	for _, name := range machineAddressNames(addresses) {
+		names.Insert(normalize(name))
+	}
+
+	return sans.Difference(names).List(), names.Difference(sans).List()
+}
+
+// machineAddressNames returns the addresses as a slice of strings.
+func machineAddressNames(addresses []corev1.NodeAddress) []string {
+	names := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		names = append(names, address.Address)
+	}
+	return names
+}
+
+// extraAllowedNodeIPs returns the extra IP addresses allowed in the serving
+// CSRs of the node, listed in the ConfigMap referenced by the config under the
+// node name, separated by commas or whitespace. Invalid IPs are ignored.
+func extraAllowedNodeIPs(c client.Client, config ClusterMachineApproverConfig, nodeName string) ([]corev1.NodeAddress, error) {
+	ref := config.ExtraAllowedNodeIPsConfigMap
+	if ref.Name == "" {
+		return nil, nil
+	}
+	if ref.Namespace == "" {
+		ref.Namespace = configNamespace
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, configMap); apierrors.IsNotFound(err) {
+		klog.Infof("Extra allowed node IPs ConfigMap %s/%s not found", ref.Namespace, ref.Name)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var addresses []corev1.NodeAddress
+	for _, ip := range strings.FieldsFunc(configMap.Data[nodeName], func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			klog.Errorf("Ignoring invalid extra allowed IP %q for node %s in %s/%s", ip, nodeName, ref.Namespace, ref.Name)
+			continue
+		}
+		addresses = append(addresses, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: parsed.String()})
+	}
+
+	return addresses, nil
+}
+
+// findMatchingMachineFromProviderID matches the node with a machine by its
+// provider ID, when enabled. The original error matching the node by name is
+// returned when the node or a matching machine is not found.
+func findMatchingMachineFromProviderID(c client.Client, config ClusterMachineApproverConfig, machines []machinehandlerpkg.Machine, nodeName string, nameErr error) (*machinehandlerpkg.Machine, error) {
+	if !config.MachineAPIAuthorization.MatchProviderID {
+		return nil, nameErr
+	}
+
+	node := &corev1.Node{}
+	if err := c.Get(context.Background(), client.ObjectKey{Name: nodeName}, node); err != nil {
+		klog.Infof("Unable to get node %s to match its provider ID: %v", nodeName, err)
+		return nil, nameErr
+	}
+
+	machine, err := machinehandlerpkg.FindMatchingMachineFromProviderID(machines, node.Spec.ProviderID)
+	switch {
+	case errors.Is(err, machinehandlerpkg.ErrAmbiguousMachineMatch):
+		return nil, err
+	case err != nil:
+		return nil, nameErr
+	}
+	if machine.Status.NodeRef != nil && machine.Status.NodeRef.Name != nodeName {
+		klog.Errorf("Machine %s/%s with provider ID %s references node %s, not %s", machine.Namespace, machine.Name, node.Spec.ProviderID, machine.Status.NodeRef.Name, nodeName)
+		return nil, nameErr
+	}
+
+	klog.Infof("Matched node %s with machine %s/%s by provider ID %s", nodeName, machine.Namespace, machine.Name, node.Spec.ProviderID)
+	return machine, nil
+}
+
+// findMatchingMachineFromLabel falls back to matching the node with the
+// machine labeled with its name, when enabled. nameErr is returned when no
+// machine matches, or the matching machine references another node.
+func findMatchingMachineFromLabel(config ClusterMachineApproverConfig, machines []machinehandlerpkg.Machine, nodeName string, nameErr error) (*machinehandlerpkg.Machine, error) {
+	labelKey := config.MachineAPIAuthorization.NodeNameLabel
+	if labelKey == "" {
+		return nil, nameErr
+	}
+
+	machine, err := machinehandlerpkg.FindMatchingMachineFromLabel(machines, labelKey, nodeName)
+	switch {
+	case errors.Is(err, machinehandlerpkg.ErrAmbiguousMachineMatch):
+		return nil, err
+	case err != nil:
+		return nil, nameErr
+	}
+	if machine.Status.NodeRef != nil && machine.Status.NodeRef.Name != nodeName {
+		klog.Errorf("Machine %s/%s with label %s=%s references node %s, not %s", machine.Namespace, machine.Name, labelKey, nodeName, machine.Status.NodeRef.Name, nodeName)
+		return nil, nameErr
+	}
+
+	klog.Infof("Matched node %s with machine %s/%s by label %s", nodeName, machine.Namespace, machine.Name, labelKey)
+	return machine, nil
+}
+
+// hasMachineForNode returns whether any machine references the node.
+func hasMachineForNode(machines []machinehandlerpkg.Machine, nodeName string) bool {
+	_, err := machinehandlerpkg.FindMatchingMachineFromNodeRef(machines, nodeName)
+	return err == nil || errors.Is(err, machinehandlerpkg.ErrAmbiguousMachineMatch)
+}
+
+// authorizeServingCertWithNode authorizes a serving CSR against the addresses
+// the node reports itself, for statically allowed nodes without a Machine.
+func authorizeServingCertWithNode(c client.Client, req *certificatesv1.CertificateSigningRequest, nodeAsking string, csr *x509.CertificateRequest) error {
+	node := &corev1.Node{}
+	if err := c.Get(context.Background(), client.ObjectKey{Name: nodeAsking}, node); err != nil {
+		klog.Errorf("%v: Serving Cert: Unable to get node %q: %v", csrLogName(req), nodeAsking, err)
+		return fmt.Errorf("Unable to get node %s: %v", nodeAsking, err)
+	}
+
+	return validateSANsMatchAddresses(req, uniqueAddresses(node.Status.Addresses), csr, "node")
+}
+
+// validateSANsMatchAddresses checks that every SAN of the CSR matches one of
+// the addresses of the given owner, a machine or a node.
+func validateSANsMatchAddresses(req *certificatesv1.CertificateSigningRequest, addresses []corev1.NodeAddress, csr *x509.CertificateRequest, owner string) error {
 	// SAN checks for both DNS and IPs, e.g.,
 	// DNS:ip-10-0-152-205, DNS:ip-10-0-152-205.ec2.internal, IP Address:10.0.152.205, IP Address:10.0.152.205
-	// All names in the request must correspond to addresses assigned to a single machine.
+	// All names in the request must correspond to addresses assigned to a single machine or node.
 	for _, san := range csr.DNSNames {
 		if len(san) == 0 {
 			continue
 		}
 		var attemptedAddresses []string
 		var foundSan bool
-		for _, addr := range targetMachine.Status.Addresses {
+		for _, addr := range addresses {
 			switch addr.Type {
 			case corev1.NodeInternalDNS, corev1.NodeExternalDNS, corev1.NodeHostName:
 				if strings.EqualFold(san, strings.TrimSuffix(addr.Address, ".")) {
@@ -405,8 +1183,8 @@ func authorizeServingCertWithMachine(machines []machinehandlerpkg.Machine, req *
 			//TODO: set annotation/emit event here.
 			// return error so we requeue, in case machine network is out of date
 			// for some reason
-			klog.Errorf("%v: DNS name '%s' not in machine names: %s", req.Name, san, strings.Join(attemptedAddresses, " "))
-			return fmt.Errorf("DNS name '%s' not in machine names: %s", san, strings.Join(attemptedAddresses, " "))
+			klog.Errorf("%v: DNS name '%s' not in %s names: %s", csrLogName(req), san, owner, strings.Join(attemptedAddresses, " "))
+			return fmt.Errorf("DNS name '%s' not in %s names: %s", san, owner, strings.Join(attemptedAddresses, " "))
 		}
 	}
 
@@ -416,7 +1194,7 @@ func authorizeServingCertWithMachine(machines []machinehandlerpkg.Machine, req *
 		}
 		var attemptedAddresses []string
 		var foundSan bool
-		for _, addr := range targetMachine.Status.Addresses {
+		for _, addr := range addresses {
 			switch corev1.NodeAddressType(addr.Type) {
 			case corev1.NodeInternalIP, corev1.NodeExternalIP:
 				if san.String() == addr.Address {
@@ -433,14 +1211,85 @@ func authorizeServingCertWithMachine(machines []machinehandlerpkg.Machine, req *
 			//TODO: set annotation/emit event here.
 			// return error so we requeue, in case machine network is out of date
 			// for some reason
-			klog.Errorf("%v: IP address '%s' not in machine addresses: %s", req.Name, san, strings.Join(attemptedAddresses, " "))
-			return fmt.Errorf("IP address '%s' not in machine addresses: %s", san, strings.Join(attemptedAddresses, " "))
+			klog.Errorf("%v: IP address '%s' not in %s addresses: %s", csrLogName(req), san, owner, strings.Join(attemptedAddresses, " "))
+			return fmt.Errorf("IP address '%s' not in %s addresses: %s", san, owner, strings.Join(attemptedAddresses, " "))
+		}
+	}
+
+	return nil
+}
+
+// uniqueAddresses returns the addresses without duplicates of the same type,
+// in their original order. Some machines report the same address many times.
+func uniqueAddresses(addresses []corev1.NodeAddress) []corev1.NodeAddress {
+	seen := make(map[corev1.NodeAddress]bool, len(addresses))
+	unique := make([]corev1.NodeAddress, 0, len(addresses))
+	for _, addr := range addresses {
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		unique = append(unique, addr)
+	}
+	return unique
+}
+
+// buildAddressIndex maps each address of the given machines to the names of
+// the nodes referenced by the machines owning it. Machines without a node
+// reference are ignored.
+func buildAddressIndex(machines []machinehandlerpkg.Machine) map[string]sets.String {
+	index := map[string]sets.String{}
+
+	for _, machine := range machines {
+		if machine.Status.NodeRef == nil {
+			continue
+		}
+
+		for _, addr := range machine.Status.Addresses {
+			address := normalizeAddress(addr.Address)
+			if address == "" {
+				continue
+			}
+			if _, ok := index[address]; !ok {
+				index[address] = sets.NewString()
+			}
+			index[address].Insert(machine.Status.NodeRef.Name)
+		}
+	}
+
+	return index
+}
+
+// validateUniqueSANs returns an error if any DNS name or IP address requested in
+// the CSR is owned by exactly one node which is not nodeName.
+func validateUniqueSANs(index map[string]sets.String, nodeName string, csr *x509.CertificateRequest) error {
+	sans := []string{}
+	sans = append(sans, csr.DNSNames...)
+	for _, ip := range csr.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	for _, san := range sans {
+		owners, ok := index[normalizeAddress(san)]
+		if !ok || owners.Len() != 1 || owners.Has(nodeName) {
+			continue
 		}
+
+		return fmt.Errorf("address '%s' belongs to node %s", san, owners.List()[0])
 	}
 
 	return nil
 }
 
+// normalizeAddress lowercases DNS names and strips any trailing dot so that
+// addresses can be compared with SAN values.
+func normalizeAddress(address string) string {
+	if ip := net.ParseIP(address); ip != nil {
+		return ip.String()
+	}
+	return strings.ToLower(strings.TrimSuffix(address, "."))
+}
+
 func verifyCertificateCommonName(nodeName string, csr *x509.CertificateRequest, currentCert *x509.Certificate, options x509.VerifyOptions) error {
 	// options.Roots should contain root certificates
 	if csr == nil || currentCert == nil || options.Roots == nil {
@@ -466,8 +1315,9 @@ func verifyCertificateCommonName(nodeName string, csr *x509.CertificateRequest,
 	return nil
 }
 
-func isReqFromNodeBootstrapper(req *certificatesv1.CertificateSigningRequest) bool {
-	return req.Spec.Username == nodeBootstrapperUsername && nodeBootstrapperGroups.Equal(sets.NewString(req.Spec.Groups...))
+func isReqFromNodeBootstrapper(config ClusterMachineApproverConfig, req *certificatesv1.CertificateSigningRequest) bool {
+	return req.Spec.Username == config.NodeClientCert.RequiredBootstrapperUsername() &&
+		config.NodeClientCert.RequiredBootstrapperGroups().Equal(sets.NewString(req.Spec.Groups...))
 }
 
 func inTimeSpan(start, end, check time.Time) bool {
@@ -483,6 +1333,15 @@ func isApproved(csr certificatesv1.CertificateSigningRequest) bool {
 	return false
 }
 
+func isDenied(csr certificatesv1.CertificateSigningRequest) bool {
+	for _, condition := range csr.Status.Conditions {
+		if condition.Type == certificatesv1.CertificateDenied {
+			return true
+		}
+	}
+	return false
+}
+
 func isRecentlyApproved(csr certificatesv1.CertificateSigningRequest) bool {
 	// assumes we are scheduled on the master meaning our clock is the same
 	currentTime := now()
@@ -497,22 +1356,29 @@ func isRecentlyApproved(csr certificatesv1.CertificateSigningRequest) bool {
 	return false
 }
 
-func isApprovedByCMA(csr certificatesv1.CertificateSigningRequest) bool {
+// isApprovedByCMA returns whether the CSR was approved by the machine
+// approver, with the configured approval message or the default one.
+func isApprovedByCMA(config ClusterMachineApproverConfig, csr certificatesv1.CertificateSigningRequest) bool {
 	for _, condition := range csr.Status.Conditions {
 		if condition.Type == certificatesv1.CertificateApproved {
-			return condition.Message == csrConditionApproveMessage
+			return condition.Message == config.ApprovalCondition.ApproveMessage() || condition.Message == csrConditionApproveMessage
 		}
 	}
 	return false
 }
 
-func recentlyPendingNodeCSRs(csrs []certificatesv1.CertificateSigningRequest) int {
+func recentlyPendingNodeCSRs(config ClusterMachineApproverConfig, csrs []certificatesv1.CertificateSigningRequest) int {
+	return len(recentlyPendingNodeCSRNames(config, csrs))
+}
+
+// recentlyPendingNodeCSRNames returns the names of the recently pending node CSRs.
+func recentlyPendingNodeCSRNames(config ClusterMachineApproverConfig, csrs []certificatesv1.CertificateSigningRequest) []string {
 	// assumes we are scheduled on the master meaning our clock is the same
 	currentTime := now()
 	start := currentTime.Add(-maxPendingDelta)
 	end := currentTime.Add(maxMachineClockSkew)
 
-	var pending int
+	var pending []string
 
 	for _, csr := range csrs {
 		// ignore "old" CSRs
@@ -520,14 +1386,42 @@ func recentlyPendingNodeCSRs(csrs []certificatesv1.CertificateSigningRequest) in
 			continue
 		}
 
-		if pendingNodeCertFilter(&csr) {
-			pending++
+		if pendingNodeCertFilter(config, &csr) {
+			pending = append(pending, csr.Name)
 		}
 	}
 
 	return pending
 }
 
+// oldestRecentlyPendingNodeCSRAge returns the age of the oldest recently pending
+// node CSR, or zero if there are none.
+func oldestRecentlyPendingNodeCSRAge(config ClusterMachineApproverConfig, csrs []certificatesv1.CertificateSigningRequest) time.Duration {
+	// assumes we are scheduled on the master meaning our clock is the same
+	currentTime := now()
+	start := currentTime.Add(-maxPendingDelta)
+	end := currentTime.Add(maxMachineClockSkew)
+
+	var oldest time.Time
+
+	for _, csr := range csrs {
+		// ignore "old" CSRs
+		if !inTimeSpan(start, end, csr.CreationTimestamp.Time) {
+			continue
+		}
+
+		if pendingNodeCertFilter(config, &csr) && (oldest.IsZero() || csr.CreationTimestamp.Time.Before(oldest)) {
+			oldest = csr.CreationTimestamp.Time
+		}
+	}
+
+	if oldest.IsZero() || oldest.After(currentTime) {
+		return 0
+	}
+
+	return currentTime.Sub(oldest)
+}
+
 func isRequestFromNodeUser(csr certificatesv1.CertificateSigningRequest) bool {
 	return strings.HasPrefix(csr.Spec.Username, nodeUserPrefix)
 }
@@ -538,44 +1432,79 @@ func isRequestFromNodeUser(csr certificatesv1.CertificateSigningRequest) bool {
 // If successful, and the returned TLS certificate is validated against the
 // given CA, the node's serving certificate as presented over the established
 // connection is returned.
-func getServingCert(c client.Client, nodeName string, ca *x509.CertPool) (*x509.Certificate, error) {
+//
+// Failures to dial the kubelet are recorded in dialFailures, and the kubelet
+// is not dialed again within the configured dial failure backoff.
+func getServingCert(ctx context.Context, c client.Client, config ClusterMachineApproverConfig, nodeName string, ca *x509.CertPool, dialFailures *kubeletDialFailureCache) (*x509.Certificate, error) {
 	if ca == nil {
 		return nil, fmt.Errorf("no CA found: will not retrieve serving cert")
 	}
 
+	backoff := config.ServingRenewal.DialFailureBackoff.Duration
+	if dialFailures.failedWithin(nodeName, backoff) {
+		return nil, fmt.Errorf("kubelet of node %s could not be dialed within the last %v, will not retrieve serving cert", nodeName, backoff)
+	}
+
+	defer observeReconcileStage(ReconcileStageGetServingCert, now())
+
 	node := &corev1.Node{}
-	if err := c.Get(context.Background(), client.ObjectKey{Name: nodeName}, node); err != nil {
+	if err := c.Get(ctx, client.ObjectKey{Name: nodeName}, node); err != nil {
+		countKubeletConnectFailure(err)
 		return nil, err
 	}
 
-	host, err := nodeInternalIP(node)
+	host, err := nodeKubeletIP(node, config.ServingRenewal.AllowExternalKubeletAddress)
 	if err != nil {
+		countKubeletConnectFailure(err)
 		return nil, err
 	}
 
+	serverName, err := config.ServingRenewal.ServerName(nodeName, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the server name of kubelet of node %s: %w", nodeName, err)
+	}
+
 	port := strconv.Itoa(int(node.Status.DaemonEndpoints.KubeletEndpoint.Port))
 
 	kubelet := net.JoinHostPort(host, port)
-	dialer := &net.Dialer{Timeout: 30 * time.Second}
-	tlsConfig := &tls.Config{
-		RootCAs:    ca,
-		ServerName: host,
+	// The dial is aborted when the context is cancelled, e.g. on shutdown
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 30 * time.Second},
+		Config: &tls.Config{
+			RootCAs:    ca,
+			ServerName: serverName,
+		},
 	}
 
 	klog.Infof("retrieving serving cert from %s (%s)", nodeName, kubelet)
 
-	conn, err := tls.DialWithDialer(dialer, "tcp", kubelet, tlsConfig)
+	conn, err := dialer.DialContext(ctx, "tcp", kubelet)
 	if err != nil {
+		countKubeletConnectFailure(err)
+		dialFailures.add(nodeName, backoff)
 		return nil, err
 	}
 
 	defer conn.Close()
 
-	cert := conn.ConnectionState().PeerCertificates[0]
+	cert, err := peerLeafCertificate(conn.(*tls.Conn).ConnectionState())
+	if err != nil {
+		countKubeletConnectFailure(err)
+		return nil, fmt.Errorf("kubelet %s of node %s: %w", kubelet, nodeName, err)
+	}
 
 	return cert, nil
 }
 
+// peerLeafCertificate returns the leaf certificate presented by the peer of a
+// TLS connection, or an error if it presented none.
+func peerLeafCertificate(state tls.ConnectionState) (*x509.Certificate, error) {
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("no serving certificate presented")
+	}
+	return state.PeerCertificates[0], nil
+}
+
 // nodeInternalIP returns the first internal IP for the node.
 func nodeInternalIP(node *corev1.Node) (string, error) {
 	for _, address := range node.Status.Addresses {
@@ -584,13 +1513,59 @@ func nodeInternalIP(node *corev1.Node) (string, error) {
 		}
 	}
 
-	return "", fmt.Errorf("node %s has no internal addresses", node.Name)
+	return "", fmt.Errorf("node %s has %w", node.Name, errNoInternalAddresses)
+}
+
+// nodeKubeletIP returns the IP to connect to the kubelet of the node on, its
+// first internal IP, or its first external IP if allowed and it has no internal IP.
+func nodeKubeletIP(node *corev1.Node, allowExternal bool) (string, error) {
+	host, err := nodeInternalIP(node)
+	if err == nil || !allowExternal {
+		return host, err
+	}
+
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeExternalIP {
+			return address.Address, nil
+		}
+	}
+
+	return "", err
+}
+
+// kubeletConnectFailureCategory returns the coarse category of a failure to
+// retrieve the serving cert of a kubelet.
+func kubeletConnectFailureCategory(err error) string {
+	var netErr net.Error
+	var verifyErr *tls.CertificateVerificationError
+
+	switch {
+	case apierrors.IsNotFound(err):
+		return KubeletConnectFailureNodeNotFound
+	case errors.Is(err, errNoInternalAddresses):
+		return KubeletConnectFailureNoInternalIP
+	case errors.As(err, &verifyErr):
+		return KubeletConnectFailureTLSVerify
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return KubeletConnectFailureDialTimeout
+	default:
+		return KubeletConnectFailureOther
+	}
+}
+
+// countKubeletConnectFailure counts a failure to retrieve the serving cert of a kubelet.
+func countKubeletConnectFailure(err error) {
+	atomic.AddUint32(KubeletConnectFailures[kubeletConnectFailureCategory(err)], 1)
 }
 
 // needsEgressCheck determines whether or not egress IP checks should be enabled.
 func needsEgressCheck(c client.Client) (bool, error) {
 	network := &configv1.Network{}
-	if err := c.Get(context.Background(), client.ObjectKey{Name: networkClusterName}, network); err != nil {
+	if err := c.Get(context.Background(), client.ObjectKey{Name: networkClusterName}, network); apierrors.IsNotFound(err) {
+		// Without a cluster network there is no egress IP to check
+		klog.V(3).Infof("Cluster network %s not found, egress checks are disabled", networkClusterName)
+		return false, nil
+	} else if err != nil {
 		return false, fmt.Errorf("could not fetch cluster network: %v", err)
 	}
 
@@ -653,7 +1628,8 @@ func equalIPAddresses(a, b []net.IP) bool {
 
 // subsetIPAddresses tests whether the set sub is contained within the set super.
 // If an element of sub does not exist in super but does exist within cidrs, this
-// is also considered a part of the superset.
+// is also considered a part of the superset. Addresses are compared in their
+// canonical string form, so IPv4 addresses match in either length.
 func subsetIPAddresses(cidrs []*net.IPNet, super, sub []net.IP) bool {
 	superSet := make(map[string]struct{})
 	for _, ipAddr := range super {
@@ -683,6 +1659,11 @@ func ipInSet(cidrs []*net.IPNet, ipSet map[string]struct{}, ipAddr net.IP) bool
 	return false
 }
 
+// countSANs returns the number of SANs of any type in a CSR
+func countSANs(csr *x509.CertificateRequest) int {
+	return len(csrSANs(csr))
+}
+
 // csrSANs returns the Subject Alternative Name values for the given
 // certificate request as a slice of strings.
 func csrSANs(csr *x509.CertificateRequest) []string {
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"bytes"
+	"context"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
@@ -10,25 +11,49 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	configv1 "github.com/openshift/api/config/v1"
 	networkv1 "github.com/openshift/api/network/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	certificatesv1 "k8s.io/api/certificates/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
 	testingclock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	machinehandlerpkg "github.com/openshift/cluster-machine-approver/pkg/machinehandler"
 )
@@ -37,7 +62,7 @@ import (
 var serverCertGood, serverKeyGood, rootCertGood string
 
 // Generated CRs, are populating within the init func
-var goodCSR, goodCSRECDSA, extraAddr, otherName, noNamePrefix, noGroup, clientGood, clientExtraO, clientWithDNS, clientWrongCN, clientEmptyName, emptyCSR, multusCSRPEM string
+var goodCSR, goodCSRECDSA, extraAddr, extraIPv6Addr, extraDualStackAddr, otherName, noNamePrefix, noGroup, clientGood, clientExtraO, clientWithDNS, clientWrongCN, clientEmptyName, emptyCSR, multusCSRPEM string
 
 var presetTimeCorrect, presetTimeExpired time.Time
 
@@ -107,6 +132,16 @@ func init() {
 		defaultOrgs,
 		[]net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("10.0.0.1"), net.ParseIP("99.0.1.1")},
 		defaultDNSNames)
+	extraIPv6Addr = createCSR(
+		"system:node:test",
+		defaultOrgs,
+		[]net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("10.0.0.1"), net.ParseIP("fd00:0:0:1::1")},
+		defaultDNSNames)
+	extraDualStackAddr = createCSR(
+		"system:node:test",
+		defaultOrgs,
+		[]net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("10.0.0.1"), net.ParseIP("99.0.1.1"), net.ParseIP("fd00:0:0:1::1")},
+		defaultDNSNames)
 	otherName = createCSR("system:node:foobar", defaultOrgs, defaultIPs, defaultDNSNames)
 	noNamePrefix = createCSR("test", defaultOrgs, defaultIPs, defaultDNSNames)
 	noGroup = createCSR("system:node:test", []string{}, defaultIPs, defaultDNSNames)
@@ -324,6 +359,7 @@ func Test_authorizeCSR(t *testing.T) {
 		args      args
 		wantErr   string
 		authorize bool
+		method    authorizationMethod
 	}{
 		{
 			name: "ok",
@@ -347,6 +383,7 @@ func Test_authorizeCSR(t *testing.T) {
 			},
 			wantErr:   "",
 			authorize: true,
+			method:    authorizedByMachine,
 		},
 		{
 			name: "ok with ECDSA",
@@ -369,6 +406,7 @@ func Test_authorizeCSR(t *testing.T) {
 			},
 			wantErr:   "",
 			authorize: true,
+			method:    authorizedByMachine,
 		},
 		{
 			name: "bad-csr",
@@ -519,6 +557,7 @@ func Test_authorizeCSR(t *testing.T) {
 			},
 			wantErr:   "",
 			authorize: true,
+			method:    authorizedByMachine,
 		},
 		{
 			name: "wrong-group",
@@ -833,6 +872,7 @@ func Test_authorizeCSR(t *testing.T) {
 			},
 			wantErr:   "",
 			authorize: true,
+			method:    authorizedByMachine,
 		},
 		{
 			name: "client good",
@@ -860,6 +900,7 @@ func Test_authorizeCSR(t *testing.T) {
 			},
 			wantErr:   "",
 			authorize: true,
+			method:    authorizedByMachine,
 		},
 		{
 			name: "client good with upper case DNS",
@@ -887,6 +928,7 @@ func Test_authorizeCSR(t *testing.T) {
 			},
 			wantErr:   "",
 			authorize: true,
+			method:    authorizedByMachine,
 		},
 		{
 			name: "client good with trailing dot in DNS",
@@ -914,6 +956,7 @@ func Test_authorizeCSR(t *testing.T) {
 			},
 			wantErr:   "",
 			authorize: true,
+			method:    authorizedByMachine,
 		},
 		{
 			name: "client extra O",
@@ -1317,6 +1360,7 @@ func Test_authorizeCSR(t *testing.T) {
 			},
 			wantErr:   "",
 			authorize: true,
+			method:    authorizedByMachine,
 		},
 		{
 			name: "client good with proper timing 2",
@@ -1360,6 +1404,7 @@ func Test_authorizeCSR(t *testing.T) {
 			},
 			wantErr:   "",
 			authorize: true,
+			method:    authorizedByMachine,
 		},
 		{
 			name: "client good but CSR too early",
@@ -1482,6 +1527,7 @@ func Test_authorizeCSR(t *testing.T) {
 				ca:  []*x509.Certificate{parseCert(t, rootCertGood)},
 			},
 			authorize: true,
+			method:    authorizedByRenewal,
 		},
 		{
 			name: "successfull fallback to fresh approval",
@@ -1509,6 +1555,7 @@ func Test_authorizeCSR(t *testing.T) {
 				ca:  []*x509.Certificate{parseCert(t, rootCertGood)},
 			},
 			authorize: true,
+			method:    authorizedByMachine,
 		},
 		{
 			name: "successfull fallback to fresh approval from incorrect server cert",
@@ -1589,6 +1636,7 @@ func Test_authorizeCSR(t *testing.T) {
 				ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
 			},
 			authorize: true,
+			method:    authorizedByEgress,
 		},
 		{
 			name: "CSR extra address in egress CIDRs",
@@ -1614,940 +1662,7898 @@ func Test_authorizeCSR(t *testing.T) {
 				ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
 			},
 			authorize: true,
+			method:    authorizedByEgress,
 		},
-	}
-
-	server := fakeResponder(t, fmt.Sprintf("%s:%v", defaultAddr, defaultPort), serverCertGood, serverKeyGood)
-	defer server.Close()
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			kubeletServer := server
-			if tt.args.kubeletServer != nil {
-				kubeletServer = tt.args.kubeletServer
-				defer kubeletServer.Close()
-			}
-
-			network := &configv1.Network{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "cluster",
+		{
+			name: "CSR address owned by another node rejected",
+			args: args{
+				config: ClusterMachineApproverConfig{
+					NodeServingCert: NodeServingCert{RejectDuplicateSANs: true},
 				},
-				Status: configv1.NetworkStatus{
-					NetworkType: tt.args.networkType,
+				node: withName("test", defaultNode()),
+				machines: []machinehandlerpkg.Machine{
+					makeMachine("test",
+						corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+						corev1.NodeAddress{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+						corev1.NodeAddress{Type: corev1.NodeExternalDNS, Address: "node1"},
+					),
+					makeMachine("other",
+						corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+					),
 				},
-			}
-
-			objs := []runtime.Object{network}
-			if tt.args.node != nil {
-				objs = append(objs, tt.args.node)
-			}
-			if tt.args.hostSubnet != nil {
-				objs = append(objs, tt.args.hostSubnet)
-			}
-			cl := fake.NewFakeClient(objs...)
-			tt.args.req.Spec.Request = []byte(tt.args.csr)
-			parsedCSR, err := parseCSR(tt.args.req)
-			if err != nil {
-				if errString(err) != tt.wantErr {
-					t.Errorf("parseCSR() error = %v, wantErr %s", err, tt.wantErr)
-				}
-				return
-			}
-
-			var ca *x509.CertPool
-			if len(tt.args.ca) > 0 {
-				// Start renewal flow
-				ca = x509.NewCertPool()
-				for _, cert := range tt.args.ca {
-					ca.AddCert(cert)
-				}
-				go respond(kubeletServer)
-			}
-			if authorize, err := authorizeCSR(cl, tt.args.config, tt.args.machines, tt.args.req, parsedCSR, ca); authorize != tt.authorize || errString(err) != tt.wantErr {
-				t.Errorf("authorizeCSR() error = %v, wantErr %s", err, tt.wantErr)
-			}
-		})
-
-		t.Run("Invalid call", func(t *testing.T) {
-			if authorize, err := authorizeCSR(nil, tt.args.config, tt.args.machines, nil, nil, nil); authorize != false {
-				t.Errorf("authorizeCSR() error = %v, wantErr %s", err, "Invalid request")
-			}
-		})
-	}
-}
-
-func TestAuthorizeServingRenewal(t *testing.T) {
-	tests := []struct {
-		name        string
-		nodeName    string
-		csr         *x509.CertificateRequest
-		currentCert *x509.Certificate
-		ca          []*x509.Certificate
-		time        time.Time
-		wantErr     string
-	}{
-		{
-			name:     "missing args",
-			nodeName: "panda",
-			wantErr:  "CSR, serving cert, or CA not provided",
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageServerAuth,
+						},
+						Username: "system:node:test",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: goodCSR,
+				ca:  []*x509.Certificate{parseCert(t, rootCertGood)},
+			},
+			wantErr:   "address '10.0.0.1' belongs to node other",
+			authorize: false,
 		},
 		{
-			name:        "all good",
-			nodeName:    "test",
-			csr:         parseCR(t, goodCSR),
-			currentCert: parseCert(t, serverCertGood),
-			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
-			time:        presetTimeCorrect,
+			name: "CSR addresses owned by the requesting node accepted",
+			args: args{
+				config: ClusterMachineApproverConfig{
+					NodeServingCert: NodeServingCert{RejectDuplicateSANs: true},
+				},
+				machines: []machinehandlerpkg.Machine{
+					makeMachine("test"),
+					makeMachine("other",
+						corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.0.0.2"},
+					),
+				},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageServerAuth,
+						},
+						Username: "system:node:test",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: goodCSR,
+			},
+			authorize: true,
+			method:    authorizedByMachine,
 		},
 		{
-			name:        "reject expired",
-			nodeName:    "test",
-			csr:         parseCR(t, goodCSR),
-			currentCert: parseCert(t, serverCertGood),
-			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
-			time:        presetTimeExpired,
-			wantErr:     fmt.Sprintf("x509: certificate has expired or is not yet valid: current time %s is before %s", presetTimeExpired.Format(time.RFC3339), presetTimeCorrect.Format(time.RFC3339)),
+			name: "client renewal good",
+			args: args{
+				config: ClusterMachineApproverConfig{
+					NodeClientCert: NodeClientCert{AllowRenewal: true},
+				},
+				node:     withName("panda", defaultNode()),
+				machines: []machinehandlerpkg.Machine{makeMachine("panda")},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageClientAuth,
+						},
+						Username: "system:node:panda",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: clientGood,
+			},
+			authorize: true,
+			method:    authorizedByClientRenewal,
 		},
 		{
-			name:        "SAN list differs",
-			nodeName:    "test",
-			csr:         parseCR(t, extraAddr),
-			currentCert: parseCert(t, serverCertGood),
-			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
-			time:        presetTimeCorrect,
-			wantErr:     "CSR Subject Alternate Name values do not match current certificate",
+			name: "client renewal not allowed",
+			args: args{
+				node:     withName("panda", defaultNode()),
+				machines: []machinehandlerpkg.Machine{makeMachine("panda")},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageClientAuth,
+						},
+						Username: "system:node:panda",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: clientGood,
+			},
+			authorize: false,
 		},
 		{
-			name:        "No certificate match",
-			nodeName:    "test",
-			csr:         parseCR(t, goodCSR),
-			currentCert: parseCert(t, serverCertGood),
-			ca:          []*x509.Certificate{},
-			time:        presetTimeCorrect,
-			wantErr:     "x509: certificate signed by unknown authority",
+			name: "client renewal for another node",
+			args: args{
+				config: ClusterMachineApproverConfig{
+					NodeClientCert: NodeClientCert{AllowRenewal: true},
+				},
+				node:     withName("panda", defaultNode()),
+				machines: []machinehandlerpkg.Machine{makeMachine("panda")},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageClientAuth,
+						},
+						Username: "system:node:bear",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: clientGood,
+			},
+			authorize: false,
 		},
 		{
-			name:        "Request from different node",
-			nodeName:    "test",
-			csr:         parseCR(t, otherName),
-			currentCert: parseCert(t, serverCertGood),
-			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
-			time:        presetTimeCorrect,
-			wantErr:     "current serving cert and CSR common name mismatch",
+			name: "client renewal but node does not exist",
+			args: args{
+				config: ClusterMachineApproverConfig{
+					NodeClientCert: NodeClientCert{AllowRenewal: true},
+				},
+				machines: []machinehandlerpkg.Machine{makeMachine("panda")},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageClientAuth,
+						},
+						Username: "system:node:panda",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: clientGood,
+			},
+			authorize: false,
 		},
 		{
-			name:        "Unexpected CN",
-			nodeName:    "panda",
-			csr:         parseCR(t, goodCSR),
-			currentCert: parseCert(t, serverCertGood),
-			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
-			time:        presetTimeCorrect,
-			wantErr:     "current serving cert has bad common name",
+			name: "client renewal but missing machine",
+			args: args{
+				config: ClusterMachineApproverConfig{
+					NodeClientCert: NodeClientCert{AllowRenewal: true},
+				},
+				node: withName("panda", defaultNode()),
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageClientAuth,
+						},
+						Username: "system:node:panda",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: clientGood,
+			},
+			wantErr:   "failed to find machine for node panda",
+			authorize: false,
 		},
-	}
-
-	for _, tt := range tests {
+		{
+			name: "strict renewal with current machine addresses",
+			args: args{
+				config: ClusterMachineApproverConfig{
+					NodeServingCert: NodeServingCert{StrictRenewal: true},
+				},
+				node:     withName("test", defaultNode()),
+				machines: []machinehandlerpkg.Machine{makeMachine("test")},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageServerAuth,
+						},
+						Username: "system:node:test",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: goodCSR,
+				ca:  []*x509.Certificate{parseCert(t, rootCertGood)},
+			},
+			authorize: true,
+			method:    authorizedByRenewal,
+		},
+		{
+			name: "renewal after machine addresses shrank",
+			args: args{
+				node: withName("test", defaultNode()),
+				machines: []machinehandlerpkg.Machine{
+					makeMachine("test",
+						corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+						corev1.NodeAddress{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+						corev1.NodeAddress{Type: corev1.NodeExternalDNS, Address: "node1"},
+					),
+				},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageServerAuth,
+						},
+						Username: "system:node:test",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: goodCSR,
+				ca:  []*x509.Certificate{parseCert(t, rootCertGood)},
+			},
+			authorize: true,
+			method:    authorizedByRenewal,
+		},
+		{
+			name: "strict renewal after machine addresses shrank",
+			args: args{
+				config: ClusterMachineApproverConfig{
+					NodeServingCert: NodeServingCert{StrictRenewal: true},
+				},
+				node: withName("test", defaultNode()),
+				machines: []machinehandlerpkg.Machine{
+					makeMachine("test",
+						corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+						corev1.NodeAddress{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+						corev1.NodeAddress{Type: corev1.NodeExternalDNS, Address: "node1"},
+					),
+				},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageServerAuth,
+						},
+						Username: "system:node:test",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: goodCSR,
+				ca:  []*x509.Certificate{parseCert(t, rootCertGood)},
+			},
+			wantErr:   "could not authorize CSR: exhausted all authorization methods: [strict renewal: IP address '10.0.0.1' not in machine addresses: 127.0.0.1, IP address '10.0.0.1' not in machine addresses: 127.0.0.1]",
+			authorize: false,
+		},
+		{
+			name: "strict renewal with a DNS name not on the machine",
+			args: args{
+				config: ClusterMachineApproverConfig{
+					NodeServingCert: NodeServingCert{StrictRenewal: true},
+				},
+				node: withName("test", defaultNode()),
+				machines: []machinehandlerpkg.Machine{
+					makeMachine("test",
+						corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+						corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+						corev1.NodeAddress{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+					),
+				},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageServerAuth,
+						},
+						Username: "system:node:test",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: goodCSR,
+				ca:  []*x509.Certificate{parseCert(t, rootCertGood)},
+			},
+			wantErr:   "could not authorize CSR: exhausted all authorization methods: [strict renewal: DNS name 'node1' not in machine names: node1.local, DNS name 'node1' not in machine names: node1.local]",
+			authorize: false,
+		},
+		{
+			name: "ambiguous serving machine match",
+			args: args{
+				machines: []machinehandlerpkg.Machine{makeMachine("test"), makeMachine("test")},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageServerAuth,
+						},
+						Username: "system:node:test",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: goodCSR,
+			},
+			wantErr:   "could not authorize CSR: exhausted all authorization methods: Ambiguous machine for node: multiple matching machines found: /, /",
+			authorize: false,
+		},
+		{
+			name: "ambiguous client machine match",
+			args: args{
+				machines: []machinehandlerpkg.Machine{
+					makeMachine("", corev1.NodeAddress{corev1.NodeInternalDNS, "panda"}),
+					makeMachine("", corev1.NodeAddress{corev1.NodeInternalDNS, "panda"}),
+				},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageClientAuth,
+						},
+						Username: "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+						Groups: []string{
+							"system:authenticated",
+							"system:serviceaccounts:openshift-machine-config-operator",
+							"system:serviceaccounts",
+						},
+					},
+				},
+				csr: clientGood,
+			},
+			wantErr:   "",
+			authorize: false,
+		},
+		{
+			name: "ok with custom serving group",
+			args: args{
+				config:   ClusterMachineApproverConfig{NodeServingCert: NodeServingCert{Group: "managed:nodes"}},
+				machines: []machinehandlerpkg.Machine{makeMachine("test")},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageServerAuth,
+						},
+						Username: "system:node:test",
+						Groups: []string{
+							"system:authenticated",
+							"managed:nodes",
+						},
+					},
+				},
+				csr: goodCSR,
+			},
+			wantErr:   "",
+			authorize: true,
+			method:    authorizedByMachine,
+		},
+		{
+			name: "unrelated group with custom serving group",
+			args: args{
+				config:   ClusterMachineApproverConfig{NodeServingCert: NodeServingCert{Group: "managed:nodes"}},
+				machines: []machinehandlerpkg.Machine{makeMachine("test")},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageServerAuth,
+						},
+						Username: "system:node:test",
+						Groups: []string{
+							"system:authenticated",
+							"unrelated:group",
+						},
+					},
+				},
+				csr: goodCSR,
+			},
+			wantErr:   "",
+			authorize: false,
+		},
+		{
+			name: "default group rejects custom serving group",
+			args: args{
+				machines: []machinehandlerpkg.Machine{makeMachine("test")},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageServerAuth,
+						},
+						Username: "system:node:test",
+						Groups: []string{
+							"system:authenticated",
+							"managed:nodes",
+						},
+					},
+				},
+				csr: goodCSR,
+			},
+			wantErr:   "",
+			authorize: false,
+		},
+		{
+			name: "client good with custom bootstrapper",
+			args: args{
+				config: ClusterMachineApproverConfig{NodeClientCert: NodeClientCert{
+					BootstrapperUsername: "system:serviceaccount:custom-mco:node-bootstrapper",
+					BootstrapperGroups: []string{
+						"system:authenticated",
+						"system:serviceaccounts:custom-mco",
+						"system:serviceaccounts",
+					},
+				}},
+				machines: []machinehandlerpkg.Machine{
+					makeMachine("", corev1.NodeAddress{corev1.NodeInternalDNS, "tigers"}),
+					makeMachine("", corev1.NodeAddress{corev1.NodeInternalDNS, "panda"}),
+				},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageClientAuth,
+						},
+						Username: "system:serviceaccount:custom-mco:node-bootstrapper",
+						Groups: []string{
+							"system:authenticated",
+							"system:serviceaccounts:custom-mco",
+							"system:serviceaccounts",
+						},
+					},
+				},
+				csr: clientGood,
+			},
+			wantErr:   "",
+			authorize: true,
+			method:    authorizedByMachine,
+		},
+		{
+			name: "client default bootstrapper with custom bootstrapper",
+			args: args{
+				config: ClusterMachineApproverConfig{NodeClientCert: NodeClientCert{
+					BootstrapperUsername: "system:serviceaccount:custom-mco:node-bootstrapper",
+				}},
+				machines: []machinehandlerpkg.Machine{
+					makeMachine("", corev1.NodeAddress{corev1.NodeInternalDNS, "tigers"}),
+					makeMachine("", corev1.NodeAddress{corev1.NodeInternalDNS, "panda"}),
+				},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageClientAuth,
+						},
+						Username: "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+						Groups: []string{
+							"system:authenticated",
+							"system:serviceaccounts:openshift-machine-config-operator",
+							"system:serviceaccounts",
+						},
+					},
+				},
+				csr: clientGood,
+			},
+			wantErr:   "",
+			authorize: false,
+		},
+		{
+			name: "client custom bootstrapper with default groups",
+			args: args{
+				config: ClusterMachineApproverConfig{NodeClientCert: NodeClientCert{
+					BootstrapperUsername: "system:serviceaccount:custom-mco:node-bootstrapper",
+				}},
+				machines: []machinehandlerpkg.Machine{
+					makeMachine("", corev1.NodeAddress{corev1.NodeInternalDNS, "panda"}),
+				},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageClientAuth,
+						},
+						Username: "system:serviceaccount:custom-mco:node-bootstrapper",
+						Groups: []string{
+							"system:authenticated",
+							"system:serviceaccounts:openshift-machine-config-operator",
+							"system:serviceaccounts",
+						},
+					},
+				},
+				csr: clientGood,
+			},
+			wantErr:   "",
+			authorize: true,
+			method:    authorizedByMachine,
+		},
+		{
+			name: "client late CSR with default max machine delta",
+			args: args{
+				machines: []machinehandlerpkg.Machine{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							CreationTimestamp: creationTimestamp(3 * time.Minute),
+						},
+						Status: machinehandlerpkg.MachineStatus{
+							Addresses: []corev1.NodeAddress{
+								{
+									Type:    corev1.NodeInternalDNS,
+									Address: "panda",
+								},
+							},
+						},
+					},
+				},
+				req: &certificatesv1.CertificateSigningRequest{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "late",
+						CreationTimestamp: creationTimestamp(3 * time.Hour),
+					},
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageClientAuth,
+						},
+						Username: "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+						Groups: []string{
+							"system:authenticated",
+							"system:serviceaccounts:openshift-machine-config-operator",
+							"system:serviceaccounts",
+						},
+					},
+				},
+				csr: clientGood,
+			},
+			wantErr:   "",
+			authorize: false,
+		},
+		{
+			name: "client late CSR with custom max machine delta",
+			args: args{
+				config: ClusterMachineApproverConfig{NodeClientCert: NodeClientCert{
+					MaxMachineDelta: metav1.Duration{Duration: 4 * time.Hour},
+				}},
+				machines: []machinehandlerpkg.Machine{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							CreationTimestamp: creationTimestamp(3 * time.Minute),
+						},
+						Status: machinehandlerpkg.MachineStatus{
+							Addresses: []corev1.NodeAddress{
+								{
+									Type:    corev1.NodeInternalDNS,
+									Address: "panda",
+								},
+							},
+						},
+					},
+				},
+				req: &certificatesv1.CertificateSigningRequest{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "late",
+						CreationTimestamp: creationTimestamp(3 * time.Hour),
+					},
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageClientAuth,
+						},
+						Username: "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+						Groups: []string{
+							"system:authenticated",
+							"system:serviceaccounts:openshift-machine-config-operator",
+							"system:serviceaccounts",
+						},
+					},
+				},
+				csr: clientGood,
+			},
+			wantErr:   "",
+			authorize: true,
+			method:    authorizedByMachine,
+		},
+		{
+			name: "renewal with machine-api authorization disabled",
+			args: args{
+				config:   ClusterMachineApproverConfig{MachineAPIAuthorization: MachineAPIAuthorization{Disabled: true}},
+				node:     withName("test", defaultNode()),
+				machines: []machinehandlerpkg.Machine{makeMachine("test")},
+				req: &certificatesv1.CertificateSigningRequest{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "renew",
+						CreationTimestamp: creationTimestamp(10 * time.Minute),
+					},
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageServerAuth,
+						},
+						Username: "system:node:test",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: goodCSR,
+				ca:  []*x509.Certificate{parseCert(t, rootCertGood)},
+			},
+			authorize: true,
+			method:    authorizedByRenewal,
+		},
+		{
+			name: "no fallback to the machine with machine-api authorization disabled",
+			args: args{
+				config:   ClusterMachineApproverConfig{MachineAPIAuthorization: MachineAPIAuthorization{Disabled: true}},
+				machines: []machinehandlerpkg.Machine{makeMachine("test")},
+				req: &certificatesv1.CertificateSigningRequest{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "renew",
+						CreationTimestamp: creationTimestamp(10 * time.Minute),
+					},
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageServerAuth,
+						},
+						Username: "system:node:test",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: goodCSR,
+			},
+			wantErr:   "could not authorize CSR: exhausted all authorization methods: machine-api authorization is disabled, only serving cert renewals can be approved",
+			authorize: false,
+		},
+		{
+			name: "client with a control character in the node name",
+			args: args{
+				machines: []machinehandlerpkg.Machine{
+					makeMachine("", corev1.NodeAddress{corev1.NodeInternalDNS, "foo\nbar"}),
+				},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageClientAuth,
+						},
+						Username: "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+						Groups: []string{
+							"system:authenticated",
+							"system:serviceaccounts:openshift-machine-config-operator",
+							"system:serviceaccounts",
+						},
+					},
+				},
+				csr: createCSR("system:node:"+"foo\nbar", defaultOrgs, []net.IP{}, []string{}),
+			},
+			wantErr:   "",
+			authorize: false,
+		},
+		{
+			name: "client with an over-length node name",
+			args: args{
+				machines: []machinehandlerpkg.Machine{
+					makeMachine("", corev1.NodeAddress{corev1.NodeInternalDNS, strings.Repeat("a", 254)}),
+				},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageClientAuth,
+						},
+						Username: "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+						Groups: []string{
+							"system:authenticated",
+							"system:serviceaccounts:openshift-machine-config-operator",
+							"system:serviceaccounts",
+						},
+					},
+				},
+				csr: createCSR("system:node:"+strings.Repeat("a", 254), defaultOrgs, []net.IP{}, []string{}),
+			},
+			wantErr:   "",
+			authorize: false,
+		},
+		{
+			name: "serving with a control character in the node name",
+			args: args{
+				machines: []machinehandlerpkg.Machine{makeMachine("foo\nbar")},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageKeyEncipherment,
+							certificatesv1.UsageDigitalSignature,
+							certificatesv1.UsageServerAuth,
+						},
+						Username: "system:node:foo\nbar",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: createCSR("system:node:foo\nbar", defaultOrgs, defaultIPs, defaultDNSNames),
+			},
+			wantErr:   "",
+			authorize: false,
+		},
+		{
+			name: "serving with additional usages",
+			args: args{
+				config: ClusterMachineApproverConfig{NodeServingCert: NodeServingCert{
+					AdditionalUsages: [][]certificatesv1.KeyUsage{
+						{certificatesv1.UsageKeyEncipherment, certificatesv1.UsageServerAuth},
+					},
+				}},
+				machines: []machinehandlerpkg.Machine{makeMachine("test")},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageServerAuth,
+							certificatesv1.UsageKeyEncipherment,
+						},
+						Username: "system:node:test",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: goodCSR,
+			},
+			wantErr:   "",
+			authorize: true,
+			method:    authorizedByMachine,
+		},
+		{
+			name: "serving with additional usages not configured",
+			args: args{
+				machines: []machinehandlerpkg.Machine{makeMachine("test")},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageServerAuth,
+							certificatesv1.UsageKeyEncipherment,
+						},
+						Username: "system:node:test",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: goodCSR,
+			},
+			wantErr:   "",
+			authorize: false,
+		},
+		{
+			name: "serving with usages not matching additional usages",
+			args: args{
+				config: ClusterMachineApproverConfig{NodeServingCert: NodeServingCert{
+					AdditionalUsages: [][]certificatesv1.KeyUsage{
+						{certificatesv1.UsageKeyEncipherment, certificatesv1.UsageServerAuth},
+					},
+				}},
+				machines: []machinehandlerpkg.Machine{makeMachine("test")},
+				req: &certificatesv1.CertificateSigningRequest{
+					Spec: certificatesv1.CertificateSigningRequestSpec{
+						Usages: []certificatesv1.KeyUsage{
+							certificatesv1.UsageServerAuth,
+							certificatesv1.UsageDataEncipherment,
+						},
+						Username: "system:node:test",
+						Groups: []string{
+							"system:authenticated",
+							"system:nodes",
+						},
+					},
+				},
+				csr: goodCSR,
+			},
+			wantErr:   "",
+			authorize: false,
+		},
+	}
+
+	server := fakeResponder(t, fmt.Sprintf("%s:%v", defaultAddr, defaultPort), serverCertGood, serverKeyGood)
+	defer server.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeletServer := server
+			if tt.args.kubeletServer != nil {
+				kubeletServer = tt.args.kubeletServer
+				defer kubeletServer.Close()
+			}
+
+			network := &configv1.Network{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "cluster",
+				},
+				Status: configv1.NetworkStatus{
+					NetworkType: tt.args.networkType,
+				},
+			}
+
+			objs := []runtime.Object{network}
+			if tt.args.node != nil {
+				objs = append(objs, tt.args.node)
+			}
+			if tt.args.hostSubnet != nil {
+				objs = append(objs, tt.args.hostSubnet)
+			}
+			cl := fake.NewFakeClient(objs...)
+			tt.args.req.Spec.Request = []byte(tt.args.csr)
+			parsedCSR, err := parseCSR(tt.args.req)
+			if err != nil {
+				if errString(err) != tt.wantErr {
+					t.Errorf("parseCSR() error = %v, wantErr %s", err, tt.wantErr)
+				}
+				return
+			}
+
+			var ca *x509.CertPool
+			if len(tt.args.ca) > 0 {
+				// Start renewal flow
+				ca = x509.NewCertPool()
+				for _, cert := range tt.args.ca {
+					ca.AddCert(cert)
+				}
+				go respond(kubeletServer)
+			}
+			result, err := authorizeCSR(context.Background(), cl, tt.args.config, tt.args.machines, tt.args.req, parsedCSR, ca, nil, nil)
+			if result.Authorized != tt.authorize || errString(err) != tt.wantErr {
+				t.Errorf("authorizeCSR() error = %v, wantErr %s", err, tt.wantErr)
+			}
+			if result.Method != tt.method {
+				t.Errorf("authorizeCSR() method = %q, want %q", result.Method, tt.method)
+			}
+		})
+
+		t.Run("Invalid call", func(t *testing.T) {
+			if result, err := authorizeCSR(context.Background(), nil, tt.args.config, tt.args.machines, nil, nil, nil, nil, nil); result.Authorized != false {
+				t.Errorf("authorizeCSR() error = %v, wantErr %s", err, "Invalid request")
+			}
+		})
+	}
+}
+
+func TestAuthorizeServingRenewal(t *testing.T) {
+	ipOnlyRootCert, ipOnlyRootKey, err := generateCertKeyPair(12*time.Hour, nil, nil, "system:node:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without DNS names, the serving cert only has the IP SANs of the node
+	ipOnlyServerCert, _, err := generateCertKeyPair(time.Hour, ipOnlyRootCert, ipOnlyRootKey, "system:node:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipOnlyTime := parseCert(t, string(ipOnlyServerCert)).NotBefore.Add(time.Minute)
+	ipOnlyCSR := createCSR("system:node:test", defaultOrgs, defaultIPs, nil)
+	ipOnlyEmptyDNSCSR := parseCR(t, createCSR("system:node:test", defaultOrgs, defaultIPs, []string{}))
+	ipOnlyEmptyDNSCSR.DNSNames = []string{}
+
+	tests := []struct {
+		name          string
+		nodeName      string
+		csr           *x509.CertificateRequest
+		currentCert   *x509.Certificate
+		ca            []*x509.Certificate
+		time          time.Time
+		renewalWindow time.Duration
+		wantErr       string
+	}{
+		{
+			name:     "missing args",
+			nodeName: "panda",
+			wantErr:  "CSR, serving cert, or CA not provided",
+		},
+		{
+			name:        "all good",
+			nodeName:    "test",
+			csr:         parseCR(t, goodCSR),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+		},
+		{
+			name:        "reject expired",
+			nodeName:    "test",
+			csr:         parseCR(t, goodCSR),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeExpired,
+			wantErr:     fmt.Sprintf("x509: certificate has expired or is not yet valid: current time %s is before %s", presetTimeExpired.Format(time.RFC3339), presetTimeCorrect.Format(time.RFC3339)),
+		},
+		{
+			name:        "SAN list differs",
+			nodeName:    "test",
+			csr:         parseCR(t, extraAddr),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			wantErr:     "CSR Subject Alternate Name values do not match current certificate",
+		},
+		{
+			name:        "No certificate match",
+			nodeName:    "test",
+			csr:         parseCR(t, goodCSR),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{},
+			time:        presetTimeCorrect,
+			wantErr:     "x509: certificate signed by unknown authority",
+		},
+		{
+			name:        "Request from different node",
+			nodeName:    "test",
+			csr:         parseCR(t, otherName),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			wantErr:     "current serving cert and CSR common name mismatch",
+		},
+		{
+			name:        "Unexpected CN",
+			nodeName:    "panda",
+			csr:         parseCR(t, goodCSR),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			wantErr:     "current serving cert has bad common name",
+		},
+		{
+			name:          "Current cert expires within the renewal window",
+			nodeName:      "test",
+			csr:           parseCR(t, goodCSR),
+			currentCert:   parseCert(t, serverCertGood),
+			ca:            []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:          parseCert(t, serverCertGood).NotAfter.Add(-30 * time.Minute),
+			renewalWindow: time.Hour,
+		},
+		{
+			name:          "Current cert expires beyond the renewal window",
+			nodeName:      "test",
+			csr:           parseCR(t, goodCSR),
+			currentCert:   parseCert(t, serverCertGood),
+			ca:            []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:          parseCert(t, serverCertGood).NotAfter.Add(-30 * time.Minute),
+			renewalWindow: 10 * time.Minute,
+			wantErr:       "premature serving cert renewal: current serving cert expires in 30m0s, beyond the renewal window of 10m0s",
+		},
+		{
+			name:        "IP SANs only",
+			nodeName:    "test",
+			csr:         parseCR(t, ipOnlyCSR),
+			currentCert: parseCert(t, string(ipOnlyServerCert)),
+			ca:          []*x509.Certificate{parseCert(t, string(ipOnlyRootCert))},
+			time:        ipOnlyTime,
+		},
+		{
+			name:        "IP SANs only with empty DNS names",
+			nodeName:    "test",
+			csr:         ipOnlyEmptyDNSCSR,
+			currentCert: parseCert(t, string(ipOnlyServerCert)),
+			ca:          []*x509.Certificate{parseCert(t, string(ipOnlyRootCert))},
+			time:        ipOnlyTime,
+		},
+		{
+			name:        "IP SANs only but current cert has DNS names",
+			nodeName:    "test",
+			csr:         parseCR(t, ipOnlyCSR),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			wantErr:     "CSR Subject Alternate Name values do not match current certificate",
+		},
+		{
+			name:        "DNS names added to IP SANs only cert",
+			nodeName:    "test",
+			csr:         parseCR(t, goodCSR),
+			currentCert: parseCert(t, string(ipOnlyServerCert)),
+			ca:          []*x509.Certificate{parseCert(t, string(ipOnlyRootCert))},
+			time:        ipOnlyTime,
+			wantErr:     "CSR Subject Alternate Name values do not match current certificate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certPool := x509.NewCertPool()
+			for _, cert := range tt.ca {
+				certPool.AddCert(cert)
+			}
+			err := authorizeServingRenewal(
+				tt.nodeName,
+				tt.csr,
+				tt.currentCert,
+				x509.VerifyOptions{Roots: certPool, CurrentTime: tt.time},
+				tt.renewalWindow,
+			)
+
+			if errString(err) != tt.wantErr {
+				t.Errorf("got: %v, want: %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetServingCertServerName(t *testing.T) {
+	caCert, caKey, err := generateCertKeyPair(time.Hour, nil, nil, "kubelet-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	servingCert, servingKey, err := generateCertKeyPair(time.Hour, caCert, caKey, "system:node:test", "test.kubelet.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	crt, err := tls.X509KeyPair(servingCert, servingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPool := x509.NewCertPool()
+	certPool.AppendCertsFromPEM(caCert)
+
+	tests := []struct {
+		name           string
+		config         ClusterMachineApproverConfig
+		wantServerName string
+		wantErr        string
+	}{
+		{
+			// IP addresses are verified, but not sent as SNI
+			name: "kubelet address by default",
+		},
+		{
+			name:           "server name template",
+			config:         ClusterMachineApproverConfig{ServingRenewal: ServingRenewal{ServerNameTemplate: "{{.NodeName}}.kubelet.example.com"}},
+			wantServerName: "test.kubelet.example.com",
+		},
+		{
+			name:           "server name not in the serving cert",
+			config:         ClusterMachineApproverConfig{ServingRenewal: ServingRenewal{ServerNameTemplate: "{{.NodeName}}.example.com"}},
+			wantServerName: "test.example.com",
+			wantErr:        "tls: failed to verify certificate: x509: certificate is valid for test.kubelet.example.com, not test.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverNames := make(chan string, 1)
+			server, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+				GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+					serverNames <- hello.ServerName
+					return &crt, nil
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer server.Close()
+			go respond(server)
+
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Status: corev1.NodeStatus{
+					Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "127.0.0.1"}},
+					DaemonEndpoints: corev1.NodeDaemonEndpoints{
+						KubeletEndpoint: corev1.DaemonEndpoint{Port: int32(server.Addr().(*net.TCPAddr).Port)},
+					},
+				},
+			}
+
+			_, err = getServingCert(context.Background(), fake.NewFakeClient(node), tt.config, "test", certPool, nil)
+			if errString(err) != tt.wantErr {
+				t.Errorf("expected error %q, got %q", tt.wantErr, errString(err))
+			}
+			select {
+			case serverName := <-serverNames:
+				if serverName != tt.wantServerName {
+					t.Errorf("expected server name %q, got %q", tt.wantServerName, serverName)
+				}
+			default:
+				t.Error("expected a TLS handshake")
+			}
+		})
+	}
+}
+
+func TestAuthorizeServingRenewalWithEgressIPs(t *testing.T) {
+	testNodeName := "test"
+
+	tests := []struct {
+		name        string
+		nodeName    string
+		csr         *x509.CertificateRequest
+		currentCert *x509.Certificate
+		ca          []*x509.Certificate
+		time        time.Time
+		hostSubnet  *networkv1.HostSubnet
+		wantErr     string
+	}{
+		{
+			name:     "missing args",
+			nodeName: "panda",
+			wantErr:  "CSR, serving cert, or CA not provided",
+		},
+		{
+			name:        "all good",
+			nodeName:    testNodeName,
+			csr:         parseCR(t, goodCSR),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			hostSubnet: &networkv1.HostSubnet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testNodeName,
+				},
+			},
+		},
+		{
+			name:        "reject expired",
+			nodeName:    testNodeName,
+			csr:         parseCR(t, goodCSR),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeExpired,
+			wantErr:     fmt.Sprintf("x509: certificate has expired or is not yet valid: current time %s is before %s", presetTimeExpired.Format(time.RFC3339), presetTimeCorrect.Format(time.RFC3339)),
+		},
+		{
+			name:        "With additional unknown IP address",
+			nodeName:    testNodeName,
+			csr:         parseCR(t, extraAddr),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			hostSubnet: &networkv1.HostSubnet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testNodeName,
+				},
+			},
+			wantErr: "CSR Subject Alternate Names includes unknown IP addresses",
+		},
+		{
+			name:        "With additional Egress IP address",
+			nodeName:    testNodeName,
+			csr:         parseCR(t, extraAddr),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			hostSubnet: &networkv1.HostSubnet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testNodeName,
+				},
+				EgressIPs: []networkv1.HostSubnetEgressIP{"99.0.1.1"},
+			},
+		},
+		{
+			name:        "With additional Egress IP in Egress CIDRs",
+			nodeName:    testNodeName,
+			csr:         parseCR(t, extraAddr),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			hostSubnet: &networkv1.HostSubnet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testNodeName,
+				},
+				EgressCIDRs: []networkv1.HostSubnetEgressCIDR{"99.0.1.0/24"},
+			},
+		},
+		{
+			name:        "With additional unknown IPv6 address",
+			nodeName:    testNodeName,
+			csr:         parseCR(t, extraIPv6Addr),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			hostSubnet: &networkv1.HostSubnet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testNodeName,
+				},
+				EgressIPs:   []networkv1.HostSubnetEgressIP{"fd00:0:0:2::1"},
+				EgressCIDRs: []networkv1.HostSubnetEgressCIDR{"99.0.1.0/24", "fd00:0:0:2::/64"},
+			},
+			wantErr: "CSR Subject Alternate Names includes unknown IP addresses",
+		},
+		{
+			name:        "With additional IPv6 Egress IP address",
+			nodeName:    testNodeName,
+			csr:         parseCR(t, extraIPv6Addr),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			hostSubnet: &networkv1.HostSubnet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testNodeName,
+				},
+				// Not in canonical form
+				EgressIPs: []networkv1.HostSubnetEgressIP{"FD00:0000:0000:0001:0000:0000:0000:0001"},
+			},
+		},
+		{
+			name:        "With additional IPv6 Egress IP in Egress CIDRs",
+			nodeName:    testNodeName,
+			csr:         parseCR(t, extraIPv6Addr),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			hostSubnet: &networkv1.HostSubnet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testNodeName,
+				},
+				EgressCIDRs: []networkv1.HostSubnetEgressCIDR{"99.0.1.0/24", "fd00:0:0:1::/64"},
+			},
+		},
+		{
+			name:        "With additional IPv6 address and dual-stack Egress IPs",
+			nodeName:    testNodeName,
+			csr:         parseCR(t, extraIPv6Addr),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			hostSubnet: &networkv1.HostSubnet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testNodeName,
+				},
+				EgressIPs: []networkv1.HostSubnetEgressIP{"99.0.1.1", "fd00:0:0:1::1"},
+			},
+		},
+		{
+			name:        "With additional dual-stack addresses and dual-stack Egress IPs",
+			nodeName:    testNodeName,
+			csr:         parseCR(t, extraDualStackAddr),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			hostSubnet: &networkv1.HostSubnet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testNodeName,
+				},
+				EgressIPs: []networkv1.HostSubnetEgressIP{"fd00:0:0:1::1", "99.0.1.1"},
+			},
+		},
+		{
+			name:        "With additional dual-stack addresses and non-canonical dual-stack Egress IPs",
+			nodeName:    testNodeName,
+			csr:         parseCR(t, extraDualStackAddr),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			hostSubnet: &networkv1.HostSubnet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testNodeName,
+				},
+				EgressIPs: []networkv1.HostSubnetEgressIP{"not-an-ip", "FD00:0000:0:1:0::1", " 99.0.1.1 "},
+			},
+		},
+		{
+			name:        "With additional dual-stack addresses and dual-stack Egress IP and CIDR",
+			nodeName:    testNodeName,
+			csr:         parseCR(t, extraDualStackAddr),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			hostSubnet: &networkv1.HostSubnet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testNodeName,
+				},
+				EgressIPs:   []networkv1.HostSubnetEgressIP{"99.0.1.1"},
+				EgressCIDRs: []networkv1.HostSubnetEgressCIDR{"fd00:0:0:1::/64"},
+			},
+		},
+		{
+			name:        "With additional dual-stack addresses and unknown IPv6 address",
+			nodeName:    testNodeName,
+			csr:         parseCR(t, extraDualStackAddr),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			hostSubnet: &networkv1.HostSubnet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: testNodeName,
+				},
+				EgressIPs:   []networkv1.HostSubnetEgressIP{"99.0.1.1", "fd00:0:0:2::1"},
+				EgressCIDRs: []networkv1.HostSubnetEgressCIDR{"99.0.1.0/24"},
+			},
+			wantErr: "CSR Subject Alternate Names includes unknown IP addresses",
+		},
+		{
+			name:        "No certificate match",
+			nodeName:    testNodeName,
+			csr:         parseCR(t, goodCSR),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{},
+			time:        presetTimeCorrect,
+			wantErr:     "x509: certificate signed by unknown authority",
+		},
+		{
+			name:        "Request from different node",
+			nodeName:    testNodeName,
+			csr:         parseCR(t, otherName),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			wantErr:     "current serving cert and CSR common name mismatch",
+		},
+		{
+			name:        "Unexpected CN",
+			nodeName:    "panda",
+			csr:         parseCR(t, goodCSR),
+			currentCert: parseCert(t, serverCertGood),
+			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
+			time:        presetTimeCorrect,
+			wantErr:     "current serving cert has bad common name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certPool := x509.NewCertPool()
+			for _, cert := range tt.ca {
+				certPool.AddCert(cert)
+			}
+
+			objs := []runtime.Object{}
+			if tt.hostSubnet != nil {
+				objs = append(objs, tt.hostSubnet)
+			}
+			cl := fake.NewFakeClient(objs...)
+
+			err := authorizeServingRenewalWithEgressIPs(
+				cl,
+				tt.nodeName,
+				tt.csr,
+				tt.currentCert,
+				x509.VerifyOptions{Roots: certPool, CurrentTime: tt.time},
+			)
+
+			if errString(err) != tt.wantErr {
+				t.Errorf("got: %v, want: %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetServingCert(t *testing.T) {
+	defaultPort := int32(25535)
+	defaultAddr := "127.0.0.1"
+	defaultNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: defaultAddr},
+			},
+			DaemonEndpoints: corev1.NodeDaemonEndpoints{
+				KubeletEndpoint: corev1.DaemonEndpoint{
+					Port: defaultPort,
+				},
+			},
+		},
+	}
+
+	wrongAddr := defaultNode.DeepCopy()
+	wrongAddr.Status.DaemonEndpoints.KubeletEndpoint.Port = int32(25544)
+
+	uninitialized := defaultNode.DeepCopy()
+	uninitialized.Status = corev1.NodeStatus{}
+
+	tests := []struct {
+		name      string
+		nodeName  string
+		node      *corev1.Node
+		rootCerts []*x509.Certificate
+		wantErr   string
+		// wantFailure is the category of the counted failure, if any
+		wantFailure string
+	}{
+		{
+			name:      "all good",
+			nodeName:  "test",
+			node:      defaultNode,
+			rootCerts: []*x509.Certificate{parseCert(t, rootCertGood)},
+		},
+		{
+			name:        "unknown certificate",
+			nodeName:    "test",
+			node:        defaultNode,
+			rootCerts:   []*x509.Certificate{parseCert(t, differentCert)},
+			wantErr:     "tls: failed to verify certificate: x509: certificate signed by unknown authority",
+			wantFailure: KubeletConnectFailureTLSVerify,
+		},
+		{
+			name:        "node not found",
+			nodeName:    "test",
+			rootCerts:   []*x509.Certificate{parseCert(t, rootCertGood)},
+			wantErr:     "nodes \"test\" not found",
+			wantFailure: KubeletConnectFailureNodeNotFound,
+		},
+		{
+			name:        "wrong address",
+			nodeName:    "test",
+			node:        wrongAddr,
+			rootCerts:   []*x509.Certificate{parseCert(t, rootCertGood)},
+			wantErr:     "dial tcp 127.0.0.1:25544: connect: connection refused",
+			wantFailure: KubeletConnectFailureOther,
+		},
+		{
+			name:     "no pool provided",
+			nodeName: "test",
+			node:     defaultNode,
+			wantErr:  "no CA found: will not retrieve serving cert",
+		},
+		{
+			name:        "node with no addr",
+			nodeName:    "test",
+			node:        uninitialized,
+			rootCerts:   []*x509.Certificate{parseCert(t, rootCertGood)},
+			wantErr:     "node test has no internal addresses",
+			wantFailure: KubeletConnectFailureNoInternalIP,
+		},
+	}
+
+	server := fakeResponder(t, fmt.Sprintf("%s:%v", defaultAddr, defaultPort), serverCertGood, serverKeyGood)
+	defer server.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var certPool *x509.CertPool
+			if len(tt.rootCerts) > 0 {
+				certPool = x509.NewCertPool()
+				for _, cert := range tt.rootCerts {
+					certPool.AddCert(cert)
+				}
+			}
+
+			objects := []runtime.Object{}
+			if tt.node != nil {
+				objects = append(objects, tt.node)
+			}
+			cl := fake.NewFakeClient(objects...)
+
+			failuresBefore := kubeletConnectFailureCounts()
+
+			go respond(server)
+			serverCert, err := getServingCert(context.Background(), cl, ClusterMachineApproverConfig{}, tt.nodeName, certPool, nil)
+			if errString(err) != tt.wantErr {
+				t.Fatalf("got: %v, want: %s", err, tt.wantErr)
+			}
+
+			for category, count := range kubeletConnectFailureCounts() {
+				want := failuresBefore[category]
+				if category == tt.wantFailure {
+					want++
+				}
+				if count != want {
+					t.Errorf("expected %d %s failures, got %d", want, category, count)
+				}
+			}
+			if err == nil && !serverCert.Equal(parseCert(t, serverCertGood)) {
+				t.Fatal("Expected server certificate match on success")
+			}
+		})
+	}
+}
+
+func TestGetServingCertContextCancelled(t *testing.T) {
+	// The kubelet accepts connections but never completes the TLS handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "127.0.0.1"}},
+			DaemonEndpoints: corev1.NodeDaemonEndpoints{
+				KubeletEndpoint: corev1.DaemonEndpoint{Port: int32(listener.Addr().(*net.TCPAddr).Port)},
+			},
+		},
+	}
+	certPool := x509.NewCertPool()
+	certPool.AddCert(parseCert(t, rootCertGood))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := getServingCert(ctx, fake.NewFakeClient(node), ClusterMachineApproverConfig{}, "test", certPool, nil)
+		errs <- err
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the dial to be cancelled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the dial to return promptly once the context is cancelled")
+	}
+}
+
+func TestPeerLeafCertificate(t *testing.T) {
+	leaf := parseCert(t, rootCertGood)
+
+	if _, err := peerLeafCertificate(tls.ConnectionState{}); errString(err) != "no serving certificate presented" {
+		t.Errorf("expected error for no peer certificates, got %v", err)
+	}
+
+	cert, err := peerLeafCertificate(tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cert != leaf {
+		t.Errorf("expected the leaf certificate, got %v", cert)
+	}
+}
+
+func kubeletConnectFailureCounts() map[string]uint32 {
+	counts := map[string]uint32{}
+	for category, count := range KubeletConnectFailures {
+		counts[category] = atomic.LoadUint32(count)
+	}
+	return counts
+}
+
+func TestKubeletConnectFailureCategory(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "dial timeout",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded},
+			want: KubeletConnectFailureDialTimeout,
+		},
+		{
+			name: "unknown authority",
+			err:  &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}},
+			want: KubeletConnectFailureTLSVerify,
+		},
+		{
+			name: "wrong hostname",
+			err:  &tls.CertificateVerificationError{Err: x509.HostnameError{Host: "127.0.0.1"}},
+			want: KubeletConnectFailureTLSVerify,
+		},
+		{
+			name: "node not found",
+			err:  apierrors.NewNotFound(corev1.Resource("nodes"), "test"),
+			want: KubeletConnectFailureNodeNotFound,
+		},
+		{
+			name: "no internal IP",
+			err:  fmt.Errorf("node test has %w", errNoInternalAddresses),
+			want: KubeletConnectFailureNoInternalIP,
+		},
+		{
+			name: "connection refused",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED},
+			want: KubeletConnectFailureOther,
+		},
+		{
+			name: "api server error",
+			err:  apierrors.NewInternalError(errors.New("boom")),
+			want: KubeletConnectFailureOther,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := kubeletConnectFailureCategory(tt.err); got != tt.want {
+				t.Errorf("expected category %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRecentlyPendingNodeBootstrapperCSRs(t *testing.T) {
+	approvedNodeBootstrapperCSR := certificatesv1.CertificateSigningRequest{
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
+			Username:   nodeBootstrapperUsername,
+			Groups:     nodeBootstrapperGroups.List(),
+		},
+		Status: certificatesv1.CertificateSigningRequestStatus{
+			Conditions: []certificatesv1.CertificateSigningRequestCondition{{
+				Type: certificatesv1.CertificateApproved,
+			}},
+		},
+	}
+	pendingNodeBootstrapperCSR := certificatesv1.CertificateSigningRequest{
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
+			Username:   nodeBootstrapperUsername,
+			Groups:     nodeBootstrapperGroups.List(),
+		},
+	}
+	pendingNodeServerCSR := certificatesv1.CertificateSigningRequest{
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Username:   nodeUserPrefix + "clustername-abcde-master-us-west-1a-0",
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Groups:     nodeServingGroups.List(),
+		},
+	}
+	pendingNodeServerCSRMissingGroups := certificatesv1.CertificateSigningRequest{
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Username:   nodeUserPrefix + "clustername-abcde-master-us-west-1a-0",
+			SignerName: certificatesv1.KubeletServingSignerName,
+		},
+	}
+	pendingCSR := certificatesv1.CertificateSigningRequest{
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
+		},
+	}
+	pendingNodeClientRenewalCSR := certificatesv1.CertificateSigningRequest{
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Username:   nodeUserPrefix + "clustername-abcde-master-us-west-1a-0",
+			SignerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
+			Groups:     nodeServingGroups.List(),
+		},
+	}
+	pendingMultusCSR := certificatesv1.CertificateSigningRequest{
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Username:   nodeUserPrefix + "clustername-abcde-master-us-west-1a-0",
+			SignerName: certificatesv1.KubeAPIServerClientSignerName, // Not approved by this controller
+			Request:    []byte(multusCSRPEM),
+		},
+	}
+
+	pendingTime := baseTime.Add(time.Second)
+	pastApprovalTime := baseTime.Add(-maxPendingDelta)
+	preApprovalTime := baseTime.Add(10 * time.Second)
+
+	createdAt := func(time time.Time, csr certificatesv1.CertificateSigningRequest) certificatesv1.CertificateSigningRequest {
+		csr.CreationTimestamp.Time = time
+		return csr
+	}
+
+	tests := []struct {
+		name          string
+		config        ClusterMachineApproverConfig
+		csrs          []certificatesv1.CertificateSigningRequest
+		expectPending int
+	}{
+		{
+			name:          "recently pending Node bootstrapper csr",
+			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(pendingTime, pendingNodeBootstrapperCSR)},
+			expectPending: 1,
+		},
+		{
+			name:          "recently pending Node csr",
+			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(pendingTime, pendingNodeServerCSR)},
+			expectPending: 1,
+		},
+		{
+			name:          "recently pending unknown csr",
+			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(pendingTime, pendingCSR)},
+			expectPending: 0,
+		},
+		{
+			name:          "recently approved csr",
+			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(pendingTime, approvedNodeBootstrapperCSR)},
+			expectPending: 0,
+		},
+		{
+			name:          "pending past approval time",
+			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(pastApprovalTime, pendingNodeBootstrapperCSR)},
+			expectPending: 0,
+		},
+		{
+			name:          "pending before approval time",
+			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(preApprovalTime, pendingNodeBootstrapperCSR)},
+			expectPending: 0,
+		},
+		{
+			name:          "recently pending node client renewal csr",
+			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(pendingTime, pendingNodeClientRenewalCSR)},
+			expectPending: 0,
+		},
+		{
+			name: "recently pending node client renewal csr with renewal allowed",
+			config: ClusterMachineApproverConfig{
+				NodeClientCert: NodeClientCert{AllowRenewal: true},
+			},
+			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(pendingTime, pendingNodeClientRenewalCSR)},
+			expectPending: 1,
+		},
+		{
+			name:          "multus node CSR",
+			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(pendingTime, pendingMultusCSR)},
+			expectPending: 0,
+		},
+		{
+			name: "csrs unrelated to machines",
+			csrs: []certificatesv1.CertificateSigningRequest{
+				createdAt(pendingTime, pendingCSR),
+				createdAt(pendingTime, pendingMultusCSR),
+				createdAt(pendingTime, pendingNodeServerCSRMissingGroups)},
+			expectPending: 0,
+		},
+		{
+			name: "multiple different csrs",
+			csrs: []certificatesv1.CertificateSigningRequest{
+				createdAt(pendingTime, pendingNodeBootstrapperCSR),
+				createdAt(pendingTime, pendingNodeBootstrapperCSR),
+				createdAt(pendingTime, pendingNodeServerCSR),
+
+				createdAt(pendingTime, pendingCSR),
+				createdAt(pendingTime, approvedNodeBootstrapperCSR),
+				createdAt(preApprovalTime, approvedNodeBootstrapperCSR),
+				createdAt(pastApprovalTime, approvedNodeBootstrapperCSR),
+				createdAt(preApprovalTime, pendingNodeBootstrapperCSR),
+				createdAt(pastApprovalTime, pendingNodeBootstrapperCSR),
+			},
+			expectPending: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if pending := recentlyPendingNodeCSRs(tt.config, tt.csrs); pending != tt.expectPending {
+				t.Errorf("Expected %v pending CSRs, got: %v", tt.expectPending, pending)
+			}
+		})
+	}
+}
+
+func TestOldestRecentlyPendingNodeCSRAge(t *testing.T) {
+	approvedNodeBootstrapperCSR := certificatesv1.CertificateSigningRequest{
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
+			Username:   nodeBootstrapperUsername,
+			Groups:     nodeBootstrapperGroups.List(),
+		},
+		Status: certificatesv1.CertificateSigningRequestStatus{
+			Conditions: []certificatesv1.CertificateSigningRequestCondition{{
+				Type: certificatesv1.CertificateApproved,
+			}},
+		},
+	}
+	pendingNodeBootstrapperCSR := certificatesv1.CertificateSigningRequest{
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
+			Username:   nodeBootstrapperUsername,
+			Groups:     nodeBootstrapperGroups.List(),
+		},
+	}
+	pendingNodeServerCSR := certificatesv1.CertificateSigningRequest{
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Username:   nodeUserPrefix + "clustername-abcde-master-us-west-1a-0",
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Groups:     nodeServingGroups.List(),
+		},
+	}
+
+	createdAgo := func(age time.Duration, csr certificatesv1.CertificateSigningRequest) certificatesv1.CertificateSigningRequest {
+		csr.CreationTimestamp.Time = baseTime.Add(-age)
+		return csr
+	}
+
+	tests := []struct {
+		name      string
+		csrs      []certificatesv1.CertificateSigningRequest
+		expectAge time.Duration
+	}{
+		{
+			name:      "no csrs",
+			expectAge: 0,
+		},
+		{
+			name:      "single pending csr",
+			csrs:      []certificatesv1.CertificateSigningRequest{createdAgo(5*time.Minute, pendingNodeServerCSR)},
+			expectAge: 5 * time.Minute,
+		},
+		{
+			name: "pending csrs of varying ages",
+			csrs: []certificatesv1.CertificateSigningRequest{
+				createdAgo(time.Minute, pendingNodeBootstrapperCSR),
+				createdAgo(20*time.Minute, pendingNodeServerCSR),
+				createdAgo(10*time.Minute, pendingNodeBootstrapperCSR),
+			},
+			expectAge: 20 * time.Minute,
+		},
+		{
+			name: "older approved csr is ignored",
+			csrs: []certificatesv1.CertificateSigningRequest{
+				createdAgo(2*time.Minute, pendingNodeBootstrapperCSR),
+				createdAgo(30*time.Minute, approvedNodeBootstrapperCSR),
+			},
+			expectAge: 2 * time.Minute,
+		},
+		{
+			name: "only approved csrs",
+			csrs: []certificatesv1.CertificateSigningRequest{
+				createdAgo(30*time.Minute, approvedNodeBootstrapperCSR),
+			},
+			expectAge: 0,
+		},
+		{
+			name: "csr pending past approval time is ignored",
+			csrs: []certificatesv1.CertificateSigningRequest{
+				createdAgo(3*time.Minute, pendingNodeServerCSR),
+				createdAgo(maxPendingDelta+time.Minute, pendingNodeServerCSR),
+			},
+			expectAge: 3 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if age := oldestRecentlyPendingNodeCSRAge(ClusterMachineApproverConfig{}, tt.csrs); age != tt.expectAge {
+				t.Errorf("Expected oldest pending CSR age %v, got: %v", tt.expectAge, age)
+			}
+		})
+	}
+}
+
+func TestNodeInternalIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		node    *corev1.Node
+		wantIP  string
+		wantErr string
+	}{
+		{
+			name: "no addresses",
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "no-addresses",
+				},
+				Status: corev1.NodeStatus{
+					Addresses: []corev1.NodeAddress{},
+				},
+			},
+			wantErr: "node no-addresses has no internal addresses",
+		},
+		{
+			name: "no internal ip",
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "no-internal-ip",
+				},
+				Status: corev1.NodeStatus{
+					Addresses: []corev1.NodeAddress{
+						{Type: corev1.NodeHostName, Address: "host.example.com"},
+					},
+				},
+			},
+			wantErr: "node no-internal-ip has no internal addresses",
+		},
+		{
+			name: "has internal ip",
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "has-internal-ip",
+				},
+				Status: corev1.NodeStatus{
+					Addresses: []corev1.NodeAddress{
+						{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+					},
+				},
+			},
+			wantIP: "10.0.0.1",
+		},
+		{
+			name: "has ipv6 address",
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "has-ipv6-address",
+				},
+				Status: corev1.NodeStatus{
+					Addresses: []corev1.NodeAddress{
+						{Type: corev1.NodeInternalIP, Address: "2600:1f18:4254:5100:ef8a:7b65:7782:9248"},
+					},
+				},
+			},
+			wantIP: "2600:1f18:4254:5100:ef8a:7b65:7782:9248",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, err := nodeInternalIP(tt.node)
+
+			if errString(err) != tt.wantErr {
+				t.Errorf("got: %v, want: %s", err, tt.wantErr)
+			}
+
+			if ip != tt.wantIP {
+				t.Errorf("got: %v, want: %s", err, tt.wantIP)
+			}
+		})
+	}
+}
+
+func TestNodeKubeletIP(t *testing.T) {
+	newNode := func(addresses ...corev1.NodeAddress) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			Status:     corev1.NodeStatus{Addresses: addresses},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		node          *corev1.Node
+		allowExternal bool
+		wantIP        string
+		wantErr       string
+	}{
+		{
+			name: "internal ip present",
+			node: newNode(
+				corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "192.0.2.1"},
+				corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+			),
+			allowExternal: true,
+			wantIP:        "10.0.0.1",
+		},
+		{
+			name:          "external ip only",
+			node:          newNode(corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "192.0.2.1"}),
+			allowExternal: true,
+			wantIP:        "192.0.2.1",
+		},
+		{
+			name:    "external ip only but not allowed",
+			node:    newNode(corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "192.0.2.1"}),
+			wantErr: "node node has no internal addresses",
+		},
+		{
+			name:          "no ip",
+			node:          newNode(corev1.NodeAddress{Type: corev1.NodeHostName, Address: "host.example.com"}),
+			allowExternal: true,
+			wantErr:       "node node has no internal addresses",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, err := nodeKubeletIP(tt.node, tt.allowExternal)
+			if errString(err) != tt.wantErr {
+				t.Errorf("got: %v, want: %s", err, tt.wantErr)
+			}
+			if ip != tt.wantIP {
+				t.Errorf("got: %v, want: %s", ip, tt.wantIP)
+			}
+		})
+	}
+}
+
+func TestValidateNodeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodeName string
+		wantErr  bool
+	}{
+		{
+			name:     "valid",
+			nodeName: "ip-10-0-152-205.ec2.internal",
+		},
+		{
+			name:     "newline",
+			nodeName: "foo\nbar",
+			wantErr:  true,
+		},
+		{
+			name:     "null byte",
+			nodeName: "foo\x00bar",
+			wantErr:  true,
+		},
+		{
+			name:     "over-length",
+			nodeName: strings.Repeat("a", 254),
+			wantErr:  true,
+		},
+		{
+			name:     "upper case",
+			nodeName: "Node1",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateNodeName(tt.nodeName); (err != nil) != tt.wantErr {
+				t.Errorf("validateNodeName(%q) = %v, wantErr %v", tt.nodeName, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	errStr := err.Error()
+	if len(errStr) == 0 {
+		panic("invalid error")
+	}
+	return errStr
+}
+
+func creationTimestamp(delta time.Duration) metav1.Time {
+	return metav1.NewTime(baseTime.Add(delta))
+}
+
+func TestGetMaxPending(t *testing.T) {
+	testCases := []struct {
+		name        string
+		machines    []machinehandlerpkg.Machine
+		nodes       []corev1.Node
+		expectedMax int
+	}{
+		{
+			name: "with more machines than nodes",
+			machines: []machinehandlerpkg.Machine{
+				{},
+				{},
+				{},
+			},
+			nodes: []corev1.Node{
+				{},
+				{},
+			},
+			expectedMax: 3 + maxDiffBetweenPendingCSRsAndMachinesCount,
+		},
+		{
+			name: "with more nodes than machines",
+			machines: []machinehandlerpkg.Machine{
+				{},
+				{},
+				{},
+			},
+			nodes: []corev1.Node{
+				{},
+				{},
+				{},
+				{},
+			},
+			expectedMax: 4 + maxDiffBetweenPendingCSRsAndMachinesCount,
+		},
+	}
+
+	for _, tc := range testCases {
+		nodeList := &corev1.NodeList{
+			Items: tc.nodes,
+		}
+		res := getMaxPending(tc.machines, nodeList)
+		if res != tc.expectedMax {
+			t.Errorf("getMaxPending returned incorrect value: %v, expect: %v", res, tc.expectedMax)
+		}
+	}
+}
+
+func TestCountMachinesWithoutNodeRef(t *testing.T) {
+	machines := []machinehandlerpkg.Machine{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "provisioned"},
+			Status:     machinehandlerpkg.MachineStatus{NodeRef: &corev1.ObjectReference{Name: "node-0"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "provisioning"},
+			Status:     machinehandlerpkg.MachineStatus{Phase: "Provisioning"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "provisioned-without-node"},
+			Status: machinehandlerpkg.MachineStatus{
+				Phase:     "Provisioned",
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "running"},
+			Status: machinehandlerpkg.MachineStatus{
+				Phase:   "Running",
+				NodeRef: &corev1.ObjectReference{Name: "node-1"},
+			},
+		},
+	}
+
+	if got := countMachinesWithoutNodeRef(machines); got != 2 {
+		t.Errorf("expected 2 machines without node ref, got %d", got)
+	}
+	if got := countMachinesWithoutNodeRef(nil); got != 0 {
+		t.Errorf("expected no machines without node ref, got %d", got)
+	}
+
+	reconcileLimits(ClusterMachineApproverConfig{}, "csr", machines, &corev1.NodeList{}, nil)
+	if got := atomic.LoadUint32(&MachinesWithoutNodeRefCount); got != 2 {
+		t.Errorf("expected the metric to report 2 machines without node ref, got %d", got)
+	}
+}
+
+func TestEqualStrings(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        []string
+		b        []string
+		expected bool
+	}{
+		{
+			name:     "both empty",
+			a:        []string{},
+			b:        []string{},
+			expected: true,
+		},
+		{
+			name:     "nil and empty",
+			a:        nil,
+			b:        []string{},
+			expected: true,
+		},
+		{
+			name:     "both nil",
+			expected: true,
+		},
+		{
+			name:     "equal",
+			a:        []string{"a", "b"},
+			b:        []string{"a", "b"},
+			expected: true,
+		},
+		{
+			name:     "different order",
+			a:        []string{"b", "a"},
+			b:        []string{"a", "b"},
+			expected: true,
+		},
+		{
+			name:     "not equal",
+			a:        []string{"a"},
+			b:        []string{"a", "b"},
+			expected: false,
+		},
+		{
+			name:     "duplicates",
+			a:        []string{"a", "a"},
+			b:        []string{"a", "b"},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertNoChange(t, tt.a, tt.b, func(t *testing.T) {
+				if equal := equalStrings(tt.a, tt.b); equal != tt.expected {
+					t.Errorf("%v == %v :: wanted %v, got %v",
+						tt.a, tt.b, tt.expected, equal)
+				}
+			})
+		})
+	}
+}
+
+func TestEqualURLs(t *testing.T) {
+	exampleNet, err := url.Parse("http://example.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exampleOrg, err := url.Parse("https://example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		a        []*url.URL
+		b        []*url.URL
+		expected bool
+	}{
+		{
+			name:     "both empty",
+			a:        []*url.URL{},
+			b:        []*url.URL{},
+			expected: true,
+		},
+		{
+			name:     "nil and empty",
+			a:        nil,
+			b:        []*url.URL{},
+			expected: true,
+		},
+		{
+			name:     "both nil",
+			expected: true,
+		},
+		{
+			name:     "equal",
+			a:        []*url.URL{exampleNet, exampleOrg},
+			b:        []*url.URL{exampleNet, exampleOrg},
+			expected: true,
+		},
+		{
+			name:     "different order",
+			a:        []*url.URL{exampleOrg, exampleNet},
+			b:        []*url.URL{exampleNet, exampleOrg},
+			expected: true,
+		},
+		{
+			name:     "not equal",
+			a:        []*url.URL{exampleOrg},
+			b:        []*url.URL{exampleNet, exampleOrg},
+			expected: false,
+		},
+		{
+			name:     "duplicates",
+			a:        []*url.URL{exampleOrg, exampleOrg},
+			b:        []*url.URL{exampleNet, exampleOrg},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if equal := equalURLs(tt.a, tt.b); equal != tt.expected {
+				t.Errorf("%v == %v :: wanted %v, got %v",
+					tt.a, tt.b, tt.expected, equal)
+			}
+		})
+	}
+}
+
+func TestEqualIPAddresses(t *testing.T) {
+	tenDotOne := net.ParseIP("10.0.0.1")
+	tenDotTwo := net.ParseIP("10.0.0.2")
+
+	tests := []struct {
+		name     string
+		a        []net.IP
+		b        []net.IP
+		expected bool
+	}{
+		{
+			name:     "both empty",
+			a:        []net.IP{},
+			b:        []net.IP{},
+			expected: true,
+		},
+		{
+			name:     "nil and empty",
+			a:        nil,
+			b:        []net.IP{},
+			expected: true,
+		},
+		{
+			name:     "both nil",
+			expected: true,
+		},
+		{
+			name:     "equal",
+			a:        []net.IP{tenDotOne, tenDotTwo},
+			b:        []net.IP{tenDotOne, tenDotTwo},
+			expected: true,
+		},
+		{
+			name:     "different order",
+			a:        []net.IP{tenDotTwo, tenDotOne},
+			b:        []net.IP{tenDotOne, tenDotTwo},
+			expected: true,
+		},
+		{
+			name:     "not equal",
+			a:        []net.IP{tenDotTwo},
+			b:        []net.IP{tenDotOne, tenDotTwo},
+			expected: false,
+		},
+		{
+			name:     "duplicates",
+			a:        []net.IP{tenDotOne, tenDotOne},
+			b:        []net.IP{tenDotOne, tenDotTwo},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if equal := equalIPAddresses(tt.a, tt.b); equal != tt.expected {
+				t.Errorf("%v == %v :: wanted %v, got %v",
+					tt.a, tt.b, tt.expected, equal)
+			}
+		})
+	}
+}
+
+func TestSubsetIPAddresses(t *testing.T) {
+	tenDotOne := net.ParseIP("10.0.0.1")
+	tenDotTwo := net.ParseIP("10.0.0.2")
+	tenDotThree := net.ParseIP("10.0.0.3")
+	tenOneThree := net.ParseIP("10.0.1.3")
+	_, tenNoughtSlash24, _ := net.ParseCIDR("10.0.0.0/24")
+	fdOneDotOne := net.ParseIP("fd00:0:0:1::1")
+	fdOneDotTwo := net.ParseIP("fd00:0:0:1::2")
+	fdTwoDotOne := net.ParseIP("fd00:0:0:2::1")
+	_, fdOneSlash64, _ := net.ParseCIDR("fd00:0:0:1::/64")
+
+	tests := []struct {
+		name     string
+		cidrs    []*net.IPNet
+		super    []net.IP
+		sub      []net.IP
+		expected bool
+	}{
+		{
+			name:     "equal sets",
+			super:    []net.IP{tenDotOne, tenDotTwo},
+			sub:      []net.IP{tenDotOne, tenDotTwo},
+			expected: true,
+		},
+		{
+			name:     "sub is a subset",
+			super:    []net.IP{tenDotOne, tenDotTwo},
+			sub:      []net.IP{tenDotOne},
+			expected: true,
+		},
+		{
+			name:     "sub is a superset",
+			super:    []net.IP{tenDotOne, tenDotTwo},
+			sub:      []net.IP{tenDotOne, tenDotThree},
+			expected: false,
+		},
+		{
+			name:     "sub is a subset with duplicates",
+			super:    []net.IP{tenDotOne, tenDotTwo},
+			sub:      []net.IP{tenDotOne, tenDotOne},
+			expected: true,
+		},
+		{
+			name:     "sub is a subset when cidrs are included",
+			cidrs:    []*net.IPNet{tenNoughtSlash24},
+			super:    []net.IP{tenOneThree},
+			sub:      []net.IP{tenDotOne, tenOneThree},
+			expected: true,
+		},
+		{
+			name:     "IPv6 equal sets",
+			super:    []net.IP{fdOneDotOne, tenDotOne},
+			sub:      []net.IP{tenDotOne, fdOneDotOne},
+			expected: true,
+		},
+		{
+			name:     "IPv6 sub is a superset",
+			super:    []net.IP{fdOneDotOne},
+			sub:      []net.IP{fdOneDotOne, fdOneDotTwo},
+			expected: false,
+		},
+		{
+			name:     "IPv6 sub is a subset when cidrs are included",
+			cidrs:    []*net.IPNet{tenNoughtSlash24, fdOneSlash64},
+			super:    []net.IP{tenOneThree},
+			sub:      []net.IP{tenDotOne, fdOneDotOne, fdOneDotTwo},
+			expected: true,
+		},
+		{
+			name:     "IPv6 sub is not in cidrs",
+			cidrs:    []*net.IPNet{tenNoughtSlash24, fdOneSlash64},
+			super:    []net.IP{fdOneDotOne},
+			sub:      []net.IP{fdTwoDotOne},
+			expected: false,
+		},
+		{
+			name:     "IPv4 cidr does not include IPv6 address",
+			cidrs:    []*net.IPNet{tenNoughtSlash24},
+			sub:      []net.IP{net.ParseIP("::a00:1")},
+			expected: false,
+		},
+		{
+			name:     "IPv4-mapped IPv6 address matches IPv4 address",
+			cidrs:    []*net.IPNet{tenNoughtSlash24},
+			super:    []net.IP{tenOneThree},
+			sub:      []net.IP{net.ParseIP("::ffff:10.0.1.3"), net.ParseIP("::ffff:10.0.0.1")},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if equal := subsetIPAddresses(tt.cidrs, tt.super, tt.sub); equal != tt.expected {
+				t.Errorf("%v subset of %v :: wanted %v, got %v",
+					tt.sub, tt.super, tt.expected, equal)
+			}
+		})
+	}
+}
+
+func TestCsrSANs(t *testing.T) {
+	uri, _ := url.Parse("http://example.com")
+	cr := &x509.CertificateRequest{
+		DNSNames:       []string{"test.local", "localhost"},
+		EmailAddresses: []string{"exaple@test.com"},
+		IPAddresses:    []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("10.0.0.1")},
+		URIs:           []*url.URL{uri},
+	}
+
+	sans := sets.NewString(csrSANs(cr)...)
+	if !sans.HasAll(
+		"http://example.com", "test.local",
+		"localhost", "exaple@test.com",
+		"127.0.0.1", "10.0.0.1") {
+		t.Errorf("Not all SANs were collected")
+	}
+
+	if len(csrSANs(nil)) > 0 {
+		t.Errorf("No SANs are expected from nil")
+	}
+}
+
+func TestCertSANs(t *testing.T) {
+	uri, _ := url.Parse("http://example.com")
+	cert := &x509.Certificate{
+		DNSNames:       []string{"test.local", "localhost"},
+		EmailAddresses: []string{"exaple@test.com"},
+		IPAddresses:    []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("10.0.0.1")},
+		URIs:           []*url.URL{uri},
+	}
+
+	sans := sets.NewString(certSANs(cert)...)
+	if !sans.HasAll(
+		"http://example.com", "test.local",
+		"localhost", "exaple@test.com",
+		"127.0.0.1", "10.0.0.1") {
+		t.Errorf("Not all SANs were collected")
+	}
+
+	if len(certSANs(nil)) > 0 {
+		t.Errorf("No SANs are expected from nil")
+	}
+}
+
+func TestSANAddressDiff(t *testing.T) {
+	cr := &x509.CertificateRequest{
+		DNSNames:    []string{"Node1.local", "node2.local"},
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("192.168.0.1")},
+	}
+	addresses := []corev1.NodeAddress{
+		{Type: corev1.NodeInternalDNS, Address: "node1.local."},
+		{Type: corev1.NodeHostName, Address: "node1"},
+		{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+		{Type: corev1.NodeExternalIP, Address: "203.0.113.1"},
+	}
+
+	missing, unrequested := sanAddressDiff(cr, addresses)
+	if want := []string{"192.168.0.1", "node2.local"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("expected SANs not in addresses %v, got %v", want, missing)
+	}
+	if want := []string{"203.0.113.1", "node1"}; !reflect.DeepEqual(unrequested, want) {
+		t.Errorf("expected addresses not requested %v, got %v", want, unrequested)
+	}
+
+	missing, unrequested = sanAddressDiff(nil, nil)
+	if len(missing) > 0 || len(unrequested) > 0 {
+		t.Errorf("expected no difference from nil, got %v and %v", missing, unrequested)
+	}
+}
+func TestSetAnnotations(t *testing.T) {
+	tests := []struct {
+		name            string
+		annotations     map[string]string
+		set             map[string]string
+		wantChanged     bool
+		wantAnnotations map[string]string
+	}{
+		{
+			name:            "annotates with renewal",
+			set:             map[string]string{authorizedByAnnotation: string(authorizedByRenewal)},
+			wantChanged:     true,
+			wantAnnotations: map[string]string{authorizedByAnnotation: "renewal"},
+		},
+		{
+			name:        "annotates with machine",
+			annotations: map[string]string{"foo": "bar"},
+			set:         map[string]string{authorizedByAnnotation: string(authorizedByMachine)},
+			wantChanged: true,
+			wantAnnotations: map[string]string{
+				"foo":                  "bar",
+				authorizedByAnnotation: "machine",
+			},
+		},
+		{
+			name: "annotates with egress and correlation ID",
+			set: map[string]string{
+				authorizedByAnnotation:  string(authorizedByEgress),
+				correlationIDAnnotation: "abc",
+			},
+			wantChanged: true,
+			wantAnnotations: map[string]string{
+				authorizedByAnnotation:  "egress",
+				correlationIDAnnotation: "abc",
+			},
+		},
+		{
+			name:            "already annotated",
+			annotations:     map[string]string{authorizedByAnnotation: "machine"},
+			set:             map[string]string{authorizedByAnnotation: string(authorizedByMachine)},
+			wantChanged:     false,
+			wantAnnotations: map[string]string{authorizedByAnnotation: "machine"},
+		},
+		{
+			name:        "no method",
+			set:         map[string]string{authorizedByAnnotation: ""},
+			wantChanged: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csr := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: tt.annotations,
+				},
+			}
+
+			if changed := setAnnotations(csr, tt.set); changed != tt.wantChanged {
+				t.Errorf("setAnnotations() changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if !reflect.DeepEqual(csr.Annotations, tt.wantAnnotations) {
+				t.Errorf("annotations = %v, want %v", csr.Annotations, tt.wantAnnotations)
+			}
+		})
+	}
+}
+
+func TestCorrelationID(t *testing.T) {
+	var logs bytes.Buffer
+	klog.LogToStderr(false)
+	klog.SetOutput(&logs)
+	defer klog.LogToStderr(true)
+
+	csr := certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "csr-traced",
+			UID:         "uid-1234",
+			Annotations: map[string]string{correlationIDAnnotation: "trace-5678"},
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageKeyEncipherment,
+				certificatesv1.UsageServerAuth,
+			},
+			Username: "system:node:test",
+			Groups: []string{
+				"system:authenticated",
+				"system:nodes",
+			},
+			Request: []byte(goodCSR),
+		},
+	}
+
+	if id := getCorrelationID(&csr); id != "trace-5678" {
+		t.Errorf("expected correlation ID from annotation, got %q", id)
+	}
+
+	withoutAnnotation := csr.DeepCopy()
+	withoutAnnotation.Annotations = nil
+	if id := getCorrelationID(withoutAnnotation); id != "uid-1234" {
+		t.Errorf("expected correlation ID derived from UID, got %q", id)
+	}
+
+	withoutAnnotation.UID = ""
+	if id := getCorrelationID(withoutAnnotation); id == "" {
+		t.Error("expected a generated correlation ID")
+	}
+
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewFakeClient(&configv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}),
+	}
+	if _, err := approver.reconcileCSR(context.Background(), csr, nil); err == nil {
+		t.Fatal("expected CSR not to be authorized without machines")
+	}
+	klog.Flush()
+
+	for _, decision := range []string{"Authorizing CSR", "CSR not authorized"} {
+		found := false
+		for _, line := range strings.Split(logs.String(), "\n") {
+			if strings.Contains(line, decision) && strings.Contains(line, "correlation ID trace-5678") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected %q to be logged with the correlation ID, got:\n%s", decision, logs.String())
+		}
+	}
+}
+
+func TestGetKubeletCA(t *testing.T) {
+	rotatedCert, _, err := generateCertKeyPair(time.Hour, nil, nil, "kubelet-ca-rotated")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kubeletCAConfigMap,
+			Namespace: configNamespace,
+		},
+		Data: map[string]string{
+			"ca-bundle.crt": rootCertGood,
+		},
+	}
+
+	cl := fake.NewClientBuilder().
+		WithRuntimeObjects(configMap).
+		WithIndex(&certificatesv1.CertificateSigningRequest{}, signerNameField, func(obj client.Object) []string {
+			return []string{obj.(*certificatesv1.CertificateSigningRequest).Spec.SignerName}
+		}).
+		Build()
+	approver := &CertificateApprover{WorkloadClient: cl}
+
+	pool := approver.getKubeletCA()
+	if pool == nil {
+		t.Fatal("expected kubelet CA to be loaded")
+	}
+	if _, err := parseCert(t, serverCertGood).Verify(x509.VerifyOptions{Roots: pool}); err != nil {
+		t.Fatalf("expected serving cert to verify against kubelet CA: %v", err)
+	}
+
+	// Unrelated reconciles must reuse the cached pool.
+	if approver.getKubeletCA() != pool {
+		t.Fatal("expected cached kubelet CA to be reused")
+	}
+
+	// Mutating the ConfigMap alone does not invalidate the cache.
+	configMap.Data["ca-bundle.crt"] = string(rotatedCert)
+	if err := cl.Update(context.Background(), configMap); err != nil {
+		t.Fatal(err)
+	}
+	if approver.getKubeletCA() != pool {
+		t.Fatal("expected cached kubelet CA to be reused until the watch fires")
+	}
+
+	// The watch firing rebuilds the pool from the new data.
+	approver.toCSRs(context.Background(), configMap)
+	rebuilt := approver.getKubeletCA()
+	if rebuilt == nil || rebuilt == pool {
+		t.Fatal("expected kubelet CA to be rebuilt")
+	}
+	if _, err := parseCert(t, string(rotatedCert)).Verify(x509.VerifyOptions{Roots: rebuilt}); err != nil {
+		t.Fatalf("expected rotated CA in rebuilt pool: %v", err)
+	}
+	if _, err := parseCert(t, serverCertGood).Verify(x509.VerifyOptions{Roots: rebuilt}); err == nil {
+		t.Fatal("expected previous CA to be dropped from rebuilt pool")
+	}
+}
+
+type fakeCacheSyncWaiter struct {
+	synced chan struct{}
+}
+
+func (f *fakeCacheSyncWaiter) WaitForCacheSync(ctx context.Context) bool {
+	select {
+	case <-f.synced:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func TestStartupDelay(t *testing.T) {
+	approver := &CertificateApprover{StartupDelay: 100 * time.Millisecond}
+	cache := &fakeCacheSyncWaiter{synced: make(chan struct{})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- approver.waitForStartupDelay(ctx, cache)
+	}()
+
+	assertDeferred := func() {
+		t.Helper()
+		result, err := approver.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKey{Name: "csr"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.RequeueAfter != startupDelayRequeueInterval {
+			t.Fatalf("expected CSR to be requeued after %v, got %v", startupDelayRequeueInterval, result.RequeueAfter)
+		}
+	}
+
+	// Caches have not synced, approvals must be deferred even once the delay would have elapsed.
+	time.Sleep(2 * approver.StartupDelay)
+	assertDeferred()
+
+	close(cache.synced)
+	syncedAt := time.Now()
+
+	// Caches synced but the delay has not elapsed yet.
+	assertDeferred()
+
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(syncedAt); elapsed < approver.StartupDelay {
+		t.Errorf("approvals allowed after %v, expected at least %v", elapsed, approver.StartupDelay)
+	}
+	if !approver.approvalsAllowed.Load() {
+		t.Error("expected approvals to be allowed once the startup delay elapsed")
+	}
+}
+
+func TestStartupObservePeriod(t *testing.T) {
+	var approvals int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&approvals, 1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	approver := &CertificateApprover{
+		StartupObservePeriod: 100 * time.Millisecond,
+		NodeRestCfg:          &rest.Config{Host: server.URL},
+		WorkloadClient:       fake.NewFakeClient(),
+	}
+	approver.observing.Store(true)
+	cache := &fakeCacheSyncWaiter{synced: make(chan struct{})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- approver.waitForStartupObservePeriod(ctx, cache)
+	}()
+
+	csr := certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Username:   "system:node:test",
+			Request:    []byte(goodCSR),
+		},
+	}
+	var authorizations int
+	authorized := func(ClusterMachineApproverConfig, *certificatesv1.CertificateSigningRequest, *x509.CertificateRequest, *x509.CertPool) (authorizationResult, error) {
+		authorizations++
+		return authorizationResult{Authorized: true, Method: authorizedByMachine}, nil
+	}
+	reconcileCSR := func() reconcile.Result {
+		t.Helper()
+		result, err := approver.reconcileCSRWith(ctx, csr, authorized)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result
+	}
+	assertDeferred := func() {
+		t.Helper()
+		before := authorizations
+		if result := reconcileCSR(); result.RequeueAfter != startupObserveRequeueInterval {
+			t.Fatalf("expected CSR to be requeued after %v, got %v", startupObserveRequeueInterval, result.RequeueAfter)
+		}
+		if authorizations != before+1 {
+			t.Errorf("expected CSR to be authorized while observing")
+		}
+		if got := atomic.LoadInt32(&approvals); got != 0 {
+			t.Errorf("expected no approvals while observing, got %d", got)
+		}
+	}
+
+	// Caches have not synced, approvals must be deferred even once the period would have elapsed.
+	time.Sleep(2 * approver.StartupObservePeriod)
+	assertDeferred()
+
+	close(cache.synced)
+	syncedAt := time.Now()
+
+	// Caches synced but the period has not elapsed yet.
+	assertDeferred()
+
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(syncedAt); elapsed < approver.StartupObservePeriod {
+		t.Errorf("approvals allowed after %v, expected at least %v", elapsed, approver.StartupObservePeriod)
+	}
+
+	if result := reconcileCSR(); result.RequeueAfter != 0 {
+		t.Errorf("expected CSR not to be requeued once the observe period elapsed, got %v", result.RequeueAfter)
+	}
+	if got := atomic.LoadInt32(&approvals); got != 1 {
+		t.Errorf("expected CSR to be approved once the observe period elapsed, got %d approvals", got)
+	}
+}
+
+func TestMachineAddressCache(t *testing.T) {
+	defer func(original func() time.Time) { now = original }(now)
+
+	addresses := []corev1.NodeAddress{
+		{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+		{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+		{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+		{Type: corev1.NodeExternalDNS, Address: "node1"},
+	}
+	machine := func(addresses ...corev1.NodeAddress) []machinehandlerpkg.Machine {
+		return []machinehandlerpkg.Machine{{
+			ObjectMeta: metav1.ObjectMeta{Name: "machine", Namespace: "openshift-machine-api"},
+			Status: machinehandlerpkg.MachineStatus{
+				NodeRef:   &corev1.ObjectReference{Name: "test"},
+				Addresses: addresses,
+			},
+		}}
+	}
+
+	req := &certificatesv1.CertificateSigningRequest{
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageKeyEncipherment,
+				certificatesv1.UsageServerAuth,
+			},
+			Username: "system:node:test",
+			Groups: []string{
+				"system:authenticated",
+				"system:nodes",
+			},
+			Request: []byte(goodCSR),
+		},
+	}
+	parsedCSR, err := parseCSR(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := fake.NewFakeClient(&configv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}})
+	authorize := func(machines []machinehandlerpkg.Machine) bool {
+		result, _ := authorizeCSR(context.Background(), cl, ClusterMachineApproverConfig{}, machines, req, parsedCSR, nil, nil, nil)
+		return result.Authorized
+	}
+
+	ttl := time.Minute
+	clock := testingclock.NewFakeClock(baseTime)
+	now = clock.Now
+
+	t.Run("disabled", func(t *testing.T) {
+		cache := &machineAddressCache{}
+		cache.apply(machine(addresses...), 0)
+		if got := cache.apply(machine(), 0); authorize(got) {
+			t.Fatal("expected no approval without cached addresses")
+		}
+	})
+
+	cache := &machineAddressCache{}
+	if got := cache.apply(machine(addresses...), ttl); !authorize(got) {
+		t.Fatal("expected approval with current addresses")
+	}
+
+	clock.Step(ttl / 2)
+	got := cache.apply(machine(), ttl)
+	if !reflect.DeepEqual(got[0].Status.Addresses, addresses) {
+		t.Fatalf("expected cached addresses %v, got %v", addresses, got[0].Status.Addresses)
+	}
+	if !authorize(got) {
+		t.Fatal("expected approval with cached addresses")
+	}
+
+	clock.Step(ttl)
+	if got := cache.apply(machine(), ttl); authorize(got) || len(got[0].Status.Addresses) != 0 {
+		t.Fatal("expected cached addresses to expire")
+	}
+}
+
+func TestGetKubeletCAAdditionalConfigMap(t *testing.T) {
+	rotatedCert, _, err := generateCertKeyPair(time.Hour, nil, nil, "kubelet-ca-rotated")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kubeletCA := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kubeletCAConfigMap,
+			Namespace: configNamespace,
+		},
+		Data: map[string]string{
+			"ca-bundle.crt": rootCertGood,
+		},
+	}
+	additionalCA := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kubelet-ca-next",
+			Namespace: configNamespace,
+		},
+		Data: map[string]string{
+			"next.crt": string(rotatedCert),
+		},
+	}
+
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewFakeClient(kubeletCA, additionalCA),
+		Config: ClusterMachineApproverConfig{
+			AdditionalKubeletCAConfigMap: ConfigMapKeyReference{
+				Name: "kubelet-ca-next",
+				Key:  "next.crt",
+			},
+		},
+	}
+
+	pool := approver.getKubeletCA()
+	if pool == nil {
+		t.Fatal("expected kubelet CA to be loaded")
+	}
+	for _, cert := range []string{serverCertGood, string(rotatedCert)} {
+		if _, err := parseCert(t, cert).Verify(x509.VerifyOptions{Roots: pool}); err != nil {
+			t.Errorf("expected certificate to verify against kubelet CA: %v", err)
+		}
+	}
+
+	refs := approver.kubeletCAConfigMaps()
+	updated := additionalCA.DeepCopy()
+	updated.Data["next.crt"] = rootCertGood
+	if !caConfigMapFilter(refs, additionalCA, updated) {
+		t.Error("expected changes to the additional kubelet CA to be watched")
+	}
+	updated.Data = map[string]string{"ca-bundle.crt": rootCertGood}
+	if caConfigMapFilter(refs, additionalCA, updated) {
+		t.Error("expected changes to other keys of the additional kubelet CA to be ignored")
+	}
+
+	// A missing additional ConfigMap does not prevent using the kubelet CA.
+	approver.WorkloadClient = fake.NewFakeClient(kubeletCA)
+	approver.resetKubeletCA()
+	if approver.getKubeletCA() == nil {
+		t.Error("expected kubelet CA to be loaded without the additional ConfigMap")
+	}
+}
+
+func TestAppendCertsFromPEM(t *testing.T) {
+	rotatedCert, _, err := generateCertKeyPair(time.Hour, nil, nil, "kubelet-ca-rotated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A CERTIFICATE block with headers is skipped, as by AppendCertsFromPEM
+	block, _ := pem.Decode(rotatedCert)
+	block.Headers = map[string]string{"Comment": "next kubelet CA"}
+	rotatedCertWithHeaders := string(pem.EncodeToMemory(block))
+	otherBlock := string(pem.EncodeToMemory(&pem.Block{Type: "EC PARAMETERS", Bytes: []byte{0x06, 0x08}}))
+
+	tests := []struct {
+		name         string
+		bundle       string
+		wantBlocks   int
+		wantAppended int
+	}{
+		{
+			name:         "certificates",
+			bundle:       rootCertGood + string(rotatedCert),
+			wantBlocks:   2,
+			wantAppended: 2,
+		},
+		{
+			name:         "comment and non-cert block",
+			bundle:       "# Kubelet CA bundle\n" + rootCertGood + otherBlock,
+			wantBlocks:   1,
+			wantAppended: 1,
+		},
+		{
+			name:         "certificate with headers",
+			bundle:       rootCertGood + otherBlock + rotatedCertWithHeaders,
+			wantBlocks:   2,
+			wantAppended: 1,
+		},
+		{
+			name:       "unparseable certificate",
+			bundle:     string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not a certificate")})),
+			wantBlocks: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certPool := x509.NewCertPool()
+			blocks, appended := appendCertsFromPEM(certPool, []byte(tt.bundle))
+			if blocks != tt.wantBlocks || appended != tt.wantAppended {
+				t.Errorf("expected %d of %d certificates appended, got %d of %d", tt.wantAppended, tt.wantBlocks, appended, blocks)
+			}
+
+			stdPool := x509.NewCertPool()
+			stdPool.AppendCertsFromPEM([]byte(tt.bundle))
+			if !certPool.Equal(stdPool) {
+				t.Error("expected the same certificates as AppendCertsFromPEM")
+			}
+		})
+	}
+}
+
+func TestServingRenewalDisabled(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	dialed := make(chan struct{}, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+			dialed <- struct{}{}
+		}
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+			},
+			DaemonEndpoints: corev1.NodeDaemonEndpoints{
+				KubeletEndpoint: corev1.DaemonEndpoint{Port: int32(port)},
+			},
+		},
+	}
+	machines := []machinehandlerpkg.Machine{{
+		Status: machinehandlerpkg.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "test"},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+				{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+				{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+				{Type: corev1.NodeExternalDNS, Address: "node1"},
+			},
+		},
+	}}
+	req := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "serving"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageKeyEncipherment,
+				certificatesv1.UsageServerAuth,
+			},
+			Username: "system:node:test",
+			Groups: []string{
+				"system:authenticated",
+				"system:nodes",
+			},
+			Request: []byte(goodCSR),
+		},
+	}
+	parsedCSR, err := parseCSR(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := x509.NewCertPool()
+	ca.AddCert(parseCert(t, rootCertGood))
+	cl := fake.NewFakeClient(node, &configv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}})
+
+	tests := []struct {
+		name       string
+		disabled   bool
+		wantDialed bool
+	}{
+		{
+			name:       "renewal enabled",
+			wantDialed: true,
+		},
+		{
+			name:       "renewal disabled",
+			disabled:   true,
+			wantDialed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ClusterMachineApproverConfig{
+				ServingRenewal: ServingRenewal{Disabled: tt.disabled},
+			}
+
+			result, err := authorizeCSR(context.Background(), cl, config, machines, req, parsedCSR, ca, nil, nil)
+			if err != nil || !result.Authorized || result.Method != authorizedByMachine {
+				t.Fatalf("expected CSR to be authorized by machine, got %+v, %v", result, err)
+			}
+
+			var gotDialed bool
+			select {
+			case <-dialed:
+				gotDialed = true
+			case <-time.After(100 * time.Millisecond):
+			}
+			if gotDialed != tt.wantDialed {
+				t.Errorf("kubelet dialed = %v, want %v", gotDialed, tt.wantDialed)
+			}
+		})
+	}
+}
+
+func TestFreshIssuanceWhenRenewalPossible(t *testing.T) {
+	caCert, caKey, err := generateCertKeyPair(time.Hour, nil, nil, "kubelet-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The current serving cert is valid, but lacks SANs the CSR requests,
+	// so the CSR cannot be authorized as its renewal.
+	servingCert, servingKey, err := generateCertKeyPair(time.Hour, caCert, caKey, "system:node:test", "node1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kubeletServer := fakeResponder(t, "127.0.0.1:0", string(servingCert), string(servingKey))
+	defer kubeletServer.Close()
+	go func() {
+		for {
+			conn, err := kubeletServer.Accept()
+			if err != nil {
+				return
+			}
+			// Complete the handshake, so that the serving cert is presented.
+			conn.Write([]byte("ok"))
+			conn.Close()
+		}
+	}()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+			},
+			DaemonEndpoints: corev1.NodeDaemonEndpoints{
+				KubeletEndpoint: corev1.DaemonEndpoint{Port: int32(kubeletServer.Addr().(*net.TCPAddr).Port)},
+			},
+		},
+	}
+	machines := []machinehandlerpkg.Machine{{
+		Status: machinehandlerpkg.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "test"},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+				{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+				{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+				{Type: corev1.NodeExternalDNS, Address: "node1"},
+			},
+		},
+	}}
+	req := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "serving"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageKeyEncipherment,
+				certificatesv1.UsageServerAuth,
+			},
+			Username: "system:node:test",
+			Groups: []string{
+				"system:authenticated",
+				"system:nodes",
+			},
+			Request: []byte(goodCSR),
+		},
+	}
+	parsedCSR, err := parseCSR(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := fake.NewFakeClient(node, &configv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}})
+
+	tests := []struct {
+		name      string
+		ca        []byte
+		wantCount uint32
+	}{
+		{
+			name:      "valid serving cert",
+			ca:        caCert,
+			wantCount: 1,
+		},
+		{
+			name:      "serving cert signed by another CA",
+			ca:        []byte(rootCertGood),
+			wantCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ca := x509.NewCertPool()
+			ca.AddCert(parseCert(t, string(tt.ca)))
+
+			before := atomic.LoadUint32(&FreshIssuanceWhenRenewalPossible)
+			result, err := authorizeCSR(context.Background(), cl, ClusterMachineApproverConfig{}, machines, req, parsedCSR, ca, nil, nil)
+			if err != nil || !result.Authorized || result.Method != authorizedByMachine {
+				t.Fatalf("expected CSR to be authorized by machine, got %+v, %v", result, err)
+			}
+			if got := atomic.LoadUint32(&FreshIssuanceWhenRenewalPossible) - before; got != tt.wantCount {
+				t.Errorf("expected the fresh issuance to be counted %d times, got %d", tt.wantCount, got)
+			}
+		})
+	}
+}
+
+func TestPendingNodeCertFilterServingGroup(t *testing.T) {
+	servingCSR := func(groups ...string) *certificatesv1.CertificateSigningRequest {
+		return &certificatesv1.CertificateSigningRequest{
+			Spec: certificatesv1.CertificateSigningRequestSpec{
+				Username:   nodeUserPrefix + "test",
+				SignerName: certificatesv1.KubeletServingSignerName,
+				Groups:     append([]string{"system:authenticated"}, groups...),
+			},
+		}
+	}
+	customGroupConfig := ClusterMachineApproverConfig{NodeServingCert: NodeServingCert{Group: "managed:nodes"}}
+
+	tests := []struct {
+		name   string
+		config ClusterMachineApproverConfig
+		csr    *certificatesv1.CertificateSigningRequest
+		want   bool
+	}{
+		{
+			name: "default group",
+			csr:  servingCSR(nodeGroup),
+			want: true,
+		},
+		{
+			name: "custom group without config",
+			csr:  servingCSR("managed:nodes"),
+			want: false,
+		},
+		{
+			name:   "custom group",
+			config: customGroupConfig,
+			csr:    servingCSR("managed:nodes"),
+			want:   true,
+		},
+		{
+			name:   "unrelated group with custom group config",
+			config: customGroupConfig,
+			csr:    servingCSR("unrelated:group"),
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pendingNodeCertFilter(tt.config, tt.csr); got != tt.want {
+				t.Errorf("pendingNodeCertFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// sleepingMachineLister blocks listing machines until its context is done,
+// like a slow management API server.
+type sleepingMachineLister struct {
+	ctx   context.Context
+	sleep time.Duration
+}
+
+func (l sleepingMachineLister) ListMachines(schema.GroupVersion) ([]machinehandlerpkg.Machine, error) {
+	select {
+	case <-time.After(l.sleep):
+		return nil, nil
+	case <-l.ctx.Done():
+		return nil, l.ctx.Err()
+	}
+}
+
+func TestReconcileMachineListTimeout(t *testing.T) {
+	pending := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "csr",
+			CreationTimestamp: metav1.NewTime(now()),
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Username:   "system:node:test",
+			Groups:     nodeServingGroups.List(),
+		},
+	}
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewClientBuilder().
+			WithObjects(pending).
+			WithIndex(&certificatesv1.CertificateSigningRequest{}, signerNameField, func(obj client.Object) []string {
+				return []string{obj.(*certificatesv1.CertificateSigningRequest).Spec.SignerName}
+			}).
+			Build(),
+		APIGroupVersions:   []schema.GroupVersion{{Group: "machine.openshift.io"}},
+		MachineListTimeout: 50 * time.Millisecond,
+		newMachineLister: func(ctx context.Context) machineLister {
+			return sleepingMachineLister{ctx: ctx, sleep: time.Minute}
+		},
+	}
+	approver.approvalsAllowed.Store(true)
+
+	start := time.Now()
+	result, err := approver.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKey{Name: "csr"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter != machineListRequeueInterval {
+		t.Errorf("expected CSR to be requeued after %v, got %v", machineListRequeueInterval, result.RequeueAfter)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("reconcile took %v, expected machine listing to time out after %v", elapsed, approver.MachineListTimeout)
+	}
+}
+
+// countingMachineLister counts the machine lists.
+type countingMachineLister struct {
+	lists *int32
+}
+
+func (l countingMachineLister) ListMachines(schema.GroupVersion) ([]machinehandlerpkg.Machine, error) {
+	atomic.AddInt32(l.lists, 1)
+	return nil, nil
+}
+
+func TestNodeToCSRs(t *testing.T) {
+	pending := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "pending",
+			CreationTimestamp: metav1.NewTime(now()),
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Username:   "system:node:test",
+			Groups:     nodeServingGroups.List(),
+		},
+	}
+	approved := pending.DeepCopy()
+	approved.Name = "approved"
+	approved.Status.Conditions = []certificatesv1.CertificateSigningRequestCondition{{
+		Type:               certificatesv1.CertificateApproved,
+		Message:            csrConditionApproveMessage,
+		LastTransitionTime: metav1.NewTime(now()),
+	}}
+
+	server := newEmptyCSRListServer()
+	defer server.Close()
+
+	var lists int32
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewClientBuilder().
+			WithObjects(pending, approved).
+			WithIndex(&certificatesv1.CertificateSigningRequest{}, signerNameField, func(obj client.Object) []string {
+				return []string{obj.(*certificatesv1.CertificateSigningRequest).Spec.SignerName}
+			}).
+			Build(),
+		NodeRestCfg:      &rest.Config{Host: server.URL},
+		APIGroupVersions: []schema.GroupVersion{{Group: "machine.openshift.io"}},
+		newMachineLister: func(context.Context) machineLister {
+			return countingMachineLister{lists: &lists}
+		},
+	}
+	approver.approvalsAllowed.Store(true)
+
+	pendingBefore, maxPendingBefore := atomic.LoadUint32(&PendingCSRs), atomic.LoadUint32(&MaxPendingCSRs)
+	defer func() {
+		atomic.StoreUint32(&PendingCSRs, pendingBefore)
+		atomic.StoreUint32(&MaxPendingCSRs, maxPendingBefore)
+	}()
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "deleted"}}
+
+	// Nothing is suppressed while the limit is not exceeded
+	atomic.StoreUint32(&PendingCSRs, 1)
+	atomic.StoreUint32(&MaxPendingCSRs, 1)
+	if requests := approver.nodeToCSRs(context.Background(), node); len(requests) != 0 {
+		t.Errorf("expected no CSR to be re-evaluated within the limit, got %v", requests)
+	}
+
+	// A node deleted while the limit is exceeded re-evaluates the pending CSRs
+	atomic.StoreUint32(&PendingCSRs, 2)
+	requests := approver.nodeToCSRs(context.Background(), node)
+	want := []reconcile.Request{{NamespacedName: client.ObjectKey{Name: "pending"}}}
+	if !reflect.DeepEqual(requests, want) {
+		t.Fatalf("expected requests %v, got %v", want, requests)
+	}
+
+	// Their reconcile recomputes the limit
+	if _, err := approver.Reconcile(context.Background(), requests[0]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := atomic.LoadUint32(&MaxPendingCSRs); got != maxDiffBetweenPendingCSRsAndMachinesCount {
+		t.Errorf("expected the limit to be recomputed as %d, got %d", maxDiffBetweenPendingCSRsAndMachinesCount, got)
+	}
+	if pendingLimitExceeded() {
+		t.Error("expected the limit not to be exceeded once recomputed")
+	}
+}
+
+// newEmptyCSRListServer serves empty CSR lists, e.g. to update the pending
+// CSRs limits once a CSR was reconciled.
+func newEmptyCSRListServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&certificatesv1.CertificateSigningRequestList{})
+	}))
+}
+
+func TestReconcileApprovedCSRSkipsMachineList(t *testing.T) {
+	approved := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "approved",
+			CreationTimestamp: metav1.NewTime(now()),
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Username:   "system:node:test",
+			Groups:     nodeServingGroups.List(),
+		},
+		Status: certificatesv1.CertificateSigningRequestStatus{
+			Conditions: []certificatesv1.CertificateSigningRequestCondition{{
+				Type:               certificatesv1.CertificateApproved,
+				Reason:             "AutoApproved",
+				LastTransitionTime: metav1.NewTime(now()),
+			}},
+		},
+	}
+	pending := approved.DeepCopy()
+	pending.Name = "pending"
+	pending.Status = certificatesv1.CertificateSigningRequestStatus{}
+
+	server := newEmptyCSRListServer()
+	defer server.Close()
+
+	var lists int32
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewClientBuilder().
+			WithObjects(approved, pending).
+			WithIndex(&certificatesv1.CertificateSigningRequest{}, signerNameField, func(obj client.Object) []string {
+				return []string{obj.(*certificatesv1.CertificateSigningRequest).Spec.SignerName}
+			}).
+			Build(),
+		NodeRestCfg:      &rest.Config{Host: server.URL},
+		APIGroupVersions: []schema.GroupVersion{{Group: "machine.openshift.io"}},
+		newMachineLister: func(context.Context) machineLister {
+			return countingMachineLister{lists: &lists}
+		},
+	}
+	approver.approvalsAllowed.Store(true)
+
+	if _, err := approver.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKey{Name: "approved"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt32(&lists); got != 0 {
+		t.Errorf("expected machines not to be listed for an approved CSR, listed %d times", got)
+	}
+	// The pending CSRs metric is still updated, it includes CSRs recently
+	// approved by another approver
+	if got := atomic.LoadUint32(&PendingCSRs); got != 2 {
+		t.Errorf("expected 2 pending CSRs, got %d", got)
+	}
+
+	// Machines are still listed for a pending CSR
+	_, _ = approver.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKey{Name: "pending"}})
+	if got := atomic.LoadInt32(&lists); got != 1 {
+		t.Errorf("expected machines to be listed once for a pending CSR, listed %d times", got)
+	}
+}
+
+func TestReconcileServingRenewalSkipsMachineList(t *testing.T) {
+	caCert, caKey, err := generateCertKeyPair(time.Hour, nil, nil, "kubelet-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var approvals int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/approval") {
+			atomic.AddInt32(&approvals, 1)
+			// Echo the updated CSR back
+			body, _ := io.ReadAll(r.Body)
+			_, _ = w.Write(body)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&certificatesv1.CertificateSigningRequestList{})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		servingSANs    []string
+		maxPendingCSRs int
+		wantApprovals  int32
+		wantLists      int32
+		wantDials      int32
+	}{
+		{
+			name:          "renewal authorized",
+			servingSANs:   defaultDNSNames,
+			wantApprovals: 1,
+			wantLists:     0,
+			wantDials:     1,
+		},
+		{
+			// The serving cert fetched ahead of the machines is reused.
+			name:          "renewal not authorized",
+			servingSANs:   []string{"node1"},
+			wantApprovals: 0,
+			wantLists:     1,
+			wantDials:     1,
+		},
+		{
+			// The kubelet is not even dialed while CSRs are suppressed.
+			name:           "pending CSRs limit exceeded",
+			servingSANs:    defaultDNSNames,
+			maxPendingCSRs: 1,
+			wantApprovals:  0,
+			wantLists:      0,
+			wantDials:      0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			servingCert, servingKey, err := generateCertKeyPair(time.Hour, caCert, caKey, "system:node:test", tt.servingSANs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			kubeletServer := fakeResponder(t, "127.0.0.1:0", string(servingCert), string(servingKey))
+			defer kubeletServer.Close()
+			var dials int32
+			go func() {
+				for {
+					conn, err := kubeletServer.Accept()
+					if err != nil {
+						return
+					}
+					atomic.AddInt32(&dials, 1)
+					conn.Write([]byte("ok"))
+					conn.Close()
+				}
+			}()
+
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Status: corev1.NodeStatus{
+					Addresses: []corev1.NodeAddress{
+						{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+					},
+					DaemonEndpoints: corev1.NodeDaemonEndpoints{
+						KubeletEndpoint: corev1.DaemonEndpoint{Port: int32(kubeletServer.Addr().(*net.TCPAddr).Port)},
+					},
+				},
+			}
+			kubeletCA := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: kubeletCAConfigMap, Namespace: configNamespace},
+				Data:       map[string]string{kubeletCABundleKey: string(caCert)},
+			}
+			csr := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "csr",
+					CreationTimestamp: metav1.NewTime(now()),
+				},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					SignerName: certificatesv1.KubeletServingSignerName,
+					Usages: []certificatesv1.KeyUsage{
+						certificatesv1.UsageDigitalSignature,
+						certificatesv1.UsageKeyEncipherment,
+						certificatesv1.UsageServerAuth,
+					},
+					Username: "system:node:test",
+					Groups:   nodeServingGroups.List(),
+					Request:  []byte(goodCSR),
+				},
+			}
+
+			otherCSR := csr.DeepCopy()
+			otherCSR.Name = "other-csr"
+
+			atomic.StoreInt32(&approvals, 0)
+			var lists int32
+			approver := &CertificateApprover{
+				WorkloadClient: fake.NewClientBuilder().
+					WithObjects(csr, otherCSR, node, kubeletCA, &configv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}).
+					WithIndex(&certificatesv1.CertificateSigningRequest{}, signerNameField, func(obj client.Object) []string {
+						return []string{obj.(*certificatesv1.CertificateSigningRequest).Spec.SignerName}
+					}).
+					Build(),
+				NodeRestCfg:      &rest.Config{Host: server.URL},
+				APIGroupVersions: []schema.GroupVersion{{Group: "machine.openshift.io"}},
+				Config:           ClusterMachineApproverConfig{MaxPendingCSRs: tt.maxPendingCSRs},
+				newMachineLister: func(context.Context) machineLister {
+					return countingMachineLister{lists: &lists}
+				},
+			}
+			approver.approvalsAllowed.Store(true)
+
+			_, _ = approver.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKey{Name: "csr"}})
+			if got := atomic.LoadInt32(&approvals); got != tt.wantApprovals {
+				t.Errorf("expected %d approvals, got %d", tt.wantApprovals, got)
+			}
+			if got := atomic.LoadInt32(&lists); got != tt.wantLists {
+				t.Errorf("expected machines to be listed %d times, listed %d times", tt.wantLists, got)
+			}
+			if got := atomic.LoadInt32(&dials); got != tt.wantDials {
+				t.Errorf("expected the kubelet to be dialed %d times, dialed %d times", tt.wantDials, got)
+			}
+		})
+	}
+}
+
+func TestReconcileCSRUnparseable(t *testing.T) {
+	csr := certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
+			Username:   "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+			Request:    []byte("-----BEGIN CERTIFICATE-----\nbm90IGEgQ1NS\n-----END CERTIFICATE-----\n"),
+		},
+	}
+	approver := &CertificateApprover{WorkloadClient: fake.NewFakeClient()}
+
+	before := atomic.LoadUint32(&UnparseableCSRs)
+	result, err := approver.reconcileCSR(context.Background(), csr, nil)
+	if err != nil {
+		t.Errorf("expected an unparseable CSR not to be retried, got error %v", err)
+	}
+	if result.RequeueAfter != 0 || result.Requeue {
+		t.Errorf("expected an unparseable CSR not to be requeued, got %+v", result)
+	}
+	if got := atomic.LoadUint32(&UnparseableCSRs) - before; got != 1 {
+		t.Errorf("expected the unparseable CSR to be counted once, got %d", got)
+	}
+}
+
+func TestReconcileCSRRecordsNodeEvent(t *testing.T) {
+	csr := certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "csr",
+			Annotations: map[string]string{correlationIDAnnotation: "id"},
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Username:   "system:node:test",
+			Request:    []byte(goodCSR),
+		},
+	}
+	mismatch := func(ClusterMachineApproverConfig, *certificatesv1.CertificateSigningRequest, *x509.CertificateRequest, *x509.CertPool) (authorizationResult, error) {
+		return authorizationResult{}, errors.New("IP address '10.0.0.1' not in machine addresses: 10.0.0.2")
+	}
+
+	tests := []struct {
+		name      string
+		objects   []client.Object
+		wantEvent string
+	}{
+		{
+			name:      "node exists",
+			objects:   []client.Object{&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test"}}},
+			wantEvent: "Warning ServingCSRNotAuthorized Serving CSR csr could not be authorized (correlation ID id): IP address '10.0.0.1' not in machine addresses: 10.0.0.2",
+		},
+		{
+			name: "node does not exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			approver := &CertificateApprover{
+				WorkloadClient: fake.NewClientBuilder().WithObjects(tt.objects...).Build(),
+				Recorder:       recorder,
+			}
+
+			if _, err := approver.reconcileCSRWith(context.Background(), csr, mismatch); err == nil {
+				t.Fatal("expected the authorization error to be returned")
+			}
+
+			select {
+			case event := <-recorder.Events:
+				if event != tt.wantEvent {
+					t.Errorf("expected event %q, got %q", tt.wantEvent, event)
+				}
+			default:
+				if tt.wantEvent != "" {
+					t.Errorf("expected event %q, got none", tt.wantEvent)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileCSRPrematureRenewalRequeue(t *testing.T) {
+	csr := certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Username:   "system:node:test",
+			Request:    []byte(goodCSR),
+		},
+	}
+	premature := func(ClusterMachineApproverConfig, *certificatesv1.CertificateSigningRequest, *x509.CertificateRequest, *x509.CertPool) (authorizationResult, error) {
+		return authorizationResult{Reason: "premature serving cert renewal", RequeueAfter: 20 * time.Minute}, nil
+	}
+	recorder := record.NewFakeRecorder(10)
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewClientBuilder().WithObjects(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test"}}).Build(),
+		Recorder:       recorder,
+	}
+
+	result, err := approver.reconcileCSRWith(context.Background(), csr, premature)
+	if err != nil {
+		t.Errorf("expected a premature renewal not to return an error, got %v", err)
+	}
+	if result.RequeueAfter != 20*time.Minute {
+		t.Errorf("expected the CSR to be requeued after 20m0s, got %v", result.RequeueAfter)
+	}
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("expected no event for a premature renewal, got %q", event)
+	default:
+	}
+}
+
+func TestReconcileCSRApprovalRateLimit(t *testing.T) {
+	var approvals int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&approvals, 1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	approver := &CertificateApprover{
+		Config:         ClusterMachineApproverConfig{MaxApprovalsPerMinute: 2},
+		NodeRestCfg:    &rest.Config{Host: server.URL},
+		WorkloadClient: fake.NewFakeClient(),
+	}
+	authorized := func(ClusterMachineApproverConfig, *certificatesv1.CertificateSigningRequest, *x509.CertificateRequest, *x509.CertPool) (authorizationResult, error) {
+		return authorizationResult{Authorized: true, Method: authorizedByMachine}, nil
+	}
+
+	for i, wantRequeueAfter := range []time.Duration{0, 0, 30 * time.Second} {
+		csr := certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("csr-%d", i)},
+			Spec: certificatesv1.CertificateSigningRequestSpec{
+				SignerName: certificatesv1.KubeletServingSignerName,
+				Username:   "system:node:test",
+				Request:    []byte(goodCSR),
+			},
+		}
+		result, err := approver.reconcileCSRWith(context.Background(), csr, authorized)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", csr.Name, err)
+		}
+		if result.RequeueAfter != wantRequeueAfter {
+			t.Errorf("%s: expected requeue after %v, got %v", csr.Name, wantRequeueAfter, result.RequeueAfter)
+		}
+	}
+	if got := atomic.LoadInt32(&approvals); got != 2 {
+		t.Errorf("expected 2 approvals within the rate, got %d", got)
+	}
+}
+
+func TestReconcileCSRApprovalRateLimitFailedApproval(t *testing.T) {
+	var approvals, failures int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first approval fails
+		if atomic.AddInt32(&failures, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		atomic.AddInt32(&approvals, 1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	approver := &CertificateApprover{
+		Config:         ClusterMachineApproverConfig{MaxApprovalsPerMinute: 1},
+		NodeRestCfg:    &rest.Config{Host: server.URL},
+		WorkloadClient: fake.NewFakeClient(),
+	}
+	authorized := func(ClusterMachineApproverConfig, *certificatesv1.CertificateSigningRequest, *x509.CertificateRequest, *x509.CertPool) (authorizationResult, error) {
+		return authorizationResult{Authorized: true, Method: authorizedByMachine}, nil
+	}
+	csr := certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Username:   "system:node:test",
+			Request:    []byte(goodCSR),
+		},
+	}
+
+	if _, err := approver.reconcileCSRWith(context.Background(), csr, authorized); err == nil {
+		t.Fatal("expected the failed approval to be retried")
+	}
+
+	// The failed approval did not use up the rate
+	result, err := approver.reconcileCSRWith(context.Background(), csr, authorized)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter != 0 {
+		t.Errorf("expected the retried approval not to be limited, got requeue after %v", result.RequeueAfter)
+	}
+	if got := atomic.LoadInt32(&approvals); got != 1 {
+		t.Errorf("expected 1 approval, got %d", got)
+	}
+}
+
+func TestReconcileSkipsRecentlyFailedKubeletDial(t *testing.T) {
+	caCert, _, err := generateCertKeyPair(time.Hour, nil, nil, "kubelet-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The kubelet hangs up before the TLS handshake completes
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	var dials int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&dials, 1)
+			conn.Close()
+		}
+	}()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "127.0.0.1"}},
+			DaemonEndpoints: corev1.NodeDaemonEndpoints{
+				KubeletEndpoint: corev1.DaemonEndpoint{Port: int32(listener.Addr().(*net.TCPAddr).Port)},
+			},
+		},
+	}
+	kubeletCA := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: kubeletCAConfigMap, Namespace: configNamespace},
+		Data:       map[string]string{kubeletCABundleKey: string(caCert)},
+	}
+	csr := certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "csr",
+			CreationTimestamp: metav1.NewTime(now()),
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageKeyEncipherment,
+				certificatesv1.UsageServerAuth,
+			},
+			Username: "system:node:test",
+			Groups:   nodeServingGroups.List(),
+			Request:  []byte(goodCSR),
+		},
+	}
+
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewFakeClient(node, kubeletCA),
+		Config: ClusterMachineApproverConfig{
+			ServingRenewal: ServingRenewal{DialFailureBackoff: metav1.Duration{Duration: time.Minute}},
+		},
+	}
+
+	expectDials := func(want int32) {
+		t.Helper()
+		if _, err := approver.reconcileCSR(context.Background(), csr, nil); err == nil {
+			t.Fatal("expected the CSR not to be authorized")
+		}
+		if got := atomic.LoadInt32(&dials); got != want {
+			t.Errorf("expected the kubelet to be dialed %d times, dialed %d times", want, got)
+		}
+	}
+
+	expectDials(1)
+	// The dial is skipped within the backoff
+	expectDials(1)
+	// A kubelet CA change forgets the failure
+	approver.resetKubeletCA()
+	expectDials(2)
+}
+
+func TestKubeletDialFailureCache(t *testing.T) {
+	var cache kubeletDialFailureCache
+	cache.add("disabled", 0)
+	if cache.failedWithin("disabled", time.Minute) {
+		t.Error("expected no failure to be recorded without a backoff")
+	}
+
+	cache.add("node-0", time.Minute)
+	if !cache.failedWithin("node-0", time.Minute) {
+		t.Error("expected a failure within the backoff")
+	}
+	if cache.failedWithin("node-0", 0) {
+		t.Error("expected no failure once the backoff is disabled")
+	}
+
+	for i := 1; i <= maxKubeletDialFailures; i++ {
+		cache.add(fmt.Sprintf("node-%d", i), time.Minute)
+	}
+	if len(cache.failures) != maxKubeletDialFailures {
+		t.Errorf("expected %d failures, got %d", maxKubeletDialFailures, len(cache.failures))
+	}
+
+	var nilCache *kubeletDialFailureCache
+	nilCache.add("node-0", time.Minute)
+	if nilCache.failedWithin("node-0", time.Minute) {
+		t.Error("expected a nil cache to remember nothing")
+	}
+}
+
+// flakyMachineLister fails the first machine lists, then recovers.
+type flakyMachineLister struct {
+	failures *int32
+}
+
+func (l flakyMachineLister) ListMachines(schema.GroupVersion) ([]machinehandlerpkg.Machine, error) {
+	if atomic.AddInt32(l.failures, -1) >= 0 {
+		return nil, errors.New("connection refused")
+	}
+	return nil, nil
+}
+
+func TestReconcileManagementClusterBackoff(t *testing.T) {
+	pending := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "pending",
+			CreationTimestamp: metav1.NewTime(now()),
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Username:   "system:node:test",
+			Groups:     nodeServingGroups.List(),
+		},
+	}
+
+	server := newEmptyCSRListServer()
+	defer server.Close()
+
+	failures := int32(3)
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewClientBuilder().
+			WithObjects(pending).
+			WithIndex(&certificatesv1.CertificateSigningRequest{}, signerNameField, func(obj client.Object) []string {
+				return []string{obj.(*certificatesv1.CertificateSigningRequest).Spec.SignerName}
+			}).
+			Build(),
+		NodeRestCfg:      &rest.Config{Host: server.URL},
+		APIGroupVersions: []schema.GroupVersion{{Group: "machine.openshift.io"}},
+		newMachineLister: func(context.Context) machineLister {
+			return flakyMachineLister{failures: &failures}
+		},
+	}
+	approver.approvalsAllowed.Store(true)
+
+	request := reconcile.Request{NamespacedName: client.ObjectKey{Name: "pending"}}
+	for _, want := range []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second} {
+		result, err := approver.Reconcile(context.Background(), request)
+		if err != nil {
+			t.Fatalf("expected management cluster failures not to be returned as errors, got %v", err)
+		}
+		if result.RequeueAfter != want {
+			t.Errorf("expected CSR to be requeued after %v, got %v", want, result.RequeueAfter)
+		}
+	}
+
+	// Once machines are listed again, the CSR is reconciled as usual. It
+	// fails to parse, which is counted.
+	unparseable := atomic.LoadUint32(&UnparseableCSRs)
+	result, err := approver.Reconcile(context.Background(), request)
+	if err != nil || result.RequeueAfter != 0 || atomic.LoadUint32(&UnparseableCSRs) != unparseable+1 {
+		t.Errorf("expected the CSR to be reconciled once machines are listed, got %+v, %v", result, err)
+	}
+	if got := approver.managementFailures.Load(); got != 0 {
+		t.Errorf("expected the management cluster failures to be reset, got %d", got)
+	}
+}
+
+func TestManagementRequeueInterval(t *testing.T) {
+	for _, tt := range []struct {
+		failures int32
+		want     time.Duration
+	}{
+		{failures: 1, want: 5 * time.Second},
+		{failures: 2, want: 10 * time.Second},
+		{failures: 4, want: 40 * time.Second},
+		{failures: 7, want: 5 * time.Minute},
+		{failures: 1000, want: 5 * time.Minute},
+	} {
+		if got := managementRequeueInterval(tt.failures); got != tt.want {
+			t.Errorf("managementRequeueInterval(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestReconcileCSRSelector(t *testing.T) {
+	labeled := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "labeled",
+			Labels:            map[string]string{"bootstrap": "edge"},
+			CreationTimestamp: metav1.NewTime(now()),
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Username:   "system:node:test",
+			Groups:     nodeServingGroups.List(),
+		},
+	}
+	unlabeled := labeled.DeepCopy()
+	unlabeled.Name = "unlabeled"
+	unlabeled.Labels = nil
+
+	server := newEmptyCSRListServer()
+	defer server.Close()
+
+	var lists int32
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewClientBuilder().
+			WithObjects(labeled, unlabeled).
+			WithIndex(&certificatesv1.CertificateSigningRequest{}, signerNameField, func(obj client.Object) []string {
+				return []string{obj.(*certificatesv1.CertificateSigningRequest).Spec.SignerName}
+			}).
+			Build(),
+		NodeRestCfg:      &rest.Config{Host: server.URL},
+		APIGroupVersions: []schema.GroupVersion{{Group: "machine.openshift.io"}},
+		CSRSelector:      labels.SelectorFromSet(labels.Set{"bootstrap": "edge"}),
+		newMachineLister: func(context.Context) machineLister {
+			return countingMachineLister{lists: &lists}
+		},
+	}
+	approver.approvalsAllowed.Store(true)
+
+	if !approver.csrSelected(labeled) {
+		t.Errorf("expected the labeled CSR to be selected")
+	}
+	if approver.csrSelected(unlabeled) {
+		t.Errorf("expected the unlabeled CSR not to be selected")
+	}
+
+	csrs, err := listNodeCSRs(context.Background(), approver.WorkloadClient, approver.csrSelector())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(csrs) != 1 || csrs[0].Name != "labeled" {
+		t.Errorf("expected only the labeled CSR to be listed, got %v", csrs)
+	}
+
+	// The unlabeled CSR is ignored, without listing machines
+	if _, err := approver.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKey{Name: "unlabeled"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt32(&lists); got != 0 {
+		t.Errorf("expected machines not to be listed for an unlabeled CSR, listed %d times", got)
+	}
+
+	// The labeled CSR is reconciled
+	_, _ = approver.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKey{Name: "labeled"}})
+	if got := atomic.LoadInt32(&lists); got != 1 {
+		t.Errorf("expected machines to be listed once for a labeled CSR, listed %d times", got)
+	}
+
+	// Without a selector, all CSRs are considered
+	approver.CSRSelector = nil
+	if !approver.csrSelected(unlabeled) {
+		t.Errorf("expected all CSRs to be selected without a selector")
+	}
+}
+
+// fakeCSRPages serves CSRs in pages of at most pageSize, as the API server
+// may return fewer items than the requested limit.
+type fakeCSRPages struct {
+	csrs     []certificatesv1.CertificateSigningRequest
+	pageSize int
+	requests []metav1.ListOptions
+}
+
+func (f *fakeCSRPages) List(_ context.Context, opts metav1.ListOptions) (*certificatesv1.CertificateSigningRequestList, error) {
+	f.requests = append(f.requests, opts)
+
+	start := 0
+	if opts.Continue != "" {
+		var err error
+		if start, err = strconv.Atoi(opts.Continue); err != nil {
+			return nil, err
+		}
+	}
+	end := start + f.pageSize
+	list := &certificatesv1.CertificateSigningRequestList{}
+	if end < len(f.csrs) {
+		list.Continue = strconv.Itoa(end)
+	} else {
+		end = len(f.csrs)
+	}
+	list.Items = f.csrs[start:end]
+	return list, nil
+}
+
+func TestListCSRPages(t *testing.T) {
+	lister := &fakeCSRPages{pageSize: 2}
+	var want []string
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("csr-%d", i)
+		lister.csrs = append(lister.csrs, certificatesv1.CertificateSigningRequest{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		want = append(want, name)
+	}
+
+	var got []string
+	if err := listCSRPages(context.Background(), lister, metav1.ListOptions{FieldSelector: kubeletServingFieldSelector}, func(csr certificatesv1.CertificateSigningRequest) {
+		got = append(got, csr.Name)
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected CSRs %v, got %v", want, got)
+	}
+	if len(lister.requests) != 3 {
+		t.Fatalf("expected 3 pages to be listed, got %d", len(lister.requests))
+	}
+	for i, opts := range lister.requests {
+		if opts.Limit != csrListPageSize {
+			t.Errorf("page %d: expected limit %d, got %d", i, csrListPageSize, opts.Limit)
+		}
+		if opts.FieldSelector != kubeletServingFieldSelector {
+			t.Errorf("page %d: expected field selector %q, got %q", i, kubeletServingFieldSelector, opts.FieldSelector)
+		}
+	}
+	if continues := []string{lister.requests[1].Continue, lister.requests[2].Continue}; !reflect.DeepEqual(continues, []string{"2", "4"}) {
+		t.Errorf("expected continue tokens [2 4], got %v", continues)
+	}
+}
+
+func TestRecordMetrics(t *testing.T) {
+	pending := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "pending",
+			CreationTimestamp: metav1.NewTime(now()),
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Username:   "system:node:test",
+			Groups:     nodeServingGroups.List(),
+		},
+	}
+	nodes := []client.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-0"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+	}
+
+	workloadClient := fake.NewClientBuilder().WithObjects(append(nodes, pending)...).Build()
+	approver := &CertificateApprover{
+		WorkloadClient:   workloadClient,
+		APIGroupVersions: []schema.GroupVersion{{Group: "machine.openshift.io"}},
+		newMachineLister: func(context.Context) machineLister {
+			return staticMachineLister{{ObjectMeta: metav1.ObjectMeta{Name: "machine-0"}}}
+		},
+		listCSRsUncached: func(context.Context) ([]certificatesv1.CertificateSigningRequest, error) {
+			return []certificatesv1.CertificateSigningRequest{*pending}, nil
+		},
+	}
+	approver.approvalsAllowed.Store(true)
+
+	atomic.StoreUint32(&PendingCSRs, 0)
+	atomic.StoreUint32(&MaxPendingCSRs, 0)
+	approvedBefore := atomic.LoadUint32(&ApprovedCSRs)
+
+	approver.recordMetrics(context.Background())
+
+	if got := atomic.LoadUint32(&PendingCSRs); got != 1 {
+		t.Errorf("expected 1 pending CSR, got %d", got)
+	}
+	if got, want := atomic.LoadUint32(&MaxPendingCSRs), uint32(2+maxDiffBetweenPendingCSRsAndMachinesCount); got != want {
+		t.Errorf("expected max pending CSRs %d, got %d", want, got)
+	}
+	if got := atomic.LoadUint32(&MachinesCount); got != 1 {
+		t.Errorf("expected 1 machine, got %d", got)
+	}
+	if got := atomic.LoadUint32(&NodesCount); got != 2 {
+		t.Errorf("expected 2 nodes, got %d", got)
+	}
+
+	// Nothing is approved
+	if got := atomic.LoadUint32(&ApprovedCSRs); got != approvedBefore {
+		t.Errorf("expected no CSR to be approved, approved %d", got-approvedBefore)
+	}
+	csr := &certificatesv1.CertificateSigningRequest{}
+	if err := workloadClient.Get(context.Background(), client.ObjectKey{Name: "pending"}, csr); err != nil {
+		t.Fatalf("failed to get CSR: %v", err)
+	}
+	if isApproved(*csr) {
+		t.Errorf("expected the CSR to stay pending")
+	}
+
+	// The resync stops once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := approver.resyncMetrics(ctx); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// staticMachineLister lists the same machines for every API group.
+type staticMachineLister []machinehandlerpkg.Machine
+
+func (l staticMachineLister) ListMachines(schema.GroupVersion) ([]machinehandlerpkg.Machine, error) {
+	return l, nil
+}
+
+func TestConcurrentReconciles(t *testing.T) {
+	const workers = 8
+	const pendingCount = 16
+
+	objs := []client.Object{}
+	for i := 0; i < pendingCount; i++ {
+		objs = append(objs, &certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              fmt.Sprintf("csr-%d", i),
+				CreationTimestamp: metav1.NewTime(now()),
+			},
+			Spec: certificatesv1.CertificateSigningRequestSpec{
+				SignerName: certificatesv1.KubeletServingSignerName,
+				Username:   "system:node:test",
+				Groups:     nodeServingGroups.List(),
+				Usages: []certificatesv1.KeyUsage{
+					certificatesv1.UsageDigitalSignature,
+					certificatesv1.UsageKeyEncipherment,
+					certificatesv1.UsageServerAuth,
+				},
+				Request: []byte(goodCSR),
+			},
+		})
+	}
+
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewClientBuilder().
+			WithObjects(objs...).
+			WithIndex(&certificatesv1.CertificateSigningRequest{}, signerNameField, func(obj client.Object) []string {
+				return []string{obj.(*certificatesv1.CertificateSigningRequest).Spec.SignerName}
+			}).
+			Build(),
+		APIGroupVersions: []schema.GroupVersion{{Group: "machine.openshift.io"}},
+		Config: ClusterMachineApproverConfig{
+			ServingRenewal:   ServingRenewal{Disabled: true},
+			MachineAddresses: MachineAddresses{CacheTTL: metav1.Duration{Duration: time.Minute}},
+		},
+		newMachineLister: func(context.Context) machineLister {
+			// The machine does not match the CSRs, so none of them is approved.
+			return staticMachineLister{{
+				ObjectMeta: metav1.ObjectMeta{Name: "machine", Namespace: "openshift-machine-api"},
+				Status: machinehandlerpkg.MachineStatus{
+					NodeRef:   &corev1.ObjectReference{Name: "other"},
+					Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}},
+				},
+			}}
+		},
+	}
+	approver.approvalsAllowed.Store(true)
+
+	requests := make(chan reconcile.Request, pendingCount)
+	for _, obj := range objs {
+		requests <- reconcile.Request{NamespacedName: client.ObjectKey{Name: obj.GetName()}}
+	}
+	close(requests)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for req := range requests {
+				// The CSRs are not authorized, only the shared state matters here.
+				_, _ = approver.Reconcile(context.Background(), req)
+				// Read the metrics concurrently, as the metrics collector does.
+				_ = atomic.LoadUint32(&PendingCSRs)
+				_ = atomic.LoadUint32(&MaxPendingCSRs)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if pending := atomic.LoadUint32(&PendingCSRs); pending != pendingCount {
+		t.Errorf("expected %d pending CSRs, got %d", pendingCount, pending)
+	}
+	if maxPending := atomic.LoadUint32(&MaxPendingCSRs); maxPending != 1+maxDiffBetweenPendingCSRsAndMachinesCount {
+		t.Errorf("expected %d max pending CSRs, got %d", 1+maxDiffBetweenPendingCSRsAndMachinesCount, maxPending)
+	}
+}
+
+func TestWaitForMachineAddresses(t *testing.T) {
+	servingCSR := certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Username:   nodeUserPrefix + "test",
+		},
+	}
+	addresses := []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}}
+	machine := func(addresses ...corev1.NodeAddress) machinehandlerpkg.Machine {
+		return machinehandlerpkg.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: "machine", Namespace: "openshift-machine-api"},
+			Status: machinehandlerpkg.MachineStatus{
+				NodeRef:   &corev1.ObjectReference{Name: "test"},
+				Addresses: addresses,
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		waitTimeout   time.Duration
+		csr           certificatesv1.CertificateSigningRequest
+		listed        [][]corev1.NodeAddress
+		wantAddresses []corev1.NodeAddress
+		wantLists     int
+	}{
+		{
+			name:          "addresses appear on second list",
+			waitTimeout:   10 * time.Second,
+			csr:           servingCSR,
+			listed:        [][]corev1.NodeAddress{nil, addresses},
+			wantAddresses: addresses,
+			wantLists:     1,
+		},
+		{
+			name:        "gives up once the wait times out",
+			waitTimeout: 100 * time.Millisecond,
+			csr:         servingCSR,
+			listed:      [][]corev1.NodeAddress{nil, nil},
+			wantLists:   1,
+		},
+		{
+			name:        "disabled",
+			waitTimeout: 0,
+			csr:         servingCSR,
+			listed:      [][]corev1.NodeAddress{nil, addresses},
+			wantLists:   0,
+		},
+		{
+			name:        "not a serving csr",
+			waitTimeout: 10 * time.Second,
+			csr: certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					SignerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
+					Username:   nodeBootstrapperUsername,
+				},
+			},
+			listed:    [][]corev1.NodeAddress{nil, addresses},
+			wantLists: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lists int
+			approver := &CertificateApprover{
+				APIGroupVersions:          []schema.GroupVersion{{Group: "machine.openshift.io"}},
+				MachineAddressWaitTimeout: tt.waitTimeout,
+				newMachineLister: func(context.Context) machineLister {
+					listed := tt.listed[len(tt.listed)-1]
+					if lists < len(tt.listed) {
+						listed = tt.listed[lists]
+					}
+					lists++
+					return staticMachineLister{machine(listed...)}
+				},
+			}
+
+			machines, err := approver.listMachines(context.Background(), tt.csr.Name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			initialLists := lists
+
+			machines = approver.waitForMachineAddresses(context.Background(), tt.csr.Name, []certificatesv1.CertificateSigningRequest{tt.csr}, machines)
+
+			if got := lists - initialLists; tt.wantLists == 0 && got != 0 {
+				t.Errorf("expected no machines to be listed while waiting, got %d lists", got)
+			} else if got < tt.wantLists {
+				t.Errorf("expected at least %d machine lists while waiting, got %d", tt.wantLists, got)
+			}
+			if !reflect.DeepEqual(machines[0].Status.Addresses, tt.wantAddresses) {
+				t.Errorf("expected addresses %v, got %v", tt.wantAddresses, machines[0].Status.Addresses)
+			}
+		})
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("nodeClientCert:\n  disabled: false\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	approver := &CertificateApprover{
+		Config:     LoadConfig(configPath),
+		ConfigPath: configPath,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watchErr := make(chan error, 1)
+	go func() { watchErr <- approver.watchConfig(ctx) }()
+
+	// The file is rewritten until the watcher picks it up, as the watch may not be set up yet
+	err := wait.PollUntilContextTimeout(ctx, 50*time.Millisecond, 10*time.Second, true, func(context.Context) (bool, error) {
+		if err := os.WriteFile(configPath, []byte("nodeClientCert:\n  disabled: true\n"), 0644); err != nil {
+			return false, err
+		}
+		return approver.config().NodeClientCert.Disabled, nil
+	})
+	if err != nil {
+		t.Fatalf("expected the node client cert flow to be disabled at runtime: %v", err)
+	}
+
+	cancel()
+	if err := <-watchErr; err != nil {
+		t.Errorf("unexpected watcher error: %v", err)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	current := ClusterMachineApproverConfig{NodeClientCert: NodeClientCert{Disabled: true}}
+
+	tests := []struct {
+		name    string
+		content string
+		want    ClusterMachineApproverConfig
+	}{
+		{
+			name:    "valid config",
+			content: "nodeClientCert:\n  allowRenewal: true\n",
+			want:    ClusterMachineApproverConfig{NodeClientCert: NodeClientCert{AllowRenewal: true}},
+		},
+		{
+			name:    "invalid config is not applied",
+			content: "preApprovalDelay: -1m\n",
+			want:    current,
+		},
+		{
+			name:    "unparsable config is not applied",
+			content: "nodeClientCert: [\n",
+			want:    current,
+		},
+		{
+			name:    "empty config is not applied",
+			content: "",
+			want:    current,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			approver := &CertificateApprover{Config: current, ConfigPath: configPath}
+			approver.reloadConfig(context.Background())
+			if got := approver.config(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected config %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+
+	tests := []struct {
+		name    string
+		content string
+		want    ClusterMachineApproverConfig
+	}{
+		{
+			name:    "valid config",
+			content: "nodeClientCert:\n  allowRenewal: true\n",
+			want:    ClusterMachineApproverConfig{NodeClientCert: NodeClientCert{AllowRenewal: true}},
+		},
+		{
+			name:    "invalid config is not applied",
+			content: "nodeClientCert:\n  allowRenewal: true\npreApprovalDelay: -1m\n",
+		},
+		{
+			name:    "unparsable config is not applied",
+			content: "nodeClientCert: [\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			if got := LoadConfig(configPath); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected config %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReloadConfigAPIGroupVersionsConfigMap(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	optionGroups := []schema.GroupVersion{{Group: "machine.openshift.io"}}
+	configMapGroups := []schema.GroupVersion{{Group: "cluster.x-k8s.io", Version: "v1beta1"}}
+
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: configNamespace, Name: "api-group-versions"},
+			Data:       map[string]string{"apiGroupVersions": "cluster.x-k8s.io/v1beta1"},
+		}).Build(),
+		ConfigPath:       configPath,
+		APIGroupVersions: optionGroups,
+		ParseAPIGroupVersion: func(gv string) (schema.GroupVersion, error) {
+			group, version, _ := strings.Cut(gv, "/")
+			return schema.GroupVersion{Group: group, Version: version}, nil
+		},
+	}
+
+	for _, tt := range []struct {
+		name    string
+		content string
+		want    []schema.GroupVersion
+	}{
+		{
+			name:    "ConfigMap added",
+			content: "apiGroupVersionsConfigMap:\n  name: api-group-versions\n",
+			want:    configMapGroups,
+		},
+		{
+			name:    "ConfigMap removed",
+			content: "nodeClientCert:\n  allowRenewal: true\n",
+			want:    optionGroups,
+		},
+		{
+			name:    "ConfigMap added again",
+			content: "apiGroupVersionsConfigMap:\n  name: api-group-versions\n",
+			want:    configMapGroups,
+		},
+		{
+			name:    "missing ConfigMap referenced",
+			content: "apiGroupVersionsConfigMap:\n  name: missing\n",
+			want:    optionGroups,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			approver.reloadConfig(context.Background())
+			if got := approver.apiGroupVersions(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected API group versions %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ClusterMachineApproverConfig
+		wantErr string
+	}{
+		{
+			name: "default config",
+		},
+		{
+			name: "negative durations",
+			config: ClusterMachineApproverConfig{
+				NodeClientCert:   NodeClientCert{MaxMachineDelta: metav1.Duration{Duration: -time.Hour}},
+				PreApprovalDelay: metav1.Duration{Duration: -time.Minute},
+			},
+			wantErr: "[nodeClientCert.maxMachineDelta must not be negative, got -1h0m0s, preApprovalDelay must not be negative, got -1m0s]",
+		},
+		{
+			name:    "negative max SAN count",
+			config:  ClusterMachineApproverConfig{MaxSANCount: -1},
+			wantErr: "maxSANCount must not be negative, got -1",
+		},
+		{
+			name:    "negative max pending CSRs",
+			config:  ClusterMachineApproverConfig{MaxPendingCSRs: -1},
+			wantErr: "maxPendingCSRs must not be negative, got -1",
+		},
+		{
+			name:    "machine address CIDR host bits out of range",
+			config:  ClusterMachineApproverConfig{NodeServingCert: NodeServingCert{MaxMachineAddressCIDRHostBits: 129}},
+			wantErr: "nodeServingCert.maxMachineAddressCIDRHostBits must be between 0 and 128, got 129",
+		},
+		{
+			name:    "invalid server name template",
+			config:  ClusterMachineApproverConfig{ServingRenewal: ServingRenewal{ServerNameTemplate: "{{.Hostname}}.example.com"}},
+			wantErr: `servingRenewal.serverNameTemplate "{{.Hostname}}.example.com" is invalid: template: serverName:1:2: executing "serverName" at <.Hostname>: can't evaluate field Hostname in type controller.kubeletServerName`,
+		},
+		{
+			name:    "invalid node name label",
+			config:  ClusterMachineApproverConfig{MachineAPIAuthorization: MachineAPIAuthorization{NodeNameLabel: "node name"}},
+			wantErr: `machineAPIAuthorization.nodeNameLabel "node name" is not a valid label key: name part must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyName',  or 'my.name',  or '123-abc', regex used for validation is '([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]')`,
+		},
+		{
+			name:    "negative pending limit requeue interval",
+			config:  ClusterMachineApproverConfig{PendingLimitRequeueInterval: metav1.Duration{Duration: -time.Minute}},
+			wantErr: "pendingLimitRequeueInterval must not be negative, got -1m0s",
+		},
+		{
+			name:    "negative max approvals per minute",
+			config:  ClusterMachineApproverConfig{MaxApprovalsPerMinute: -1},
+			wantErr: "maxApprovalsPerMinute must not be negative, got -1",
+		},
+		{
+			name:    "unknown curve",
+			config:  ClusterMachineApproverConfig{KeyPolicy: KeyPolicy{AllowedCurves: []string{"P-256", "secp256k1"}}},
+			wantErr: `keyPolicy.allowedCurves: unknown curve "secp256k1", must be one of [P-224 P-256 P-384 P-521]`,
+		},
+		{
+			name:    "invalid static node name",
+			config:  ClusterMachineApproverConfig{StaticNodes: []string{"Master-0"}},
+			wantErr: `staticNodes: Invalid node name "Master-0": a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`,
+		},
+		{
+			name:   "node name domain suffix",
+			config: ClusterMachineApproverConfig{NodeClientCert: NodeClientCert{NodeNameDomainSuffix: ".Example.com."}},
+		},
+		{
+			name:    "empty machine phase",
+			config:  ClusterMachineApproverConfig{NodeClientCert: NodeClientCert{RequireMachinePhases: []string{"Running", ""}}},
+			wantErr: "nodeClientCert.requireMachinePhases must not contain empty phases",
+		},
+		{
+			name:    "invalid node name domain suffix",
+			config:  ClusterMachineApproverConfig{NodeClientCert: NodeClientCert{NodeNameDomainSuffix: "example_com"}},
+			wantErr: `nodeClientCert.nodeNameDomainSuffix "example_com" is not a valid domain: a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`,
+		},
+		{
+			name:   "provider addresses path",
+			config: ClusterMachineApproverConfig{MachineAddresses: MachineAddresses{ProviderAddressesPath: "status.providerStatus.addresses"}},
+		},
+		{
+			name:    "provider addresses path with empty field",
+			config:  ClusterMachineApproverConfig{MachineAddresses: MachineAddresses{ProviderAddressesPath: "status..addresses"}},
+			wantErr: `machineAddresses.providerAddressesPath "status..addresses" must not contain empty fields`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); errString(err) != tt.wantErr {
+				t.Errorf("expected error %q, got %q", tt.wantErr, errString(err))
+			}
+		})
+	}
+}
+
+func TestConfigHandler(t *testing.T) {
+	approver := &CertificateApprover{
+		ConfigPath: "/var/run/configmaps/config/config.yaml",
+		Config: ClusterMachineApproverConfig{
+			NodeClientCert: NodeClientCert{Disabled: true},
+		},
+		APIGroupVersions: []schema.GroupVersion{
+			{Group: "machine.openshift.io"},
+			{Group: "cluster.x-k8s.io", Version: "v1beta1"},
+		},
+		MachineListTimeout: 30 * time.Second,
+	}
+
+	rec := httptest.NewRecorder()
+	approver.ConfigHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, DebugConfigPath, nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("expected JSON content type, got %q", contentType)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode %q: %v", rec.Body.String(), err)
+	}
+
+	wantAPIGroupVersions := []interface{}{"machine.openshift.io", "cluster.x-k8s.io/v1beta1"}
+	if !reflect.DeepEqual(got["apiGroupVersions"], wantAPIGroupVersions) {
+		t.Errorf("expected API group versions %v, got %v", wantAPIGroupVersions, got["apiGroupVersions"])
+	}
+	if got["configPath"] != approver.ConfigPath {
+		t.Errorf("expected config path %q, got %v", approver.ConfigPath, got["configPath"])
+	}
+	var gotConfig struct {
+		Config ClusterMachineApproverConfig `json:"config"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &gotConfig); err != nil {
+		t.Fatalf("failed to decode config: %v", err)
+	}
+	if !gotConfig.Config.NodeClientCert.Disabled {
+		t.Errorf("expected node client certs to be disabled in %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	approver.ConfigHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, DebugConfigPath, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d for POST, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
+
+func TestReconcileLimitsMachineAndNodeCounts(t *testing.T) {
+	machines := make([]machinehandlerpkg.Machine, 3)
+	nodes := &corev1.NodeList{Items: make([]corev1.Node, 2)}
+
+	reconcileLimits(ClusterMachineApproverConfig{}, "csr", machines, nodes, nil)
+	if got := atomic.LoadUint32(&MachinesCount); got != 3 {
+		t.Errorf("expected 3 machines, got %d", got)
+	}
+	if got := atomic.LoadUint32(&NodesCount); got != 2 {
+		t.Errorf("expected 2 nodes, got %d", got)
+	}
+
+	// The gauges follow the counts seen in the latest reconcile
+	reconcileLimits(ClusterMachineApproverConfig{}, "csr", machines[:1], &corev1.NodeList{Items: make([]corev1.Node, 4)}, nil)
+	if got := atomic.LoadUint32(&MachinesCount); got != 1 {
+		t.Errorf("expected 1 machine, got %d", got)
+	}
+	if got := atomic.LoadUint32(&NodesCount); got != 4 {
+		t.Errorf("expected 4 nodes, got %d", got)
+	}
+}
+
+func TestReconcileLimitsMaxPendingCSRsOverride(t *testing.T) {
+	machines := make([]machinehandlerpkg.Machine, 3)
+	nodes := &corev1.NodeList{Items: make([]corev1.Node, 2)}
+	computed := uint32(3 + maxDiffBetweenPendingCSRsAndMachinesCount)
+
+	reconcileLimits(ClusterMachineApproverConfig{MaxPendingCSRs: 5}, "csr", machines, nodes, nil)
+	if got := atomic.LoadUint32(&MaxPendingCSRs); got != 5 {
+		t.Errorf("expected the overridden limit 5, got %d", got)
+	}
+
+	reconcileLimits(ClusterMachineApproverConfig{}, "csr", machines, nodes, nil)
+	if got := atomic.LoadUint32(&MaxPendingCSRs); got != computed {
+		t.Errorf("expected the computed limit %d, got %d", computed, got)
+	}
+
+	// The flag takes precedence over the config file
+	approver := &CertificateApprover{
+		Config:         ClusterMachineApproverConfig{MaxPendingCSRs: 5},
+		MaxPendingCSRs: 7,
+	}
+	reconcileLimits(approver.config(), "csr", machines, nodes, nil)
+	if got := atomic.LoadUint32(&MaxPendingCSRs); got != 7 {
+		t.Errorf("expected the flag limit 7, got %d", got)
+	}
+}
+
+func TestReconcileLimitsSuppressedCSRs(t *testing.T) {
+	pendingCSRs := func(count int) []certificatesv1.CertificateSigningRequest {
+		csrs := make([]certificatesv1.CertificateSigningRequest, count)
+		for i := range csrs {
+			csrs[i] = certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              fmt.Sprintf("csr-%d", i),
+					CreationTimestamp: metav1.Time{Time: now()},
+				},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					SignerName: certificatesv1.KubeletServingSignerName,
+					Groups:     []string{"system:authenticated", "system:nodes"},
+				},
+			}
+		}
+		return csrs
+	}
+	nodes := &corev1.NodeList{}
+
+	before := atomic.LoadUint32(&SuppressedCSRs)
+	if reconcileLimits(ClusterMachineApproverConfig{}, "csr", nil, nodes, pendingCSRs(maxDiffBetweenPendingCSRsAndMachinesCount)) {
+		t.Fatal("expected the limit not to trip")
+	}
+	if got := atomic.LoadUint32(&SuppressedCSRs); got != before {
+		t.Errorf("expected %d suppressed CSRs, got %d", before, got)
+	}
+
+	if !reconcileLimits(ClusterMachineApproverConfig{}, "csr", nil, nodes, pendingCSRs(maxDiffBetweenPendingCSRsAndMachinesCount+1)) {
+		t.Fatal("expected the limit to trip")
+	}
+	if got := atomic.LoadUint32(&SuppressedCSRs); got != before+1 {
+		t.Errorf("expected %d suppressed CSRs, got %d", before+1, got)
+	}
+}
+
+func TestReconcilePendingLimitRequeue(t *testing.T) {
+	var objects []client.Object
+	for _, name := range []string{"csr-0", "csr-1"} {
+		objects = append(objects, &certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(now()),
+			},
+			Spec: certificatesv1.CertificateSigningRequestSpec{
+				SignerName: certificatesv1.KubeletServingSignerName,
+				Username:   "system:node:test",
+				Groups:     nodeServingGroups.List(),
+			},
+		})
+	}
+
+	var lists int32
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewClientBuilder().
+			WithObjects(objects...).
+			WithIndex(&certificatesv1.CertificateSigningRequest{}, signerNameField, func(obj client.Object) []string {
+				return []string{obj.(*certificatesv1.CertificateSigningRequest).Spec.SignerName}
+			}).
+			Build(),
+		Config:           ClusterMachineApproverConfig{MaxPendingCSRs: 1, ServingRenewal: ServingRenewal{Disabled: true}},
+		APIGroupVersions: []schema.GroupVersion{{Group: "machine.openshift.io"}},
+		newMachineLister: func(context.Context) machineLister {
+			return countingMachineLister{lists: &lists}
+		},
+	}
+	approver.approvalsAllowed.Store(true)
+
+	for _, tt := range []struct {
+		name     string
+		interval time.Duration
+		want     time.Duration
+	}{
+		{
+			name: "default interval",
+			want: defaultPendingLimitRequeueInterval,
+		},
+		{
+			name:     "configured interval",
+			interval: 10 * time.Second,
+			want:     10 * time.Second,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			approver.Config.PendingLimitRequeueInterval = metav1.Duration{Duration: tt.interval}
+
+			result, err := approver.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKey{Name: "csr-0"}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.RequeueAfter != tt.want {
+				t.Errorf("expected the suppressed CSR to be requeued after %v, got %v", tt.want, result.RequeueAfter)
+			}
+		})
+	}
+}
+
+// recordingMachineLister records the API group versions machines are listed from.
+type recordingMachineLister struct {
+	mu     *sync.Mutex
+	groups *[]schema.GroupVersion
+}
+
+func (l recordingMachineLister) ListMachines(apiGroupVersion schema.GroupVersion) ([]machinehandlerpkg.Machine, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	*l.groups = append(*l.groups, apiGroupVersion)
+	return nil, nil
+}
+
+func TestReconcileAPIGroupVersionsConfigMap(t *testing.T) {
+	csr := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "csr",
+			CreationTimestamp: metav1.NewTime(now()),
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Username:   "system:node:test",
+			Groups:     nodeServingGroups.List(),
+		},
+	}
+
+	// Serves the uncached list of CSRs when reconciling the limits
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"CertificateSigningRequestList","apiVersion":"certificates.k8s.io/v1","items":[]}`))
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var groups []schema.GroupVersion
+	approver := &CertificateApprover{
+		NodeRestCfg: &rest.Config{Host: server.URL},
+		WorkloadClient: fake.NewClientBuilder().
+			WithObjects(csr).
+			WithIndex(&certificatesv1.CertificateSigningRequest{}, signerNameField, func(obj client.Object) []string {
+				return []string{obj.(*certificatesv1.CertificateSigningRequest).Spec.SignerName}
+			}).
+			Build(),
+		Config: ClusterMachineApproverConfig{
+			APIGroupVersionsConfigMap: ConfigMapKeyReference{Name: "api-group-versions"},
+		},
+		APIGroupVersions: []schema.GroupVersion{{Group: "machine.openshift.io"}},
+		ParseAPIGroupVersion: func(gv string) (schema.GroupVersion, error) {
+			group, version, _ := strings.Cut(gv, "/")
+			if group != "machine.openshift.io" && group != "cluster.x-k8s.io" {
+				return schema.GroupVersion{}, fmt.Errorf("unsupported APIGroup %q", group)
+			}
+			return schema.GroupVersion{Group: group, Version: version}, nil
+		},
+		newMachineLister: func(context.Context) machineLister {
+			return recordingMachineLister{mu: &mu, groups: &groups}
+		},
+	}
+	approver.approvalsAllowed.Store(true)
+
+	for _, tt := range []struct {
+		name    string
+		data    map[string]string
+		deleted bool
+		want    []schema.GroupVersion
+	}{
+		{
+			name: "groups from the option before the ConfigMap is observed",
+			want: []schema.GroupVersion{{Group: "machine.openshift.io"}},
+		},
+		{
+			name: "groups added",
+			data: map[string]string{"apiGroupVersions": "machine.openshift.io, cluster.x-k8s.io/v1beta1"},
+			want: []schema.GroupVersion{{Group: "machine.openshift.io"}, {Group: "cluster.x-k8s.io", Version: "v1beta1"}},
+		},
+		{
+			name: "group removed",
+			data: map[string]string{"apiGroupVersions": "cluster.x-k8s.io/v1beta1"},
+			want: []schema.GroupVersion{{Group: "cluster.x-k8s.io", Version: "v1beta1"}},
+		},
+		{
+			name: "invalid group ignored",
+			data: map[string]string{"apiGroupVersions": "machine.openshift.io\nexample.com"},
+			want: []schema.GroupVersion{{Group: "cluster.x-k8s.io", Version: "v1beta1"}},
+		},
+		{
+			name: "empty list ignored",
+			data: map[string]string{"apiGroupVersions": " "},
+			want: []schema.GroupVersion{{Group: "cluster.x-k8s.io", Version: "v1beta1"}},
+		},
+		{
+			name:    "groups from the option once the ConfigMap is deleted",
+			deleted: true,
+			want:    []schema.GroupVersion{{Group: "machine.openshift.io"}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.deleted {
+				requests := approver.apiGroupVersionsConfigMapDeleted(context.Background(), &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Namespace: configNamespace, Name: "api-group-versions"},
+				})
+				if len(requests) != 1 {
+					t.Errorf("expected the pending CSR to be re-evaluated, got requests %v", requests)
+				}
+			}
+			if tt.data != nil {
+				requests := approver.toCSRs(context.Background(), &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Namespace: configNamespace, Name: "api-group-versions"},
+					Data:       tt.data,
+				})
+				if len(requests) != 1 {
+					t.Errorf("expected the pending CSR to be re-evaluated, got requests %v", requests)
+				}
+			}
+
+			mu.Lock()
+			groups = nil
+			mu.Unlock()
+
+			if _, err := approver.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKey{Name: "csr"}}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(groups, tt.want) {
+				t.Errorf("expected machines to be listed from %v, got %v", tt.want, groups)
+			}
+		})
+	}
+}
+
+func TestReconcileUncachedLimitsRefresh(t *testing.T) {
+	csr := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "csr",
+			CreationTimestamp: metav1.NewTime(now()),
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Username:   "system:node:test",
+			Groups:     nodeServingGroups.List(),
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Echo the approved CSR back
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		name           string
+		disabled       bool
+		servingRenewal bool
+		wantUncached   int32
+	}{
+		{
+			name:         "uncached refresh by default",
+			wantUncached: 1,
+		},
+		{
+			name:     "uncached refresh disabled",
+			disabled: true,
+		},
+		{
+			name:           "uncached refresh by default for serving renewals",
+			servingRenewal: true,
+			wantUncached:   1,
+		},
+		{
+			name:           "uncached refresh disabled for serving renewals",
+			disabled:       true,
+			servingRenewal: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var lists, uncachedLists int32
+			approver := &CertificateApprover{
+				NodeRestCfg: &rest.Config{Host: server.URL},
+				WorkloadClient: fake.NewClientBuilder().
+					WithObjects(csr).
+					WithIndex(&certificatesv1.CertificateSigningRequest{}, signerNameField, func(obj client.Object) []string {
+						return []string{obj.(*certificatesv1.CertificateSigningRequest).Spec.SignerName}
+					}).
+					Build(),
+				Config:           ClusterMachineApproverConfig{DisableUncachedLimitsRefresh: tt.disabled},
+				APIGroupVersions: []schema.GroupVersion{{Group: "machine.openshift.io"}},
+				newMachineLister: func(context.Context) machineLister {
+					return countingMachineLister{lists: &lists}
+				},
+				listCSRsUncached: func(context.Context) ([]certificatesv1.CertificateSigningRequest, error) {
+					atomic.AddInt32(&uncachedLists, 1)
+					return []certificatesv1.CertificateSigningRequest{*csr}, nil
+				},
+			}
+			approver.approvalsAllowed.Store(true)
+
+			var err error
+			if tt.servingRenewal {
+				_, err = approver.reconcileServingRenewal(context.Background(), approver.config(), *csr)
+			} else {
+				_, err = approver.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKey{Name: "csr"}})
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := atomic.LoadInt32(&uncachedLists); got != tt.wantUncached {
+				t.Errorf("expected CSRs to be listed uncached %d times, got %d", tt.wantUncached, got)
+			}
+			if got := atomic.LoadUint32(&PendingCSRs); got != 1 {
+				t.Errorf("expected the limits to be refreshed with 1 pending CSR, got %d", got)
+			}
+		})
+	}
+}
+
+func TestReconcileCSRSkipAnnotation(t *testing.T) {
+	var approvals int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/approval") {
+			atomic.AddInt32(&approvals, 1)
+		}
+		// Echo the updated CSR back
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	machines := []machinehandlerpkg.Machine{{
+		Status: machinehandlerpkg.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "test"},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+				{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+				{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+				{Type: corev1.NodeExternalDNS, Address: "node1"},
+			},
+		},
+	}}
+	csr := certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageKeyEncipherment,
+				certificatesv1.UsageServerAuth,
+			},
+			Username: "system:node:test",
+			Groups: []string{
+				"system:authenticated",
+				"system:nodes",
+			},
+			Request: []byte(goodCSR),
+		},
+	}
+
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewFakeClient(),
+		NodeRestCfg:    &rest.Config{Host: server.URL},
+		Config:         ClusterMachineApproverConfig{ServingRenewal: ServingRenewal{Disabled: true}},
+	}
+
+	skipped := csr.DeepCopy()
+	skipped.Annotations = map[string]string{skipAnnotation: "true"}
+	if _, err := approver.reconcileCSR(context.Background(), *skipped, machines); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt32(&approvals); got != 0 {
+		t.Fatalf("expected annotated CSR not to be approved, got %d approvals", got)
+	}
+	if isApproved(*skipped) {
+		t.Error("expected annotated CSR to be left pending")
+	}
+
+	// The same CSR is approved without the annotation
+	if _, err := approver.reconcileCSR(context.Background(), csr, machines); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt32(&approvals); got != 1 {
+		t.Errorf("expected CSR without annotation to be approved, got %d approvals", got)
+	}
+}
+
+func TestReconcileCSRAuditLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Echo the updated CSR back
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	machines := []machinehandlerpkg.Machine{{
+		Status: machinehandlerpkg.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "test"},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+				{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+				{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+				{Type: corev1.NodeExternalDNS, Address: "node1"},
+			},
+		},
+	}}
+	csr := certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageKeyEncipherment,
+				certificatesv1.UsageServerAuth,
+			},
+			Username: "system:node:test",
+			Groups: []string{
+				"system:authenticated",
+				"system:nodes",
+			},
+			Request: []byte(goodCSR),
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := NewAuditLog(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer auditLog.Close()
+
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewFakeClient(),
+		NodeRestCfg:    &rest.Config{Host: server.URL},
+		Config:         ClusterMachineApproverConfig{ServingRenewal: ServingRenewal{Disabled: true}},
+		AuditLog:       auditLog,
+	}
+
+	if _, err := approver.reconcileCSR(context.Background(), *csr.DeepCopy(), machines); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The CSR is not authorized without machines
+	_, _ = approver.reconcileCSR(context.Background(), *csr.DeepCopy(), nil)
+
+	readRecords := func(path string) []auditRecord {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read audit log: %v", err)
+		}
+		var records []auditRecord
+		for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			var record auditRecord
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("failed to parse audit line %q: %v", line, err)
+			}
+			records = append(records, record)
+		}
+		return records
+	}
+
+	records := readRecords(path)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 audit records, got %d: %+v", len(records), records)
+	}
+	approved, notAuthorized := records[0], records[1]
+	if approved.CSR != "csr" || approved.Node != "test" || approved.SignerName != certificatesv1.KubeletServingSignerName ||
+		approved.Decision != reconcileOutcomeApproved || approved.Method != authorizedByMachine ||
+		approved.CorrelationID == "" || approved.Timestamp.IsZero() {
+		t.Errorf("unexpected approval audit record: %+v", approved)
+	}
+	if notAuthorized.Decision != reconcileOutcomeNotAuthorized || notAuthorized.Method != "" || notAuthorized.Reason == "" {
+		t.Errorf("unexpected not authorized audit record: %+v", notAuthorized)
+	}
+
+	// Records are written to a new file once the audit log was rotated
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := approver.reconcileCSR(context.Background(), *csr.DeepCopy(), machines); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if records := readRecords(path); len(records) != 1 || records[0].Decision != reconcileOutcomeApproved {
+		t.Errorf("expected a single approval audit record after rotation, got %+v", records)
+	}
+	if records := readRecords(path + ".1"); len(records) != 2 {
+		t.Errorf("expected the rotated audit log to keep 2 records, got %d", len(records))
+	}
+}
+
+func TestPendingNodeCertFilterBootstrapper(t *testing.T) {
+	clientCSR := func(username string) *certificatesv1.CertificateSigningRequest {
+		return &certificatesv1.CertificateSigningRequest{
+			Spec: certificatesv1.CertificateSigningRequestSpec{
+				Username:   username,
+				SignerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
+			},
+		}
+	}
+	customBootstrapper := "system:serviceaccount:custom-mco:node-bootstrapper"
+	customConfig := ClusterMachineApproverConfig{NodeClientCert: NodeClientCert{BootstrapperUsername: customBootstrapper}}
+
+	if !pendingNodeCertFilter(ClusterMachineApproverConfig{}, clientCSR(nodeBootstrapperUsername)) {
+		t.Error("expected CSR from the default bootstrapper to be reconciled")
+	}
+	if pendingNodeCertFilter(ClusterMachineApproverConfig{}, clientCSR(customBootstrapper)) {
+		t.Error("expected CSR from a custom bootstrapper to be ignored by default")
+	}
+	if !pendingNodeCertFilter(customConfig, clientCSR(customBootstrapper)) {
+		t.Error("expected CSR from the configured bootstrapper to be reconciled")
+	}
+	if pendingNodeCertFilter(customConfig, clientCSR(nodeBootstrapperUsername)) {
+		t.Error("expected CSR from the default bootstrapper to be ignored when another is configured")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	servingCSR := func(name string, conditions ...certificatesv1.CertificateSigningRequestCondition) *certificatesv1.CertificateSigningRequest {
+		return &certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: certificatesv1.CertificateSigningRequestSpec{
+				SignerName: certificatesv1.KubeletServingSignerName,
+				Usages: []certificatesv1.KeyUsage{
+					certificatesv1.UsageDigitalSignature,
+					certificatesv1.UsageKeyEncipherment,
+					certificatesv1.UsageServerAuth,
+				},
+				Username: "system:node:test",
+				Groups: []string{
+					"system:authenticated",
+					"system:nodes",
+				},
+				Request: []byte(goodCSR),
+			},
+			Status: certificatesv1.CertificateSigningRequestStatus{Conditions: conditions},
+		}
+	}
+	machine := machinehandlerpkg.Machine{
+		Status: machinehandlerpkg.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "test"},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+				{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+				{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+				{Type: corev1.NodeExternalDNS, Address: "node1"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		csrName  string
+		machines []machinehandlerpkg.Machine
+		want     CheckResult
+		wantErr  bool
+	}{
+		{
+			name:     "authorized",
+			csrName:  "pending",
+			machines: []machinehandlerpkg.Machine{machine},
+			want:     CheckResult{Authorized: true, Method: string(authorizedByMachine)},
+		},
+		{
+			name:    "not authorized without machines",
+			csrName: "pending",
+			want:    CheckResult{Reason: "could not authorize CSR: exhausted all authorization methods: Unable to find machine for node"},
+		},
+		{
+			name:     "already approved",
+			csrName:  "approved",
+			machines: []machinehandlerpkg.Machine{machine},
+			want:     CheckResult{Reason: "CSR is already approved"},
+		},
+		{
+			name:    "missing CSR",
+			csrName: "missing",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			certPool := x509.NewCertPool()
-			for _, cert := range tt.ca {
-				certPool.AddCert(cert)
-			}
-			err := authorizeServingRenewal(
-				tt.nodeName,
-				tt.csr,
-				tt.currentCert,
-				x509.VerifyOptions{Roots: certPool, CurrentTime: tt.time},
+			pending := servingCSR("pending")
+			cl := fake.NewFakeClient(
+				pending,
+				servingCSR("approved", certificatesv1.CertificateSigningRequestCondition{Type: certificatesv1.CertificateApproved}),
+				&configv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}},
 			)
+			approver := &CertificateApprover{
+				WorkloadClient:   cl,
+				Config:           ClusterMachineApproverConfig{ServingRenewal: ServingRenewal{Disabled: true}},
+				APIGroupVersions: []schema.GroupVersion{{Group: "machine.openshift.io"}},
+				newMachineLister: func(context.Context) machineLister {
+					return staticMachineLister(tt.machines)
+				},
+			}
+
+			got, err := approver.Check(context.Background(), tt.csrName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+
+			// Checking must not mutate the CSR
+			after := &certificatesv1.CertificateSigningRequest{}
+			if err := cl.Get(context.Background(), client.ObjectKey{Name: "pending"}, after); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if after.ResourceVersion != pending.ResourceVersion || isApproved(*after) || len(after.Annotations) != 0 {
+				t.Errorf("expected the CSR not to be mutated, got %+v", after)
+			}
+		})
+	}
+}
+
+func TestApproveRetryOnConflict(t *testing.T) {
+	latest := &certificatesv1.CertificateSigningRequest{
+		TypeMeta: metav1.TypeMeta{Kind: "CertificateSigningRequest", APIVersion: "certificates.k8s.io/v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "csr",
+			ResourceVersion: "2",
+			// Modified concurrently by another controller
+			Labels: map[string]string{"modified": "true"},
+		},
+	}
+
+	var mu sync.Mutex
+	var puts, gets, approvals int
+	var approved certificatesv1.CertificateSigningRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/certificatesigningrequests/csr"):
+			gets++
+			_ = json.NewEncoder(w).Encode(latest)
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/certificatesigningrequests/csr/approval"):
+			puts++
+			if puts == 1 {
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(&metav1.Status{
+					TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+					Status:   metav1.StatusFailure,
+					Reason:   metav1.StatusReasonConflict,
+					Code:     http.StatusConflict,
+				})
+				return
+			}
+			approvals++
+			body, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &approved); err != nil {
+				t.Errorf("failed to decode approval: %v", err)
+			}
+			_, _ = w.Write(body)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	csr := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr", ResourceVersion: "1"},
+	}
+	if err := approve(context.Background(), &rest.Config{Host: server.URL}, csr, map[string]string{authorizedByAnnotation: string(authorizedByMachine)}, ApprovalCondition{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if puts != 2 || gets != 1 || approvals != 1 {
+		t.Errorf("expected one conflict, one refetch and a single approval, got %d updates, %d gets, %d approvals", puts, gets, approvals)
+	}
+	if approved.ResourceVersion != "2" || approved.Labels["modified"] != "true" {
+		t.Errorf("expected the approval to apply to the latest CSR, got %+v", approved.ObjectMeta)
+	}
+	if !isApproved(approved) || approved.Annotations[authorizedByAnnotation] != string(authorizedByMachine) {
+		t.Errorf("expected the latest CSR to be approved and annotated, got %+v", approved)
+	}
+}
+
+func TestUpdateApprovalConflictAlreadyDecided(t *testing.T) {
+	decided := func(conditionType certificatesv1.RequestConditionType) []certificatesv1.CertificateSigningRequestCondition {
+		return []certificatesv1.CertificateSigningRequestCondition{{
+			Type:   conditionType,
+			Status: corev1.ConditionTrue,
+			Reason: "DecidedElsewhere",
+		}}
+	}
+	approveCSR := func(cfg *rest.Config, csr *certificatesv1.CertificateSigningRequest) error {
+		return approve(context.Background(), cfg, csr, nil, ApprovalCondition{})
+	}
+	denyCSR := func(cfg *rest.Config, csr *certificatesv1.CertificateSigningRequest) error {
+		return deny(context.Background(), cfg, csr, nil, csrConditionDisabledFlowDenyReason, "denied")
+	}
+
+	// newServer returns a server on which updating the approval of the CSR
+	// conflicts with the latest version of the CSR, decided with conditions.
+	newServer := func(t *testing.T, conditions []certificatesv1.CertificateSigningRequestCondition) (*httptest.Server, func() (int, int)) {
+		latest := &certificatesv1.CertificateSigningRequest{
+			TypeMeta:   metav1.TypeMeta{Kind: "CertificateSigningRequest", APIVersion: "certificates.k8s.io/v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "csr", ResourceVersion: "2"},
+			Spec:       certificatesv1.CertificateSigningRequestSpec{SignerName: certificatesv1.KubeletServingSignerName},
+			Status:     certificatesv1.CertificateSigningRequestStatus{Conditions: conditions},
+		}
+
+		var mu sync.Mutex
+		var puts, gets int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/certificatesigningrequests/csr"):
+				gets++
+				_ = json.NewEncoder(w).Encode(latest)
+			case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/certificatesigningrequests/csr/approval"):
+				puts++
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(&metav1.Status{
+					TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+					Status:   metav1.StatusFailure,
+					Reason:   metav1.StatusReasonConflict,
+					Code:     http.StatusConflict,
+				})
+			default:
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		return server, func() (int, int) {
+			mu.Lock()
+			defer mu.Unlock()
+			return puts, gets
+		}
+	}
+
+	for _, tt := range []struct {
+		name       string
+		update     func(*rest.Config, *certificatesv1.CertificateSigningRequest) error
+		conditions []certificatesv1.CertificateSigningRequestCondition
+	}{
+		{
+			name:       "approve after concurrent approval",
+			update:     approveCSR,
+			conditions: decided(certificatesv1.CertificateApproved),
+		},
+		{
+			name:       "approve after concurrent denial",
+			update:     approveCSR,
+			conditions: decided(certificatesv1.CertificateDenied),
+		},
+		{
+			name:       "deny after concurrent approval",
+			update:     denyCSR,
+			conditions: decided(certificatesv1.CertificateApproved),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newServer(t, tt.conditions)
+			defer server.Close()
+
+			csr := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "csr", ResourceVersion: "1"},
+			}
+			if err := tt.update(&rest.Config{Host: server.URL}, csr); !errors.Is(err, errDecidedConcurrently) {
+				t.Fatalf("expected %v, got %v", errDecidedConcurrently, err)
+			}
+
+			if puts, gets := requests(); puts != 1 || gets != 1 {
+				t.Errorf("expected one conflict and one refetch without further updates, got %d updates, %d gets", puts, gets)
+			}
+			if !reflect.DeepEqual(csr.Status.Conditions, tt.conditions) {
+				t.Errorf("expected the concurrent decision to be kept, got %+v", csr.Status.Conditions)
+			}
+		})
+	}
+
+	machines := []machinehandlerpkg.Machine{{
+		Status: machinehandlerpkg.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "test"},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+				{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+				{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+				{Type: corev1.NodeExternalDNS, Address: "node1"},
+			},
+		},
+	}}
+	for _, conditionType := range []certificatesv1.RequestConditionType{certificatesv1.CertificateApproved, certificatesv1.CertificateDenied} {
+		t.Run(fmt.Sprintf("reconcile after concurrent %s", conditionType), func(t *testing.T) {
+			server, _ := newServer(t, decided(conditionType))
+			defer server.Close()
+
+			path := filepath.Join(t.TempDir(), "audit.log")
+			auditLog, err := NewAuditLog(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer auditLog.Close()
+
+			approver := &CertificateApprover{
+				WorkloadClient: fake.NewFakeClient(),
+				NodeRestCfg:    &rest.Config{Host: server.URL},
+				Config:         ClusterMachineApproverConfig{ServingRenewal: ServingRenewal{Disabled: true}},
+				AuditLog:       auditLog,
+			}
+			csr := certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "csr", ResourceVersion: "1"},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					SignerName: certificatesv1.KubeletServingSignerName,
+					Usages: []certificatesv1.KeyUsage{
+						certificatesv1.UsageDigitalSignature,
+						certificatesv1.UsageKeyEncipherment,
+						certificatesv1.UsageServerAuth,
+					},
+					Username: "system:node:test",
+					Groups: []string{
+						"system:authenticated",
+						"system:nodes",
+					},
+					Request: []byte(goodCSR),
+				},
+			}
+
+			approvedBefore := csrDecisions(t, csr.Spec.SignerName, reconcileOutcomeApproved, "")
+			approvedCSRsBefore := atomic.LoadUint32(&ApprovedCSRs)
+			if _, err := approver.reconcileCSR(context.Background(), csr, machines); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := csrDecisions(t, csr.Spec.SignerName, reconcileOutcomeApproved, ""); got != approvedBefore {
+				t.Errorf("expected no approved decision to be counted, got %v", got-approvedBefore)
+			}
+			if got := atomic.LoadUint32(&ApprovedCSRs); got != approvedCSRsBefore {
+				t.Errorf("expected no approved CSR to be counted, got %d", got-approvedCSRsBefore)
+			}
+			if data, err := os.ReadFile(path); err != nil || len(data) != 0 {
+				t.Errorf("expected no audit record, got %q (%v)", data, err)
+			}
+		})
+	}
+}
+
+func TestApproveCustomCondition(t *testing.T) {
+	var approved certificatesv1.CertificateSigningRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &approved); err != nil {
+			t.Errorf("failed to decode approval: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	config := ClusterMachineApproverConfig{
+		ApprovalCondition: ApprovalCondition{
+			Reason:  "StagingApprove",
+			Message: "Approved by the staging machine approver",
+		},
+	}
+	csr := &certificatesv1.CertificateSigningRequest{ObjectMeta: metav1.ObjectMeta{Name: "csr"}}
+	if err := approve(context.Background(), &rest.Config{Host: server.URL}, csr, nil, config.ApprovalCondition); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(approved.Status.Conditions) != 1 {
+		t.Fatalf("expected a single condition, got %+v", approved.Status.Conditions)
+	}
+	if condition := approved.Status.Conditions[0]; condition.Reason != "StagingApprove" || condition.Message != "Approved by the staging machine approver" {
+		t.Errorf("expected the configured reason and message, got %q, %q", condition.Reason, condition.Message)
+	}
+	if !isApprovedByCMA(config, approved) {
+		t.Error("expected the configured message to be recognized as approved by the machine approver")
+	}
+	if isApprovedByCMA(ClusterMachineApproverConfig{}, approved) {
+		t.Error("expected the message of another configuration not to be recognized")
+	}
+
+	// CSRs approved before the message was configured are still recognized
+	approved.Status.Conditions[0].Message = csrConditionApproveMessage
+	if !isApprovedByCMA(config, approved) {
+		t.Error("expected the default message to be recognized as approved by the machine approver")
+	}
+}
+
+func TestReconcileCSRDenyDisabledFlow(t *testing.T) {
+	bootstrapper := "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper"
+	bootstrapperGroups := []string{
+		"system:authenticated",
+		"system:serviceaccounts:openshift-machine-config-operator",
+		"system:serviceaccounts",
+	}
+
+	tests := []struct {
+		name       string
+		config     ClusterMachineApproverConfig
+		username   string
+		groups     []string
+		wantDenied bool
+		wantReason string
+		wantErr    string
+	}{
+		{
+			name:    "left pending by default",
+			config:  ClusterMachineApproverConfig{NodeClientCert: NodeClientCert{Disabled: true}},
+			wantErr: "CSR csr for node client cert rejected as the flow is disabled",
+		},
+		{
+			name: "denied",
+			config: ClusterMachineApproverConfig{
+				NodeClientCert:       NodeClientCert{Disabled: true},
+				DenyDisabledFlowCSRs: true,
+			},
+			wantDenied: true,
+			wantReason: "NodeCSRFlowDisabled",
+		},
+		{
+			name: "denied with custom reason",
+			config: ClusterMachineApproverConfig{
+				NodeClientCert:       NodeClientCert{Disabled: true},
+				DenyDisabledFlowCSRs: true,
+				ApprovalCondition:    ApprovalCondition{DenyReason: "StagingNodeClientCertsDisabled"},
+			},
+			wantDenied: true,
+			wantReason: "StagingNodeClientCertsDisabled",
+		},
+		{
+			name: "renewal by existing node left pending",
+			config: ClusterMachineApproverConfig{
+				NodeClientCert:       NodeClientCert{Disabled: true, AllowRenewal: true},
+				DenyDisabledFlowCSRs: true,
+			},
+			username: "system:node:panda",
+			groups:   []string{"system:authenticated", "system:nodes"},
+			wantErr:  "CSR csr for node client cert rejected as the flow is disabled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updates int
+			var updated certificatesv1.CertificateSigningRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				updates++
+				body, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(body, &updated); err != nil {
+					t.Errorf("failed to decode approval: %v", err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write(body)
+			}))
+			defer server.Close()
+
+			approver := &CertificateApprover{
+				Config:         tt.config,
+				NodeRestCfg:    &rest.Config{Host: server.URL},
+				WorkloadClient: fake.NewFakeClient(),
+			}
+			username, groups := bootstrapper, bootstrapperGroups
+			if tt.username != "" {
+				username, groups = tt.username, tt.groups
+			}
+			csr := certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					SignerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
+					Usages: []certificatesv1.KeyUsage{
+						certificatesv1.UsageKeyEncipherment,
+						certificatesv1.UsageDigitalSignature,
+						certificatesv1.UsageClientAuth,
+					},
+					Username: username,
+					Groups:   groups,
+					Request:  []byte(clientGood),
+				},
+			}
+
+			_, err := approver.reconcileCSR(context.Background(), csr, nil)
+			if errString(err) != tt.wantErr {
+				t.Fatalf("expected error %q, got %q", tt.wantErr, errString(err))
+			}
+
+			if !tt.wantDenied {
+				if updates != 0 {
+					t.Errorf("expected the CSR to be left pending, got %d approval updates", updates)
+				}
+				return
+			}
+			if updates != 1 {
+				t.Fatalf("expected a single approval update, got %d", updates)
+			}
+			if !isDenied(updated) || isApproved(updated) {
+				t.Fatalf("expected the CSR to be denied, got conditions %+v", updated.Status.Conditions)
+			}
+			if condition := updated.Status.Conditions[0]; condition.Reason != tt.wantReason || !strings.Contains(condition.Message, "rejected as the flow is disabled") {
+				t.Errorf("expected reason %q and the disabled flow in the message, got %q, %q", tt.wantReason, condition.Reason, condition.Message)
+			}
+		})
+	}
+}
+
+func TestReconcileCSRPreApprovalDelay(t *testing.T) {
+	var approvals int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/approval") {
+			atomic.AddInt32(&approvals, 1)
+		}
+		// Echo the updated CSR back
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	machines := []machinehandlerpkg.Machine{{
+		Status: machinehandlerpkg.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "test"},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+				{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+				{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+				{Type: corev1.NodeExternalDNS, Address: "node1"},
+			},
+		},
+	}}
+	csr := certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "csr",
+			CreationTimestamp: metav1.NewTime(now().Add(-10 * time.Second)),
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageKeyEncipherment,
+				certificatesv1.UsageServerAuth,
+			},
+			Username: "system:node:test",
+			Groups: []string{
+				"system:authenticated",
+				"system:nodes",
+			},
+			Request: []byte(goodCSR),
+		},
+	}
+
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewFakeClient(),
+		NodeRestCfg:    &rest.Config{Host: server.URL},
+		Config: ClusterMachineApproverConfig{
+			ServingRenewal:   ServingRenewal{Disabled: true},
+			PreApprovalDelay: metav1.Duration{Duration: 30 * time.Second},
+		},
+	}
+
+	result, err := approver.reconcileCSR(context.Background(), csr, machines)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter != 20*time.Second {
+		t.Errorf("expected CSR to be requeued after the remaining 20s, got %v", result.RequeueAfter)
+	}
+	if got := atomic.LoadInt32(&approvals); got != 0 {
+		t.Fatalf("expected CSR not to be approved during the delay, got %d approvals", got)
+	}
+
+	// A CSR denied by an external validator during the delay is left alone
+	denied := csr.DeepCopy()
+	denied.CreationTimestamp = metav1.NewTime(now().Add(-time.Minute))
+	denied.Status.Conditions = []certificatesv1.CertificateSigningRequestCondition{{
+		Type:   certificatesv1.CertificateDenied,
+		Status: corev1.ConditionTrue,
+	}}
+	result, err = approver.reconcileCSR(context.Background(), *denied, machines)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter != 0 {
+		t.Errorf("expected denied CSR not to be requeued, got %v", result.RequeueAfter)
+	}
+	if got := atomic.LoadInt32(&approvals); got != 0 {
+		t.Fatalf("expected denied CSR not to be approved, got %d approvals", got)
+	}
+
+	// The CSR is approved once the delay has elapsed
+	csr.CreationTimestamp = metav1.NewTime(now().Add(-time.Minute))
+	result, err = approver.reconcileCSR(context.Background(), csr, machines)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter != 0 {
+		t.Errorf("expected CSR not to be requeued once the delay elapsed, got %v", result.RequeueAfter)
+	}
+	if got := atomic.LoadInt32(&approvals); got != 1 {
+		t.Errorf("expected CSR to be approved once the delay elapsed, got %d approvals", got)
+	}
+}
+
+func TestAuthorizeServingCertWithDuplicateMachineAddresses(t *testing.T) {
+	addresses := []corev1.NodeAddress{
+		{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+		{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+		{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+		{Type: corev1.NodeExternalDNS, Address: "node1"},
+	}
+	var duplicated []corev1.NodeAddress
+	for i := 0; i < 3; i++ {
+		duplicated = append(duplicated, addresses...)
+	}
+	// The same address with another type is kept
+	duplicated = append(duplicated, corev1.NodeAddress{Type: corev1.NodeHostName, Address: "node1"})
+
+	want := append(append([]corev1.NodeAddress{}, addresses...), corev1.NodeAddress{Type: corev1.NodeHostName, Address: "node1"})
+	if got := uniqueAddresses(duplicated); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected unique addresses %v, got %v", want, got)
+	}
+
+	req := &certificatesv1.CertificateSigningRequest{ObjectMeta: metav1.ObjectMeta{Name: "csr"}}
+	machine := func(addresses []corev1.NodeAddress) []machinehandlerpkg.Machine {
+		return []machinehandlerpkg.Machine{{
+			Status: machinehandlerpkg.MachineStatus{
+				NodeRef:   &corev1.ObjectReference{Name: "test"},
+				Addresses: addresses,
+			},
+		}}
+	}
+
+	for csrPEM, wantErr := range map[string]string{
+		goodCSR: "",
+		// Only distinct addresses are reported
+		extraAddr: "IP address '99.0.1.1' not in machine addresses: 127.0.0.1 10.0.0.1",
+	} {
+		parsedCSR, err := parseCSR(&certificatesv1.CertificateSigningRequest{
+			Spec: certificatesv1.CertificateSigningRequestSpec{Request: []byte(csrPEM)},
+		})
+		if err != nil {
+			t.Fatalf("failed to parse CSR: %v", err)
+		}
+
+		if _, err := authorizeServingCertWithMachine(nil, ClusterMachineApproverConfig{}, machine(addresses), req, "test", parsedCSR); errString(err) != wantErr {
+			t.Errorf("expected %q, got %q", wantErr, errString(err))
+		}
+		if _, err := authorizeServingCertWithMachine(nil, ClusterMachineApproverConfig{}, machine(duplicated), req, "test", parsedCSR); errString(err) != wantErr {
+			t.Errorf("expected the same result with duplicated addresses %q, got %q", wantErr, errString(err))
+		}
+	}
+}
+
+func TestAuthorizeCSRStaticNodes(t *testing.T) {
+	nodeAddresses := []corev1.NodeAddress{
+		{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+		{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+		{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+		{Type: corev1.NodeExternalDNS, Address: "node1"},
+	}
+	staticNodes := ClusterMachineApproverConfig{StaticNodes: []string{"test"}}
+
+	tests := []struct {
+		name       string
+		config     ClusterMachineApproverConfig
+		addresses  []corev1.NodeAddress
+		machines   []machinehandlerpkg.Machine
+		csr        string
+		wantErr    string
+		wantMethod authorizationMethod
+	}{
+		{
+			name:       "listed node authorized with node addresses",
+			config:     staticNodes,
+			addresses:  nodeAddresses,
+			csr:        goodCSR,
+			wantMethod: authorizedByStaticNode,
+		},
+		{
+			name:      "listed node with SANs not in node addresses",
+			config:    staticNodes,
+			addresses: nodeAddresses,
+			csr:       extraAddr,
+			wantErr:   "could not authorize CSR: exhausted all authorization methods: IP address '99.0.1.1' not in node addresses: 127.0.0.1 10.0.0.1",
+		},
+		{
+			name:    "unlisted node still requires a machine",
+			config:  ClusterMachineApproverConfig{StaticNodes: []string{"other"}},
+			csr:     goodCSR,
+			wantErr: "could not authorize CSR: exhausted all authorization methods: Unable to find machine for node",
+		},
+		{
+			name:   "listed node with a machine is authorized with the machine",
+			config: staticNodes,
+			machines: []machinehandlerpkg.Machine{{
+				Status: machinehandlerpkg.MachineStatus{
+					NodeRef:   &corev1.ObjectReference{Name: "test"},
+					Addresses: nodeAddresses,
+				},
+			}},
+			csr:        goodCSR,
+			wantMethod: authorizedByMachine,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Status:     corev1.NodeStatus{Addresses: tt.addresses},
+			}
+			network := &configv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}
+			cl := fake.NewFakeClient(node, network)
+
+			req := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					Usages: []certificatesv1.KeyUsage{
+						certificatesv1.UsageKeyEncipherment,
+						certificatesv1.UsageDigitalSignature,
+						certificatesv1.UsageServerAuth,
+					},
+					Username: "system:node:test",
+					Groups: []string{
+						"system:authenticated",
+						"system:nodes",
+					},
+					Request: []byte(tt.csr),
+				},
+			}
+			parsedCSR, err := parseCSR(req)
+			if err != nil {
+				t.Fatalf("failed to parse CSR: %v", err)
+			}
 
+			result, err := authorizeCSR(context.Background(), cl, tt.config, tt.machines, req, parsedCSR, nil, nil, nil)
 			if errString(err) != tt.wantErr {
-				t.Errorf("got: %v, want: %s", err, tt.wantErr)
+				t.Errorf("expected error %q, got %q", tt.wantErr, errString(err))
+			}
+			if result.Authorized != (tt.wantMethod != "") || result.Method != tt.wantMethod {
+				t.Errorf("expected method %q, got %+v", tt.wantMethod, result)
 			}
 		})
 	}
 }
 
-func TestAuthorizeServingRenewalWithEgressIPs(t *testing.T) {
-	testNodeName := "test"
+func TestAuthorizeCSRNodeAddresses(t *testing.T) {
+	nodeAddresses := []corev1.NodeAddress{
+		{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+		{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+		{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+		{Type: corev1.NodeExternalDNS, Address: "node1"},
+	}
+	// The internal IP of the machine drifted from the node's
+	driftedMachines := []machinehandlerpkg.Machine{{
+		Status: machinehandlerpkg.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "test"},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "192.168.0.1"},
+				{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+				{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+				{Type: corev1.NodeExternalDNS, Address: "node1"},
+			},
+		},
+	}}
+	allowNodeAddresses := ClusterMachineApproverConfig{NodeServingCert: NodeServingCert{AllowNodeAddresses: true}}
 
 	tests := []struct {
-		name        string
-		nodeName    string
-		csr         *x509.CertificateRequest
-		currentCert *x509.Certificate
-		ca          []*x509.Certificate
-		time        time.Time
-		hostSubnet  *networkv1.HostSubnet
-		wantErr     string
+		name       string
+		config     ClusterMachineApproverConfig
+		node       *corev1.Node
+		machines   []machinehandlerpkg.Machine
+		csr        string
+		wantErr    string
+		wantMethod authorizationMethod
 	}{
 		{
-			name:     "missing args",
-			nodeName: "panda",
-			wantErr:  "CSR, serving cert, or CA not provided",
+			name:     "node addresses not used by default",
+			node:     &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Status: corev1.NodeStatus{Addresses: nodeAddresses}},
+			machines: driftedMachines,
+			csr:      goodCSR,
+			wantErr:  "could not authorize CSR: exhausted all authorization methods: IP address '127.0.0.1' not in machine addresses: 192.168.0.1 10.0.0.1",
 		},
 		{
-			name:        "all good",
-			nodeName:    testNodeName,
-			csr:         parseCR(t, goodCSR),
-			currentCert: parseCert(t, serverCertGood),
-			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
-			time:        presetTimeCorrect,
-			hostSubnet: &networkv1.HostSubnet{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: testNodeName,
-				},
-			},
+			name:       "drifted machine authorized with node addresses",
+			config:     allowNodeAddresses,
+			node:       &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Status: corev1.NodeStatus{Addresses: nodeAddresses}},
+			machines:   driftedMachines,
+			csr:        goodCSR,
+			wantMethod: authorizedByNode,
 		},
 		{
-			name:        "reject expired",
-			nodeName:    testNodeName,
-			csr:         parseCR(t, goodCSR),
-			currentCert: parseCert(t, serverCertGood),
-			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
-			time:        presetTimeExpired,
-			wantErr:     fmt.Sprintf("x509: certificate has expired or is not yet valid: current time %s is before %s", presetTimeExpired.Format(time.RFC3339), presetTimeCorrect.Format(time.RFC3339)),
+			name:     "SANs not in node addresses",
+			config:   allowNodeAddresses,
+			node:     &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Status: corev1.NodeStatus{Addresses: nodeAddresses}},
+			machines: driftedMachines,
+			csr:      extraAddr,
+			wantErr:  "could not authorize CSR: exhausted all authorization methods: [IP address '127.0.0.1' not in machine addresses: 192.168.0.1 10.0.0.1, IP address '99.0.1.1' not in node addresses: 127.0.0.1 10.0.0.1]",
 		},
 		{
-			name:        "With additional unknown IP address",
-			nodeName:    testNodeName,
-			csr:         parseCR(t, extraAddr),
-			currentCert: parseCert(t, serverCertGood),
-			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
-			time:        presetTimeCorrect,
-			hostSubnet: &networkv1.HostSubnet{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: testNodeName,
+			name:     "node does not exist",
+			config:   allowNodeAddresses,
+			machines: driftedMachines,
+			csr:      goodCSR,
+			wantErr:  `could not authorize CSR: exhausted all authorization methods: [IP address '127.0.0.1' not in machine addresses: 192.168.0.1 10.0.0.1, Unable to get node test: nodes "test" not found]`,
+		},
+		{
+			name:   "machine authorizes first",
+			config: allowNodeAddresses,
+			node:   &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Status: corev1.NodeStatus{Addresses: nodeAddresses}},
+			machines: []machinehandlerpkg.Machine{{
+				Status: machinehandlerpkg.MachineStatus{
+					NodeRef:   &corev1.ObjectReference{Name: "test"},
+					Addresses: nodeAddresses,
+				},
+			}},
+			csr:        goodCSR,
+			wantMethod: authorizedByMachine,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := []client.Object{&configv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}}
+			if tt.node != nil {
+				objects = append(objects, tt.node)
+			}
+			cl := fake.NewClientBuilder().WithObjects(objects...).Build()
+
+			req := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					Usages: []certificatesv1.KeyUsage{
+						certificatesv1.UsageKeyEncipherment,
+						certificatesv1.UsageDigitalSignature,
+						certificatesv1.UsageServerAuth,
+					},
+					Username: "system:node:test",
+					Groups: []string{
+						"system:authenticated",
+						"system:nodes",
+					},
+					Request: []byte(tt.csr),
+				},
+			}
+			parsedCSR, err := parseCSR(req)
+			if err != nil {
+				t.Fatalf("failed to parse CSR: %v", err)
+			}
+
+			result, err := authorizeCSR(context.Background(), cl, tt.config, tt.machines, req, parsedCSR, nil, nil, nil)
+			if errString(err) != tt.wantErr {
+				t.Errorf("expected error %q, got %q", tt.wantErr, errString(err))
+			}
+			if result.Authorized != (tt.wantMethod != "") || result.Method != tt.wantMethod {
+				t.Errorf("expected method %q, got %+v", tt.wantMethod, result)
+			}
+		})
+	}
+}
+
+func TestAuthorizeServingCertWithMachineCIDRs(t *testing.T) {
+	newMachines := func(internalIP string) []machinehandlerpkg.Machine {
+		return []machinehandlerpkg.Machine{{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Status: machinehandlerpkg.MachineStatus{
+				NodeRef: &corev1.ObjectReference{Name: "test"},
+				Addresses: []corev1.NodeAddress{
+					{Type: corev1.NodeInternalIP, Address: internalIP},
+					{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+					{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+					{Type: corev1.NodeExternalDNS, Address: "node1"},
 				},
 			},
-			wantErr: "CSR Subject Alternate Names includes unknown IP addresses",
+		}}
+	}
+	allowCIDRs := ClusterMachineApproverConfig{NodeServingCert: NodeServingCert{AllowMachineAddressCIDRs: true}}
+	otherMachine := machinehandlerpkg.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "other"},
+		Status: machinehandlerpkg.MachineStatus{
+			NodeRef:   &corev1.ObjectReference{Name: "other"},
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "127.0.0.1"}},
 		},
+	}
+	node := func(name, internalIP string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: internalIP}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		config   ClusterMachineApproverConfig
+		machines []machinehandlerpkg.Machine
+		nodes    []client.Object
+		wantErr  string
+	}{
 		{
-			name:        "With additional Egress IP address",
-			nodeName:    testNodeName,
-			csr:         parseCR(t, extraAddr),
-			currentCert: parseCert(t, serverCertGood),
-			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
-			time:        presetTimeCorrect,
-			hostSubnet: &networkv1.HostSubnet{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: testNodeName,
+			name:     "CIDR not used by default",
+			machines: newMachines("127.0.0.0/24"),
+			wantErr:  "IP address '127.0.0.1' not in machine addresses: 127.0.0.0/24 10.0.0.1",
+		},
+		{
+			name:     "CSR IP in range",
+			config:   allowCIDRs,
+			machines: newMachines("127.0.0.0/24"),
+		},
+		{
+			name:     "CSR IP out of range",
+			config:   allowCIDRs,
+			machines: newMachines("192.168.0.0/24"),
+			wantErr:  "IP address '127.0.0.1' not in machine addresses: 192.168.0.0/24 10.0.0.1",
+		},
+		{
+			name:     "plain IP addresses",
+			config:   allowCIDRs,
+			machines: newMachines("127.0.0.1"),
+		},
+		{
+			name:     "range wider than the default limit",
+			config:   allowCIDRs,
+			machines: newMachines("127.0.0.0/16"),
+			wantErr:  "IP address '127.0.0.1' not in machine addresses: 127.0.0.0/16 10.0.0.1",
+		},
+		{
+			name:     "any IP range rejected",
+			config:   allowCIDRs,
+			machines: newMachines("0.0.0.0/0"),
+			wantErr:  "IP address '127.0.0.1' not in machine addresses: 0.0.0.0/0 10.0.0.1",
+		},
+		{
+			name: "range within the configured limit",
+			config: ClusterMachineApproverConfig{NodeServingCert: NodeServingCert{
+				AllowMachineAddressCIDRs:      true,
+				MaxMachineAddressCIDRHostBits: 16,
+			}},
+			machines: newMachines("127.0.0.0/16"),
+		},
+		{
+			name:     "IP in range of another machine",
+			config:   allowCIDRs,
+			machines: append(newMachines("127.0.0.0/24"), otherMachine),
+			wantErr:  "IP address '127.0.0.1' not in machine addresses: 127.0.0.0/24 10.0.0.1",
+		},
+		{
+			name:     "IP in range of another node",
+			config:   allowCIDRs,
+			machines: newMachines("127.0.0.0/24"),
+			nodes:    []client.Object{node("other", "127.0.0.1")},
+			wantErr:  "IP address '127.0.0.1' not in machine addresses: 127.0.0.0/24 10.0.0.1",
+		},
+		{
+			name:     "IP in range of the node itself",
+			config:   allowCIDRs,
+			machines: newMachines("127.0.0.0/24"),
+			nodes:    []client.Object{node("test", "127.0.0.1")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					Username: "system:node:test",
+					Request:  []byte(goodCSR),
+				},
+			}
+			parsedCSR, err := parseCSR(req)
+			if err != nil {
+				t.Fatalf("failed to parse CSR: %v", err)
+			}
+
+			_, err = authorizeServingCertWithMachine(fake.NewClientBuilder().WithObjects(tt.nodes...).Build(), tt.config, tt.machines, req, "test", parsedCSR)
+			if errString(err) != tt.wantErr {
+				t.Errorf("expected error %q, got %q", tt.wantErr, errString(err))
+			}
+		})
+	}
+}
+
+func TestAuthorizeServingCertWithMachineHostName(t *testing.T) {
+	// The machine has no DNS names but its Hostname
+	newMachines := func(hostName string) []machinehandlerpkg.Machine {
+		return []machinehandlerpkg.Machine{{
+			Status: machinehandlerpkg.MachineStatus{
+				NodeRef: &corev1.ObjectReference{Name: "test"},
+				Addresses: []corev1.NodeAddress{
+					{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+					{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+					{Type: corev1.NodeHostName, Address: hostName},
 				},
-				EgressIPs: []networkv1.HostSubnetEgressIP{"99.0.1.1"},
 			},
+		}}
+	}
+	allowShortNames := ClusterMachineApproverConfig{NodeServingCert: NodeServingCert{AllowHostNameShortNames: true}}
+
+	tests := []struct {
+		name     string
+		config   ClusterMachineApproverConfig
+		csr      string
+		machines []machinehandlerpkg.Machine
+		wantErr  string
+	}{
+		{
+			name:     "hostname only",
+			csr:      createCSR("system:node:test", defaultOrgs, defaultIPs, []string{"node1.local"}),
+			machines: newMachines("node1.local"),
 		},
 		{
-			name:        "With additional Egress IP in Egress CIDRs",
-			nodeName:    testNodeName,
-			csr:         parseCR(t, extraAddr),
-			currentCert: parseCert(t, serverCertGood),
-			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
-			time:        presetTimeCorrect,
-			hostSubnet: &networkv1.HostSubnet{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: testNodeName,
+			name:     "hostname only with trailing dot",
+			csr:      createCSR("system:node:test", defaultOrgs, defaultIPs, []string{"node1.local"}),
+			machines: newMachines("node1.local."),
+		},
+		{
+			name:     "short name not allowed by default",
+			csr:      goodCSR,
+			machines: newMachines("node1.local"),
+			wantErr:  "DNS name 'node1' not in machine names: node1.local",
+		},
+		{
+			name:     "short name allowed",
+			config:   allowShortNames,
+			csr:      goodCSR,
+			machines: newMachines("node1.local"),
+		},
+		{
+			name:     "short name of another hostname",
+			config:   allowShortNames,
+			csr:      goodCSR,
+			machines: newMachines("node2.local"),
+			wantErr:  "DNS name 'node1' not in machine names: node2.local node2",
+		},
+		{
+			name:     "unqualified hostname",
+			config:   allowShortNames,
+			csr:      createCSR("system:node:test", defaultOrgs, defaultIPs, []string{"node1"}),
+			machines: newMachines("node1"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					Username: "system:node:test",
+					Request:  []byte(tt.csr),
+				},
+			}
+			parsedCSR, err := parseCSR(req)
+			if err != nil {
+				t.Fatalf("failed to parse CSR: %v", err)
+			}
+
+			_, err = authorizeServingCertWithMachine(fake.NewFakeClient(), tt.config, tt.machines, req, "test", parsedCSR)
+			if errString(err) != tt.wantErr {
+				t.Errorf("expected error %q, got %q", tt.wantErr, errString(err))
+			}
+		})
+	}
+}
+
+func TestHostNameShortNames(t *testing.T) {
+	addresses := []corev1.NodeAddress{
+		{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+		{Type: corev1.NodeInternalDNS, Address: "node1.internal"},
+		{Type: corev1.NodeHostName, Address: "node1.example.com"},
+		{Type: corev1.NodeHostName, Address: "node2"},
+		{Type: corev1.NodeHostName, Address: "10.0.0.2"},
+		{Type: corev1.NodeHostName, Address: ".example.com"},
+	}
+	want := []corev1.NodeAddress{{Type: corev1.NodeHostName, Address: "node1"}}
+
+	if got := hostNameShortNames(addresses); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected short names %v, got %v", want, got)
+	}
+}
+
+func TestAuthorizeServingCertWithMachineLabel(t *testing.T) {
+	// The machine addresses match the SANs, but no machine address or node
+	// ref matches the node name.
+	machine := func(labels map[string]string, nodeRef *corev1.ObjectReference) []machinehandlerpkg.Machine {
+		return []machinehandlerpkg.Machine{{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "machine", Labels: labels},
+			Status: machinehandlerpkg.MachineStatus{
+				NodeRef: nodeRef,
+				Addresses: []corev1.NodeAddress{
+					{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+					{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+					{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+					{Type: corev1.NodeExternalDNS, Address: "node1"},
 				},
-				EgressCIDRs: []networkv1.HostSubnetEgressCIDR{"99.0.1.0/24"},
 			},
+		}}
+	}
+	matchLabel := ClusterMachineApproverConfig{MachineAPIAuthorization: MachineAPIAuthorization{NodeNameLabel: "node-name"}}
+
+	tests := []struct {
+		name     string
+		config   ClusterMachineApproverConfig
+		machines []machinehandlerpkg.Machine
+		wantErr  string
+	}{
+		{
+			name:     "label not matched by default",
+			machines: machine(map[string]string{"node-name": "test"}, nil),
+			wantErr:  "Unable to find machine for node",
 		},
 		{
-			name:        "No certificate match",
-			nodeName:    testNodeName,
-			csr:         parseCR(t, goodCSR),
-			currentCert: parseCert(t, serverCertGood),
-			ca:          []*x509.Certificate{},
-			time:        presetTimeCorrect,
-			wantErr:     "x509: certificate signed by unknown authority",
+			name:     "matched by label",
+			config:   matchLabel,
+			machines: machine(map[string]string{"node-name": "test"}, nil),
 		},
 		{
-			name:        "Request from different node",
-			nodeName:    testNodeName,
-			csr:         parseCR(t, otherName),
-			currentCert: parseCert(t, serverCertGood),
-			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
-			time:        presetTimeCorrect,
-			wantErr:     "current serving cert and CSR common name mismatch",
+			name:     "label of another node",
+			config:   matchLabel,
+			machines: machine(map[string]string{"node-name": "other"}, nil),
+			wantErr:  "Unable to find machine for node",
 		},
 		{
-			name:        "Unexpected CN",
-			nodeName:    "panda",
-			csr:         parseCR(t, goodCSR),
-			currentCert: parseCert(t, serverCertGood),
-			ca:          []*x509.Certificate{parseCert(t, rootCertGood)},
-			time:        presetTimeCorrect,
-			wantErr:     "current serving cert has bad common name",
+			name:     "labeled machine references another node",
+			config:   matchLabel,
+			machines: machine(map[string]string{"node-name": "test"}, &corev1.ObjectReference{Name: "other"}),
+			wantErr:  "Unable to find machine for node",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			certPool := x509.NewCertPool()
-			for _, cert := range tt.ca {
-				certPool.AddCert(cert)
+			req := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					Username: "system:node:test",
+					Request:  []byte(goodCSR),
+				},
+			}
+			parsedCSR, err := parseCSR(req)
+			if err != nil {
+				t.Fatalf("failed to parse CSR: %v", err)
 			}
 
-			objs := []runtime.Object{}
-			if tt.hostSubnet != nil {
-				objs = append(objs, tt.hostSubnet)
+			targetMachine, err := authorizeServingCertWithMachine(fake.NewFakeClient(), tt.config, tt.machines, req, "test", parsedCSR)
+			if errString(err) != tt.wantErr {
+				t.Errorf("expected error %q, got %q", tt.wantErr, errString(err))
 			}
-			cl := fake.NewFakeClient(objs...)
+			if err == nil && targetMachine.Name != "machine" {
+				t.Errorf("expected machine to authorize the CSR, got %v", targetMachine)
+			}
+		})
+	}
+}
 
-			err := authorizeServingRenewalWithEgressIPs(
-				cl,
-				tt.nodeName,
-				tt.csr,
-				tt.currentCert,
-				x509.VerifyOptions{Roots: certPool, CurrentTime: tt.time},
-			)
+func TestAuthorizeCSRProviderID(t *testing.T) {
+	// The machine addresses match the SANs, but no machine address or node
+	// ref matches the node name.
+	machineAddresses := []corev1.NodeAddress{
+		{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+		{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+		{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+		{Type: corev1.NodeExternalDNS, Address: "node1"},
+	}
+	machine := func(providerID, nodeRef string) []machinehandlerpkg.Machine {
+		machine := machinehandlerpkg.Machine{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "machine"},
+			Spec:       machinehandlerpkg.MachineSpec{ProviderID: &providerID},
+			Status:     machinehandlerpkg.MachineStatus{Addresses: machineAddresses},
+		}
+		if nodeRef != "" {
+			machine.Status.NodeRef = &corev1.ObjectReference{Name: nodeRef}
+		}
+		return []machinehandlerpkg.Machine{machine}
+	}
+	matchProviderID := ClusterMachineApproverConfig{MachineAPIAuthorization: MachineAPIAuthorization{MatchProviderID: true}}
+
+	tests := []struct {
+		name       string
+		config     ClusterMachineApproverConfig
+		machines   []machinehandlerpkg.Machine
+		wantErr    string
+		wantMethod authorizationMethod
+	}{
+		{
+			name:       "matched by provider ID",
+			config:     matchProviderID,
+			machines:   machine("aws:///us-east-1a/i-1", ""),
+			wantMethod: authorizedByMachine,
+		},
+		{
+			name:     "provider ID matching disabled",
+			machines: machine("aws:///us-east-1a/i-1", ""),
+			wantErr:  "could not authorize CSR: exhausted all authorization methods: Unable to find machine for node",
+		},
+		{
+			name:     "different provider ID",
+			config:   matchProviderID,
+			machines: machine("aws:///us-east-1a/i-2", ""),
+			wantErr:  "could not authorize CSR: exhausted all authorization methods: Unable to find machine for node",
+		},
+		{
+			name:     "machine referencing another node",
+			config:   matchProviderID,
+			machines: machine("aws:///us-east-1a/i-1", "other"),
+			wantErr:  "could not authorize CSR: exhausted all authorization methods: Unable to find machine for node",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec:       corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-1"},
+			}
+			network := &configv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}
+			cl := fake.NewFakeClient(node, network)
+
+			req := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					Usages: []certificatesv1.KeyUsage{
+						certificatesv1.UsageKeyEncipherment,
+						certificatesv1.UsageDigitalSignature,
+						certificatesv1.UsageServerAuth,
+					},
+					Username: "system:node:test",
+					Groups: []string{
+						"system:authenticated",
+						"system:nodes",
+					},
+					Request: []byte(goodCSR),
+				},
+			}
+			parsedCSR, err := parseCSR(req)
+			if err != nil {
+				t.Fatalf("failed to parse CSR: %v", err)
+			}
 
+			result, err := authorizeCSR(context.Background(), cl, tt.config, tt.machines, req, parsedCSR, nil, nil, nil)
 			if errString(err) != tt.wantErr {
-				t.Errorf("got: %v, want: %s", err, tt.wantErr)
+				t.Errorf("expected error %q, got %q", tt.wantErr, errString(err))
+			}
+			if result.Authorized != (tt.wantMethod != "") || result.Method != tt.wantMethod {
+				t.Errorf("expected method %q, got %+v", tt.wantMethod, result)
 			}
 		})
 	}
 }
 
-func TestGetServingCert(t *testing.T) {
-	defaultPort := int32(25535)
-	defaultAddr := "127.0.0.1"
-	defaultNode := &corev1.Node{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "test",
-		},
-		Status: corev1.NodeStatus{
-			Addresses: []corev1.NodeAddress{
-				{Type: corev1.NodeInternalIP, Address: defaultAddr},
-			},
-			DaemonEndpoints: corev1.NodeDaemonEndpoints{
-				KubeletEndpoint: corev1.DaemonEndpoint{
-					Port: defaultPort,
+func TestAuthorizeServingCertWithExtraAllowedNodeIPs(t *testing.T) {
+	// The machine has the public IP, the CSR requests the private IP 127.0.0.1
+	machine := func(nodeName string) []machinehandlerpkg.Machine {
+		return []machinehandlerpkg.Machine{{
+			Status: machinehandlerpkg.MachineStatus{
+				NodeRef: &corev1.ObjectReference{Name: nodeName},
+				Addresses: []corev1.NodeAddress{
+					{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+					{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+					{Type: corev1.NodeExternalDNS, Address: "node1"},
 				},
 			},
+		}}
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: configNamespace, Name: "extra-ips"},
+		Data: map[string]string{
+			"test":  "127.0.0.1, not-an-ip",
+			"other": "127.0.0.1",
 		},
 	}
-
-	wrongAddr := defaultNode.DeepCopy()
-	wrongAddr.Status.DaemonEndpoints.KubeletEndpoint.Port = int32(25544)
-
-	uninitialized := defaultNode.DeepCopy()
-	uninitialized.Status = corev1.NodeStatus{}
+	extraIPs := ClusterMachineApproverConfig{ExtraAllowedNodeIPsConfigMap: ConfigMapReference{Name: "extra-ips"}}
 
 	tests := []struct {
-		name      string
-		nodeName  string
-		node      *corev1.Node
-		rootCerts []*x509.Certificate
-		wantErr   string
+		name     string
+		config   ClusterMachineApproverConfig
+		nodeName string
+		objects  []client.Object
+		wantErr  string
 	}{
 		{
-			name:      "all good",
-			nodeName:  "test",
-			node:      defaultNode,
-			rootCerts: []*x509.Certificate{parseCert(t, rootCertGood)},
-		},
-		{
-			name:      "unknown certificate",
-			nodeName:  "test",
-			node:      defaultNode,
-			rootCerts: []*x509.Certificate{parseCert(t, differentCert)},
-			wantErr:   "tls: failed to verify certificate: x509: certificate signed by unknown authority",
-		},
-		{
-			name:      "node not found",
-			nodeName:  "test",
-			rootCerts: []*x509.Certificate{parseCert(t, rootCertGood)},
-			wantErr:   "nodes \"test\" not found",
+			name:     "mapped node extra IP accepted",
+			config:   extraIPs,
+			nodeName: "test",
+			objects:  []client.Object{configMap},
 		},
 		{
-			name:      "wrong address",
-			nodeName:  "test",
-			node:      wrongAddr,
-			rootCerts: []*x509.Certificate{parseCert(t, rootCertGood)},
-			wantErr:   "dial tcp 127.0.0.1:25544: connect: connection refused",
+			name:     "unmapped node rejected",
+			config:   extraIPs,
+			nodeName: "unmapped",
+			objects:  []client.Object{configMap},
+			wantErr:  "IP address '127.0.0.1' not in machine addresses: 10.0.0.1",
 		},
 		{
-			name:     "no pool provided",
+			name:     "not configured",
 			nodeName: "test",
-			node:     defaultNode,
-			wantErr:  "no CA found: will not retrieve serving cert",
+			objects:  []client.Object{configMap},
+			wantErr:  "IP address '127.0.0.1' not in machine addresses: 10.0.0.1",
 		},
 		{
-			name:      "node with no addr",
-			nodeName:  "test",
-			node:      uninitialized,
-			rootCerts: []*x509.Certificate{parseCert(t, rootCertGood)},
-			wantErr:   "node test has no internal addresses",
+			name:     "ConfigMap not found",
+			config:   extraIPs,
+			nodeName: "test",
+			wantErr:  "IP address '127.0.0.1' not in machine addresses: 10.0.0.1",
 		},
 	}
 
-	server := fakeResponder(t, fmt.Sprintf("%s:%v", defaultAddr, defaultPort), serverCertGood, serverKeyGood)
-	defer server.Close()
-
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var certPool *x509.CertPool
-			if len(tt.rootCerts) > 0 {
-				certPool = x509.NewCertPool()
-				for _, cert := range tt.rootCerts {
-					certPool.AddCert(cert)
-				}
+			cl := fake.NewClientBuilder().WithObjects(tt.objects...).Build()
+			req := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+				Spec:       certificatesv1.CertificateSigningRequestSpec{Request: []byte(goodCSR)},
 			}
-
-			objects := []runtime.Object{}
-			if tt.node != nil {
-				objects = append(objects, tt.node)
+			parsedCSR, err := parseCSR(req)
+			if err != nil {
+				t.Fatalf("failed to parse CSR: %v", err)
 			}
-			cl := fake.NewFakeClient(objects...)
 
-			go respond(server)
-			serverCert, err := getServingCert(cl, tt.nodeName, certPool)
+			_, err = authorizeServingCertWithMachine(cl, tt.config, machine(tt.nodeName), req, tt.nodeName, parsedCSR)
 			if errString(err) != tt.wantErr {
-				t.Fatalf("got: %v, want: %s", err, tt.wantErr)
-			}
-			if err == nil && !serverCert.Equal(parseCert(t, serverCertGood)) {
-				t.Fatal("Expected server certificate match on success")
+				t.Errorf("expected error %q, got %q", tt.wantErr, errString(err))
 			}
 		})
 	}
 }
 
-func TestRecentlyPendingNodeBootstrapperCSRs(t *testing.T) {
-	approvedNodeBootstrapperCSR := certificatesv1.CertificateSigningRequest{
-		Spec: certificatesv1.CertificateSigningRequestSpec{
-			SignerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
-			Username:   nodeBootstrapperUsername,
-			Groups:     nodeBootstrapperGroups.List(),
+func TestNeedsEgressCheck(t *testing.T) {
+	network := func(networkType string) *configv1.Network {
+		return &configv1.Network{
+			ObjectMeta: metav1.ObjectMeta{Name: networkClusterName},
+			Status:     configv1.NetworkStatus{NetworkType: networkType},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		client  client.Client
+		want    bool
+		wantErr string
+	}{
+		{
+			name:   "openshift-sdn",
+			client: fake.NewFakeClient(network(networkTypeOpenShiftSDN)),
+			want:   true,
 		},
-		Status: certificatesv1.CertificateSigningRequestStatus{
-			Conditions: []certificatesv1.CertificateSigningRequestCondition{{
-				Type: certificatesv1.CertificateApproved,
-			}},
+		{
+			name:   "other network type",
+			client: fake.NewFakeClient(network("OVNKubernetes")),
 		},
-	}
-	pendingNodeBootstrapperCSR := certificatesv1.CertificateSigningRequest{
-		Spec: certificatesv1.CertificateSigningRequestSpec{
-			SignerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
-			Username:   nodeBootstrapperUsername,
-			Groups:     nodeBootstrapperGroups.List(),
+		{
+			name:   "no cluster network",
+			client: fake.NewFakeClient(),
 		},
-	}
-	pendingNodeServerCSR := certificatesv1.CertificateSigningRequest{
-		Spec: certificatesv1.CertificateSigningRequestSpec{
-			Username:   nodeUserPrefix + "clustername-abcde-master-us-west-1a-0",
-			SignerName: certificatesv1.KubeletServingSignerName,
-			Groups:     nodeServingGroups.List(),
+		{
+			name: "API failure",
+			client: interceptor.NewClient(fake.NewFakeClient(network(networkTypeOpenShiftSDN)).(client.WithWatch), interceptor.Funcs{
+				Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+					return errors.New("connection refused")
+				},
+			}),
+			wantErr: "could not fetch cluster network: connection refused",
 		},
 	}
-	pendingNodeServerCSRMissingGroups := certificatesv1.CertificateSigningRequest{
-		Spec: certificatesv1.CertificateSigningRequestSpec{
-			Username:   nodeUserPrefix + "clustername-abcde-master-us-west-1a-0",
-			SignerName: certificatesv1.KubeletServingSignerName,
-		},
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := needsEgressCheck(tt.client)
+			if errString(err) != tt.wantErr {
+				t.Errorf("expected error %q, got %q", tt.wantErr, errString(err))
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
 	}
-	pendingCSR := certificatesv1.CertificateSigningRequest{
+}
+
+func TestAuthorizeCSRWithoutClusterNetwork(t *testing.T) {
+	req := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr"},
 		Spec: certificatesv1.CertificateSigningRequestSpec{
-			SignerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageKeyEncipherment,
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageServerAuth,
+			},
+			Username: "system:node:test",
+			Groups: []string{
+				"system:authenticated",
+				"system:nodes",
+			},
+			Request: []byte(goodCSR),
 		},
 	}
-	pendingMultusCSR := certificatesv1.CertificateSigningRequest{
-		Spec: certificatesv1.CertificateSigningRequestSpec{
-			Username:   nodeUserPrefix + "clustername-abcde-master-us-west-1a-0",
-			SignerName: certificatesv1.KubeAPIServerClientSignerName, // Not approved by this controller
-			Request:    []byte(multusCSRPEM),
-		},
+	parsedCSR, err := parseCSR(req)
+	if err != nil {
+		t.Fatalf("failed to parse CSR: %v", err)
 	}
 
-	pendingTime := baseTime.Add(time.Second)
-	pastApprovalTime := baseTime.Add(-maxPendingDelta)
-	preApprovalTime := baseTime.Add(10 * time.Second)
+	machines := []machinehandlerpkg.Machine{{
+		Status: machinehandlerpkg.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "test"},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+				{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+				{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+				{Type: corev1.NodeExternalDNS, Address: "node1"},
+			},
+		},
+	}}
 
-	createdAt := func(time time.Time, csr certificatesv1.CertificateSigningRequest) certificatesv1.CertificateSigningRequest {
-		csr.CreationTimestamp.Time = time
-		return csr
+	result, err := authorizeCSR(context.Background(), fake.NewFakeClient(), ClusterMachineApproverConfig{}, machines, req, parsedCSR, nil, nil, nil)
+	if err != nil || !result.Authorized || result.Method != authorizedByMachine {
+		t.Errorf("expected authorization by machine, got %+v, error %v", result, err)
+	}
+
+	// Without a machine, all methods are exhausted rather than failing to check egress
+	wantErr := "could not authorize CSR: exhausted all authorization methods: Unable to find machine for node"
+	result, err = authorizeCSR(context.Background(), fake.NewFakeClient(), ClusterMachineApproverConfig{}, nil, req, parsedCSR, nil, nil, nil)
+	if result.Authorized || errString(err) != wantErr {
+		t.Errorf("expected error %q, got %+v, error %q", wantErr, result, errString(err))
 	}
+}
 
+func TestAuthorizeCSRAmbiguousUsages(t *testing.T) {
 	tests := []struct {
-		name          string
-		csrs          []certificatesv1.CertificateSigningRequest
-		expectPending int
+		name       string
+		username   string
+		groups     []string
+		csr        string
+		wantReason string
 	}{
 		{
-			name:          "recently pending Node bootstrapper csr",
-			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(pendingTime, pendingNodeBootstrapperCSR)},
-			expectPending: 1,
-		},
-		{
-			name:          "recently pending Node csr",
-			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(pendingTime, pendingNodeServerCSR)},
-			expectPending: 1,
+			name:       "client CSR with server auth",
+			username:   "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+			groups:     nodeBootstrapperGroups.List(),
+			csr:        clientGood,
+			wantReason: reasonAmbiguousUsages,
 		},
 		{
-			name:          "recently pending unknown csr",
-			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(pendingTime, pendingCSR)},
-			expectPending: 0,
+			name:       "serving CSR with client auth",
+			username:   "system:node:test",
+			groups:     nodeServingGroups.List(),
+			csr:        goodCSR,
+			wantReason: reasonAmbiguousUsages,
 		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					Usages: []certificatesv1.KeyUsage{
+						certificatesv1.UsageDigitalSignature,
+						certificatesv1.UsageKeyEncipherment,
+						certificatesv1.UsageClientAuth,
+						certificatesv1.UsageServerAuth,
+					},
+					Username: tt.username,
+					Groups:   tt.groups,
+					Request:  []byte(tt.csr),
+				},
+			}
+			parsedCSR, err := parseCSR(req)
+			if err != nil {
+				t.Fatalf("failed to parse CSR: %v", err)
+			}
+
+			before := atomic.LoadUint32(&AmbiguousUsageCSRs)
+			result, err := authorizeCSR(context.Background(), fake.NewFakeClient(), ClusterMachineApproverConfig{}, nil, req, parsedCSR, nil, nil, nil)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if result.Authorized || result.Reason != tt.wantReason {
+				t.Errorf("expected not authorized with reason %q, got %+v", tt.wantReason, result)
+			}
+			if got := atomic.LoadUint32(&AmbiguousUsageCSRs) - before; got != 1 {
+				t.Errorf("expected the ambiguous usages to be counted once, got %d", got)
+			}
+		})
+	}
+}
+
+func TestAuthorizeCSRSignerUsagesMismatch(t *testing.T) {
+	clientUsages := []certificatesv1.KeyUsage{
+		certificatesv1.UsageDigitalSignature,
+		certificatesv1.UsageKeyEncipherment,
+		certificatesv1.UsageClientAuth,
+	}
+	servingUsages := []certificatesv1.KeyUsage{
+		certificatesv1.UsageDigitalSignature,
+		certificatesv1.UsageKeyEncipherment,
+		certificatesv1.UsageServerAuth,
+	}
+
+	tests := []struct {
+		name       string
+		signerName string
+		usages     []certificatesv1.KeyUsage
+		username   string
+		groups     []string
+		csr        string
+		wantReason string
+	}{
 		{
-			name:          "recently approved csr",
-			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(pendingTime, approvedNodeBootstrapperCSR)},
-			expectPending: 0,
+			name:       "serving signer with client usages",
+			signerName: certificatesv1.KubeletServingSignerName,
+			usages:     clientUsages,
+			username:   "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+			groups:     nodeBootstrapperGroups.List(),
+			csr:        clientGood,
+			wantReason: reasonServingSignerWithoutServerAuth,
 		},
 		{
-			name:          "pending past approval time",
-			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(pastApprovalTime, pendingNodeBootstrapperCSR)},
-			expectPending: 0,
+			name:       "serving signer without auth usages",
+			signerName: certificatesv1.KubeletServingSignerName,
+			usages:     []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature},
+			username:   "system:node:test",
+			groups:     nodeServingGroups.List(),
+			csr:        goodCSR,
+			wantReason: reasonServingSignerWithoutServerAuth,
 		},
 		{
-			name:          "pending before approval time",
-			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(preApprovalTime, pendingNodeBootstrapperCSR)},
-			expectPending: 0,
+			name:       "client signer with serving usages",
+			signerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
+			usages:     servingUsages,
+			username:   "system:node:test",
+			groups:     nodeServingGroups.List(),
+			csr:        goodCSR,
+			wantReason: reasonClientSignerWithoutClientAuth,
 		},
 		{
-			name:          "multus node CSR",
-			csrs:          []certificatesv1.CertificateSigningRequest{createdAt(pendingTime, pendingMultusCSR)},
-			expectPending: 0,
+			name:       "client signer without auth usages",
+			signerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
+			usages:     []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature},
+			username:   "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+			groups:     nodeBootstrapperGroups.List(),
+			csr:        clientGood,
+			wantReason: reasonClientSignerWithoutClientAuth,
 		},
 		{
-			name: "csrs unrelated to machines",
-			csrs: []certificatesv1.CertificateSigningRequest{
-				createdAt(pendingTime, pendingCSR),
-				createdAt(pendingTime, pendingMultusCSR),
-				createdAt(pendingTime, pendingNodeServerCSRMissingGroups)},
-			expectPending: 0,
+			name:       "serving signer with serving usages",
+			signerName: certificatesv1.KubeletServingSignerName,
+			usages:     servingUsages,
+			username:   "system:node:test",
+			groups:     nodeServingGroups.List(),
+			csr:        goodCSR,
 		},
 		{
-			name: "multiple different csrs",
-			csrs: []certificatesv1.CertificateSigningRequest{
-				createdAt(pendingTime, pendingNodeBootstrapperCSR),
-				createdAt(pendingTime, pendingNodeBootstrapperCSR),
-				createdAt(pendingTime, pendingNodeServerCSR),
-
-				createdAt(pendingTime, pendingCSR),
-				createdAt(pendingTime, approvedNodeBootstrapperCSR),
-				createdAt(preApprovalTime, approvedNodeBootstrapperCSR),
-				createdAt(pastApprovalTime, approvedNodeBootstrapperCSR),
-				createdAt(preApprovalTime, pendingNodeBootstrapperCSR),
-				createdAt(pastApprovalTime, pendingNodeBootstrapperCSR),
-			},
-			expectPending: 3,
+			name:       "client signer with client usages",
+			signerName: certificatesv1.KubeAPIServerClientKubeletSignerName,
+			usages:     clientUsages,
+			username:   "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+			groups:     nodeBootstrapperGroups.List(),
+			csr:        clientGood,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if pending := recentlyPendingNodeCSRs(tt.csrs); pending != tt.expectPending {
-				t.Errorf("Expected %v pending CSRs, got: %v", tt.expectPending, pending)
+			req := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					SignerName: tt.signerName,
+					Usages:     tt.usages,
+					Username:   tt.username,
+					Groups:     tt.groups,
+					Request:    []byte(tt.csr),
+				},
+			}
+			parsedCSR, err := parseCSR(req)
+			if err != nil {
+				t.Fatalf("failed to parse CSR: %v", err)
+			}
+
+			// CSRs without a mismatch fail later on, as there are no machines.
+			result, _ := authorizeCSR(context.Background(), fake.NewFakeClient(), ClusterMachineApproverConfig{}, nil, req, parsedCSR, nil, nil, nil)
+			if result.Authorized || result.Reason != tt.wantReason {
+				t.Errorf("expected not authorized with reason %q, got %+v", tt.wantReason, result)
 			}
 		})
 	}
 }
 
-func TestNodeInternalIP(t *testing.T) {
+func TestAuthorizeCSRMaxSANCount(t *testing.T) {
+	sans := func(count int) ([]string, []corev1.NodeAddress) {
+		var dnsNames []string
+		var addresses []corev1.NodeAddress
+		for i := 0; i < count; i++ {
+			name := fmt.Sprintf("node-%d.local", i)
+			dnsNames = append(dnsNames, name)
+			addresses = append(addresses, corev1.NodeAddress{Type: corev1.NodeInternalDNS, Address: name})
+		}
+		return dnsNames, addresses
+	}
+
 	tests := []struct {
-		name    string
-		node    *corev1.Node
-		wantIP  string
-		wantErr string
+		name      string
+		config    ClusterMachineApproverConfig
+		count     int
+		authorize bool
 	}{
 		{
-			name: "no addresses",
-			node: &corev1.Node{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "no-addresses",
-				},
-				Status: corev1.NodeStatus{
-					Addresses: []corev1.NodeAddress{},
-				},
-			},
-			wantErr: "node no-addresses has no internal addresses",
+			name:      "just under the default limit",
+			count:     defaultMaxSANCount,
+			authorize: true,
 		},
 		{
-			name: "no internal ip",
-			node: &corev1.Node{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "no-internal-ip",
-				},
-				Status: corev1.NodeStatus{
-					Addresses: []corev1.NodeAddress{
-						{Type: corev1.NodeHostName, Address: "host.example.com"},
-					},
-				},
-			},
-			wantErr: "node no-internal-ip has no internal addresses",
+			name:  "just over the default limit",
+			count: defaultMaxSANCount + 1,
 		},
 		{
-			name: "has internal ip",
-			node: &corev1.Node{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "has-internal-ip",
-				},
-				Status: corev1.NodeStatus{
-					Addresses: []corev1.NodeAddress{
-						{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
-					},
-				},
-			},
-			wantIP: "10.0.0.1",
+			name:      "just under a configured limit",
+			config:    ClusterMachineApproverConfig{MaxSANCount: 4},
+			count:     4,
+			authorize: true,
 		},
 		{
-			name: "has ipv6 address",
-			node: &corev1.Node{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "has-ipv6-address",
-				},
-				Status: corev1.NodeStatus{
-					Addresses: []corev1.NodeAddress{
-						{Type: corev1.NodeInternalIP, Address: "2600:1f18:4254:5100:ef8a:7b65:7782:9248"},
-					},
-				},
-			},
-			wantIP: "2600:1f18:4254:5100:ef8a:7b65:7782:9248",
+			name:   "just over a configured limit",
+			config: ClusterMachineApproverConfig{MaxSANCount: 4},
+			count:  5,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ip, err := nodeInternalIP(tt.node)
-
-			if errString(err) != tt.wantErr {
-				t.Errorf("got: %v, want: %s", err, tt.wantErr)
+			dnsNames, addresses := sans(tt.count)
+			req := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					Usages: []certificatesv1.KeyUsage{
+						certificatesv1.UsageKeyEncipherment,
+						certificatesv1.UsageDigitalSignature,
+						certificatesv1.UsageServerAuth,
+					},
+					Username: "system:node:test",
+					Groups: []string{
+						"system:authenticated",
+						"system:nodes",
+					},
+					Request: []byte(createCSR("system:node:test", []string{"system:nodes"}, nil, dnsNames)),
+				},
+			}
+			parsedCSR, err := parseCSR(req)
+			if err != nil {
+				t.Fatalf("failed to parse CSR: %v", err)
+			}
+			if got := countSANs(parsedCSR); got != tt.count {
+				t.Fatalf("expected %d SANs, got %d", tt.count, got)
 			}
 
-			if ip != tt.wantIP {
-				t.Errorf("got: %v, want: %s", err, tt.wantIP)
+			machines := []machinehandlerpkg.Machine{{
+				Status: machinehandlerpkg.MachineStatus{
+					NodeRef:   &corev1.ObjectReference{Name: "test"},
+					Addresses: addresses,
+				},
+			}}
+			result, err := authorizeCSR(context.Background(), fake.NewFakeClient(), tt.config, machines, req, parsedCSR, nil, nil, nil)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if result.Authorized != tt.authorize {
+				t.Errorf("expected authorized %v, got %v", tt.authorize, result.Authorized)
 			}
 		})
 	}
 }
 
-func errString(err error) string {
-	if err == nil {
-		return ""
-	}
-	errStr := err.Error()
-	if len(errStr) == 0 {
-		panic("invalid error")
+func TestCountSANs(t *testing.T) {
+	uri, _ := url.Parse("spiffe://cluster.local/node")
+	csr := &x509.CertificateRequest{
+		DNSNames:       []string{"node1", "node1.local"},
+		EmailAddresses: []string{"admin@example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("127.0.0.1")},
+		URIs:           []*url.URL{uri},
 	}
-	return errStr
-}
 
-func creationTimestamp(delta time.Duration) metav1.Time {
-	return metav1.NewTime(baseTime.Add(delta))
+	if got := countSANs(csr); got != 5 {
+		t.Errorf("expected 5 SANs, got %d", got)
+	}
+	if got := countSANs(nil); got != 0 {
+		t.Errorf("expected no SANs, got %d", got)
+	}
 }
 
-func TestGetMaxPending(t *testing.T) {
-	testCases := []struct {
-		name        string
-		machines    []machinehandlerpkg.Machine
-		nodes       []corev1.Node
-		expectedMax int
-	}{
-		{
-			name: "with more machines than nodes",
-			machines: []machinehandlerpkg.Machine{
-				{},
-				{},
-				{},
-			},
-			nodes: []corev1.Node{
-				{},
-				{},
-			},
-			expectedMax: 3 + maxDiffBetweenPendingCSRsAndMachinesCount,
-		},
-		{
-			name: "with more nodes than machines",
-			machines: []machinehandlerpkg.Machine{
-				{},
-				{},
-				{},
+func TestAuthorizeNodeClientCSRExistingNode(t *testing.T) {
+	reissue := ClusterMachineApproverConfig{NodeClientCert: NodeClientCert{AllowClientCertReissueForExistingNode: true}}
+	machine := func(nodeRef string) []machinehandlerpkg.Machine {
+		machine := machinehandlerpkg.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "machine",
+				CreationTimestamp: creationTimestamp(-30 * 24 * time.Hour),
 			},
-			nodes: []corev1.Node{
-				{},
-				{},
-				{},
-				{},
+			Status: machinehandlerpkg.MachineStatus{
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalDNS, Address: "panda"}},
 			},
-			expectedMax: 4 + maxDiffBetweenPendingCSRsAndMachinesCount,
-		},
-	}
-
-	for _, tc := range testCases {
-		nodeList := &corev1.NodeList{
-			Items: tc.nodes,
 		}
-		res := getMaxPending(tc.machines, nodeList)
-		if res != tc.expectedMax {
-			t.Errorf("getMaxPending returned incorrect value: %v, expect: %v", res, tc.expectedMax)
+		if nodeRef != "" {
+			machine.Status.NodeRef = &corev1.ObjectReference{Name: nodeRef}
 		}
+		return []machinehandlerpkg.Machine{machine}
+	}
+
+	ready := func(status corev1.ConditionStatus, heartbeat time.Duration) []corev1.NodeCondition {
+		return []corev1.NodeCondition{{
+			Type:              corev1.NodeReady,
+			Status:            status,
+			LastHeartbeatTime: metav1.NewTime(now().Add(heartbeat)),
+		}}
 	}
-}
 
-func TestEqualStrings(t *testing.T) {
 	tests := []struct {
-		name     string
-		a        []string
-		b        []string
-		expected bool
+		name       string
+		config     ClusterMachineApproverConfig
+		machines   []machinehandlerpkg.Machine
+		conditions []corev1.NodeCondition
+		created    time.Duration
+		authorize  bool
 	}{
 		{
-			name:     "both empty",
-			a:        []string{},
-			b:        []string{},
-			expected: true,
+			name:     "rejected by default",
+			machines: machine("panda"),
 		},
 		{
-			name:     "equal",
-			a:        []string{"a", "b"},
-			b:        []string{"a", "b"},
-			expected: true,
+			name:      "re-bootstrap permitted without Ready condition",
+			config:    reissue,
+			machines:  machine("panda"),
+			authorize: true,
 		},
 		{
-			name:     "different order",
-			a:        []string{"b", "a"},
-			b:        []string{"a", "b"},
-			expected: true,
+			name:       "re-bootstrap permitted for NotReady node",
+			config:     reissue,
+			machines:   machine("panda"),
+			conditions: ready(corev1.ConditionFalse, -time.Minute),
+			authorize:  true,
 		},
 		{
-			name:     "not equal",
-			a:        []string{"a"},
-			b:        []string{"a", "b"},
-			expected: false,
+			name:       "re-bootstrap permitted for node with unknown status",
+			config:     reissue,
+			machines:   machine("panda"),
+			conditions: ready(corev1.ConditionUnknown, -time.Minute),
+			authorize:  true,
 		},
 		{
-			name:     "duplicates",
-			a:        []string{"a", "a"},
-			b:        []string{"a", "b"},
-			expected: false,
+			name:       "re-bootstrap permitted for node with stale heartbeat",
+			config:     reissue,
+			machines:   machine("panda"),
+			conditions: ready(corev1.ConditionTrue, -time.Hour),
+			authorize:  true,
+		},
+		{
+			name:       "healthy node rejected",
+			config:     reissue,
+			machines:   machine("panda"),
+			conditions: ready(corev1.ConditionTrue, -time.Minute),
+		},
+		{
+			name: "healthy node rejected within configured staleness",
+			config: ClusterMachineApproverConfig{NodeClientCert: NodeClientCert{
+				AllowClientCertReissueForExistingNode: true,
+				ReissueHeartbeatStaleAfter:            metav1.Duration{Duration: 2 * time.Hour},
+			}},
+			machines:   machine("panda"),
+			conditions: ready(corev1.ConditionTrue, -time.Hour),
+		},
+		{
+			name:     "machine without node ref",
+			config:   reissue,
+			machines: machine(""),
+		},
+		{
+			name:     "machine referencing another node",
+			config:   reissue,
+			machines: machine("bear"),
+		},
+		{
+			name:     "CSR too old",
+			config:   reissue,
+			machines: machine("panda"),
+			created:  -3 * time.Hour,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			assertNoChange(t, tt.a, tt.b, func(t *testing.T) {
-				if equal := equalStrings(tt.a, tt.b); equal != tt.expected {
-					t.Errorf("%v == %v :: wanted %v, got %v",
-						tt.a, tt.b, tt.expected, equal)
-				}
+			cl := fake.NewFakeClient(&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "panda"},
+				Status:     corev1.NodeStatus{Conditions: tt.conditions},
 			})
+			req := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "csr",
+					CreationTimestamp: creationTimestamp(tt.created),
+				},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					Usages: []certificatesv1.KeyUsage{
+						certificatesv1.UsageKeyEncipherment,
+						certificatesv1.UsageDigitalSignature,
+						certificatesv1.UsageClientAuth,
+					},
+					Username: "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+					Groups: []string{
+						"system:authenticated",
+						"system:serviceaccounts:openshift-machine-config-operator",
+						"system:serviceaccounts",
+					},
+					Request: []byte(clientGood),
+				},
+			}
+			parsedCSR, err := parseCSR(req)
+			if err != nil {
+				t.Fatalf("failed to parse CSR: %v", err)
+			}
+
+			nodeMachine, err := authorizeNodeClientCSR(cl, tt.config, tt.machines, req, parsedCSR)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if authorized := nodeMachine != nil; authorized != tt.authorize {
+				t.Errorf("expected authorized %v, got %v", tt.authorize, authorized)
+			}
 		})
 	}
 }
 
-func TestEqualURLs(t *testing.T) {
-	exampleNet, err := url.Parse("http://example.net")
-	if err != nil {
-		t.Fatal(err)
+func clientCSRMachineAgeSamples(t *testing.T) (uint64, float64) {
+	metric := &dto.Metric{}
+	if err := ClientCSRMachineAge.Write(metric); err != nil {
+		t.Fatalf("failed to read client CSR machine age: %v", err)
 	}
+	return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+}
 
-	exampleOrg, err := url.Parse("https://example.org")
-	if err != nil {
-		t.Fatal(err)
-	}
+func TestAuthorizeNodeClientCSRMachineAge(t *testing.T) {
+	machines := []machinehandlerpkg.Machine{{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "machine",
+			CreationTimestamp: creationTimestamp(-10 * time.Minute),
+		},
+		Status: machinehandlerpkg.MachineStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalDNS, Address: "panda"}},
+		},
+	}}
 
 	tests := []struct {
-		name     string
-		a        []*url.URL
-		b        []*url.URL
-		expected bool
+		name      string
+		created   time.Duration
+		authorize bool
+		wantCount uint64
+		wantSum   float64
 	}{
 		{
-			name:     "both empty",
-			a:        []*url.URL{},
-			b:        []*url.URL{},
-			expected: true,
+			name:      "observed on approval",
+			authorize: true,
+			wantCount: 1,
+			wantSum:   600,
 		},
 		{
-			name:     "equal",
-			a:        []*url.URL{exampleNet, exampleOrg},
-			b:        []*url.URL{exampleNet, exampleOrg},
-			expected: true,
+			name:    "not observed when not authorized",
+			created: 3 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "csr",
+					CreationTimestamp: creationTimestamp(tt.created),
+				},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					Usages: []certificatesv1.KeyUsage{
+						certificatesv1.UsageKeyEncipherment,
+						certificatesv1.UsageDigitalSignature,
+						certificatesv1.UsageClientAuth,
+					},
+					Username: "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+					Groups: []string{
+						"system:authenticated",
+						"system:serviceaccounts:openshift-machine-config-operator",
+						"system:serviceaccounts",
+					},
+					Request: []byte(clientGood),
+				},
+			}
+			parsedCSR, err := parseCSR(req)
+			if err != nil {
+				t.Fatalf("failed to parse CSR: %v", err)
+			}
+
+			countBefore, sumBefore := clientCSRMachineAgeSamples(t)
+			nodeMachine, err := authorizeNodeClientCSR(fake.NewFakeClient(), ClusterMachineApproverConfig{}, machines, req, parsedCSR)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if authorized := nodeMachine != nil; authorized != tt.authorize {
+				t.Errorf("expected authorized %v, got %v", tt.authorize, authorized)
+			}
+
+			count, sum := clientCSRMachineAgeSamples(t)
+			if count-countBefore != tt.wantCount || sum-sumBefore != tt.wantSum {
+				t.Errorf("expected %d observations summing to %v, got %d summing to %v", tt.wantCount, tt.wantSum, count-countBefore, sum-sumBefore)
+			}
+		})
+	}
+}
+
+func TestAuthorizeNodeClientCSRNodeRefLog(t *testing.T) {
+	var logs bytes.Buffer
+	klog.LogToStderr(false)
+	klog.SetOutput(&logs)
+	defer klog.LogToStderr(true)
+
+	machines := []machinehandlerpkg.Machine{{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "machine",
+			CreationTimestamp: creationTimestamp(-10 * time.Minute),
+		},
+		Status: machinehandlerpkg.MachineStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalDNS, Address: "panda"}},
+			NodeRef:   &corev1.ObjectReference{Kind: "Node", Name: "other"},
+		},
+	}}
+	req := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "csr",
+			UID:               "uid",
+			CreationTimestamp: creationTimestamp(0),
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageKeyEncipherment,
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageClientAuth,
+			},
+			Username: "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+			Groups: []string{
+				"system:authenticated",
+				"system:serviceaccounts:openshift-machine-config-operator",
+				"system:serviceaccounts",
+			},
+			Request: []byte(clientGood),
+		},
+	}
+	parsedCSR, err := parseCSR(req)
+	if err != nil {
+		t.Fatalf("failed to parse CSR: %v", err)
+	}
+
+	nodeMachine, err := authorizeNodeClientCSR(fake.NewFakeClient(), ClusterMachineApproverConfig{}, machines, req, parsedCSR)
+	if err != nil || nodeMachine != nil {
+		t.Fatalf("expected the CSR not to be authorized, got machine %v and error %v", nodeMachine, err)
+	}
+	klog.Flush()
+
+	want := "csr (correlation ID uid): machine machine for node panda already has node ref other, cannot approve"
+	if !strings.Contains(logs.String(), want) {
+		t.Errorf("expected log %q, got:\n%s", want, logs.String())
+	}
+	if strings.Contains(logs.String(), "%!") {
+		t.Errorf("expected no format errors in logs, got:\n%s", logs.String())
+	}
+}
+
+func TestAuthorizeNodeClientCSRMachinePhase(t *testing.T) {
+	running := ClusterMachineApproverConfig{NodeClientCert: NodeClientCert{RequireMachinePhases: []string{"Provisioned", "Running"}}}
+
+	tests := []struct {
+		name      string
+		config    ClusterMachineApproverConfig
+		phase     string
+		authorize bool
+	}{
+		{
+			name:      "any phase allowed by default",
+			phase:     "Deleting",
+			authorize: true,
 		},
 		{
-			name:     "different order",
-			a:        []*url.URL{exampleOrg, exampleNet},
-			b:        []*url.URL{exampleNet, exampleOrg},
-			expected: true,
+			name:      "allowed phase",
+			config:    running,
+			phase:     "Provisioned",
+			authorize: true,
 		},
 		{
-			name:     "not equal",
-			a:        []*url.URL{exampleOrg},
-			b:        []*url.URL{exampleNet, exampleOrg},
-			expected: false,
+			name:   "deleting machine",
+			config: running,
+			phase:  "Deleting",
 		},
 		{
-			name:     "duplicates",
-			a:        []*url.URL{exampleOrg, exampleOrg},
-			b:        []*url.URL{exampleNet, exampleOrg},
-			expected: false,
+			name:   "machine without phase",
+			config: running,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if equal := equalURLs(tt.a, tt.b); equal != tt.expected {
-				t.Errorf("%v == %v :: wanted %v, got %v",
-					tt.a, tt.b, tt.expected, equal)
+			machines := []machinehandlerpkg.Machine{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "machine",
+					CreationTimestamp: creationTimestamp(-10 * time.Minute),
+				},
+				Status: machinehandlerpkg.MachineStatus{
+					Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalDNS, Address: "panda"}},
+					Phase:     tt.phase,
+				},
+			}}
+			req := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "csr",
+					CreationTimestamp: creationTimestamp(0),
+				},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					Usages: []certificatesv1.KeyUsage{
+						certificatesv1.UsageKeyEncipherment,
+						certificatesv1.UsageDigitalSignature,
+						certificatesv1.UsageClientAuth,
+					},
+					Username: "system:serviceaccount:openshift-machine-config-operator:node-bootstrapper",
+					Groups: []string{
+						"system:authenticated",
+						"system:serviceaccounts:openshift-machine-config-operator",
+						"system:serviceaccounts",
+					},
+					Request: []byte(clientGood),
+				},
+			}
+			parsedCSR, err := parseCSR(req)
+			if err != nil {
+				t.Fatalf("failed to parse CSR: %v", err)
+			}
+
+			nodeMachine, err := authorizeNodeClientCSR(fake.NewFakeClient(), tt.config, machines, req, parsedCSR)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if authorized := nodeMachine != nil; authorized != tt.authorize {
+				t.Errorf("expected authorized %v, got %v", tt.authorize, authorized)
 			}
 		})
 	}
 }
 
-func TestEqualIPAddresses(t *testing.T) {
-	tenDotOne := net.ParseIP("10.0.0.1")
-	tenDotTwo := net.ParseIP("10.0.0.2")
+func TestAuthorizeCSRKeyPolicy(t *testing.T) {
+	rsaKey1024, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: "system:node:test", Organization: defaultOrgs},
+		IPAddresses: defaultIPs,
+		DNSNames:    defaultDNSNames,
+	}, rsaKey1024)
+	if err != nil {
+		t.Fatalf("failed to create CSR: %v", err)
+	}
+	csrRSA1024 := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes}))
+
+	policy := ClusterMachineApproverConfig{KeyPolicy: KeyPolicy{MinRSABits: 2048, AllowedCurves: []string{"P-256"}}}
 
 	tests := []struct {
-		name     string
-		a        []net.IP
-		b        []net.IP
-		expected bool
+		name      string
+		config    ClusterMachineApproverConfig
+		csr       string
+		authorize bool
 	}{
 		{
-			name:     "both empty",
-			a:        []net.IP{},
-			b:        []net.IP{},
-			expected: true,
+			name:   "RSA-1024 rejected",
+			config: policy,
+			csr:    csrRSA1024,
 		},
 		{
-			name:     "equal",
-			a:        []net.IP{tenDotOne, tenDotTwo},
-			b:        []net.IP{tenDotOne, tenDotTwo},
-			expected: true,
+			name:      "RSA-2048 accepted",
+			config:    policy,
+			csr:       goodCSR,
+			authorize: true,
 		},
 		{
-			name:     "different order",
-			a:        []net.IP{tenDotTwo, tenDotOne},
-			b:        []net.IP{tenDotOne, tenDotTwo},
-			expected: true,
+			name:      "ECDSA-P256 accepted",
+			config:    policy,
+			csr:       goodCSRECDSA,
+			authorize: true,
 		},
 		{
-			name:     "not equal",
-			a:        []net.IP{tenDotTwo},
-			b:        []net.IP{tenDotOne, tenDotTwo},
-			expected: false,
+			name:   "ECDSA curve not allowed",
+			config: ClusterMachineApproverConfig{KeyPolicy: KeyPolicy{AllowedCurves: []string{"P-384"}}},
+			csr:    goodCSRECDSA,
 		},
 		{
-			name:     "duplicates",
-			a:        []net.IP{tenDotOne, tenDotOne},
-			b:        []net.IP{tenDotOne, tenDotTwo},
-			expected: false,
+			name:      "RSA-1024 accepted without a policy",
+			csr:       csrRSA1024,
+			authorize: true,
 		},
 	}
 
+	machines := []machinehandlerpkg.Machine{{
+		Status: machinehandlerpkg.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "test"},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+				{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+				{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+				{Type: corev1.NodeExternalDNS, Address: "node1"},
+			},
+		},
+	}}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if equal := equalIPAddresses(tt.a, tt.b); equal != tt.expected {
-				t.Errorf("%v == %v :: wanted %v, got %v",
-					tt.a, tt.b, tt.expected, equal)
+			req := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					Usages: []certificatesv1.KeyUsage{
+						certificatesv1.UsageKeyEncipherment,
+						certificatesv1.UsageDigitalSignature,
+						certificatesv1.UsageServerAuth,
+					},
+					Username: "system:node:test",
+					Groups: []string{
+						"system:authenticated",
+						"system:nodes",
+					},
+					Request: []byte(tt.csr),
+				},
+			}
+			parsedCSR, err := parseCSR(req)
+			if err != nil {
+				t.Fatalf("failed to parse CSR: %v", err)
+			}
+
+			result, err := authorizeCSR(context.Background(), fake.NewFakeClient(), tt.config, machines, req, parsedCSR, nil, nil, nil)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if result.Authorized != tt.authorize {
+				t.Errorf("expected authorized %v, got %v", tt.authorize, result.Authorized)
 			}
 		})
 	}
 }
 
-func TestSubsetIPAddresses(t *testing.T) {
-	tenDotOne := net.ParseIP("10.0.0.1")
-	tenDotTwo := net.ParseIP("10.0.0.2")
-	tenDotThree := net.ParseIP("10.0.0.3")
-	tenOneThree := net.ParseIP("10.0.1.3")
-	_, tenNoughtSlash24, _ := net.ParseCIDR("10.0.0.0/24")
+func reconcileStageSamples(t *testing.T, stage string) (uint64, float64) {
+	metric := &dto.Metric{}
+	if err := ReconcileDuration.WithLabelValues(stage).(prometheus.Histogram).Write(metric); err != nil {
+		t.Fatalf("failed to read %s duration: %v", stage, err)
+	}
+	return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+}
 
-	tests := []struct {
-		name     string
-		cidrs    []*net.IPNet
-		super    []net.IP
-		sub      []net.IP
-		expected bool
-	}{
-		{
-			name:     "equal sets",
-			super:    []net.IP{tenDotOne, tenDotTwo},
-			sub:      []net.IP{tenDotOne, tenDotTwo},
-			expected: true,
+func TestObserveReconcileStage(t *testing.T) {
+	defer func(original func() time.Time) { now = original }(now)
+
+	clock := testingclock.NewFakeClock(baseTime)
+	now = clock.Now
+
+	countBefore, sumBefore := reconcileStageSamples(t, ReconcileStageAuthorize)
+	totalCountBefore, _ := reconcileStageSamples(t, ReconcileStageTotal)
+
+	start := now()
+	clock.Step(1500 * time.Millisecond)
+	observeReconcileStage(ReconcileStageAuthorize, start)
+
+	count, sum := reconcileStageSamples(t, ReconcileStageAuthorize)
+	if count != countBefore+1 {
+		t.Errorf("expected a single authorize observation, got %d", count-countBefore)
+	}
+	if observed := sum - sumBefore; observed != 1.5 {
+		t.Errorf("expected an authorize observation of 1.5s, got %vs", observed)
+	}
+	if totalCount, _ := reconcileStageSamples(t, ReconcileStageTotal); totalCount != totalCountBefore {
+		t.Errorf("expected no total observation, got %d", totalCount-totalCountBefore)
+	}
+
+	// Retrieving a serving cert is observed whether it succeeds or not
+	servingCountBefore, _ := reconcileStageSamples(t, ReconcileStageGetServingCert)
+	if _, err := getServingCert(context.Background(), fake.NewFakeClient(), ClusterMachineApproverConfig{}, "missing", x509.NewCertPool(), nil); err == nil {
+		t.Fatal("expected an error retrieving the serving cert of a missing node")
+	}
+	if servingCount, _ := reconcileStageSamples(t, ReconcileStageGetServingCert); servingCount != servingCountBefore+1 {
+		t.Errorf("expected a single get_serving_cert observation, got %d", servingCount-servingCountBefore)
+	}
+}
+
+func TestReconcileCSRAnnotateOutcome(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Echo the updated CSR back
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	csr := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageKeyEncipherment,
+				certificatesv1.UsageServerAuth,
+			},
+			Username: "system:node:test",
+			Groups: []string{
+				"system:authenticated",
+				"system:nodes",
+			},
+			Request: []byte(goodCSR),
 		},
-		{
-			name:     "sub is a subset",
-			super:    []net.IP{tenDotOne, tenDotTwo},
-			sub:      []net.IP{tenDotOne},
-			expected: true,
+	}
+	machines := []machinehandlerpkg.Machine{{
+		Status: machinehandlerpkg.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "test"},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+				{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+				{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+				{Type: corev1.NodeExternalDNS, Address: "node1"},
+			},
+		},
+	}}
+
+	cl := fake.NewFakeClient(csr, &configv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}})
+	approver := &CertificateApprover{
+		WorkloadClient:           cl,
+		NodeRestCfg:              &rest.Config{Host: server.URL},
+		Config:                   ClusterMachineApproverConfig{ServingRenewal: ServingRenewal{Disabled: true}},
+		AnnotateReconcileOutcome: true,
+	}
+
+	reconcileCSR := func(machines []machinehandlerpkg.Machine) *certificatesv1.CertificateSigningRequest {
+		latest := &certificatesv1.CertificateSigningRequest{}
+		if err := cl.Get(context.Background(), client.ObjectKeyFromObject(csr), latest); err != nil {
+			t.Fatalf("failed to get CSR: %v", err)
+		}
+		_, _ = approver.reconcileCSR(context.Background(), *latest, machines)
+		if err := cl.Get(context.Background(), client.ObjectKeyFromObject(csr), latest); err != nil {
+			t.Fatalf("failed to get CSR: %v", err)
+		}
+		return latest
+	}
+
+	// Not authorized without machines
+	for i := 1; i <= 3; i++ {
+		got := reconcileCSR(nil)
+		if count := got.Annotations[reconcileCountAnnotation]; count != strconv.Itoa(i) {
+			t.Errorf("expected reconcile count %d, got %q", i, count)
+		}
+		if outcome := got.Annotations[reconcileOutcomeAnnotation]; outcome != string(reconcileOutcomeNotAuthorized) {
+			t.Errorf("expected outcome %s, got %q", reconcileOutcomeNotAuthorized, outcome)
+		}
+	}
+
+	got := reconcileCSR(machines)
+	if count := got.Annotations[reconcileCountAnnotation]; count != "4" {
+		t.Errorf("expected reconcile count 4, got %q", count)
+	}
+	if outcome := got.Annotations[reconcileOutcomeAnnotation]; outcome != string(reconcileOutcomeApproved) {
+		t.Errorf("expected outcome %s, got %q", reconcileOutcomeApproved, outcome)
+	}
+
+	// Annotating is off by default
+	approver.AnnotateReconcileOutcome = false
+	got = reconcileCSR(nil)
+	if count := got.Annotations[reconcileCountAnnotation]; count != "4" {
+		t.Errorf("expected reconcile count to stay 4, got %q", count)
+	}
+}
+
+func csrDecisions(t *testing.T, signerName string, decision reconcileOutcome, machineAPIGroup string) float64 {
+	metric := &dto.Metric{}
+	if err := CSRDecisions.WithLabelValues(signerName, string(decision), machineAPIGroup).Write(metric); err != nil {
+		t.Fatalf("failed to read %s %s decisions: %v", signerName, decision, err)
+	}
+	return metric.GetCounter().GetValue()
+}
+
+func TestReconcileCSRDecisions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Echo the updated CSR back
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	csr := certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageKeyEncipherment,
+				certificatesv1.UsageServerAuth,
+			},
+			Username: "system:node:test",
+			Groups: []string{
+				"system:authenticated",
+				"system:nodes",
+			},
+			Request: []byte(goodCSR),
+		},
+	}
+	machines := []machinehandlerpkg.Machine{{
+		Status: machinehandlerpkg.MachineStatus{
+			NodeRef: &corev1.ObjectReference{Name: "test"},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+				{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+				{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+				{Type: corev1.NodeExternalDNS, Address: "node1"},
+			},
+		},
+	}}
+
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewFakeClient(&configv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}),
+		NodeRestCfg:    &rest.Config{Host: server.URL},
+		Config:         ClusterMachineApproverConfig{ServingRenewal: ServingRenewal{Disabled: true}},
+	}
+
+	signer := certificatesv1.KubeletServingSignerName
+	otherSigner := certificatesv1.KubeAPIServerClientKubeletSignerName
+	approvedBefore := csrDecisions(t, signer, reconcileOutcomeApproved, "")
+	notAuthorizedBefore := csrDecisions(t, signer, reconcileOutcomeNotAuthorized, "")
+	otherBefore := csrDecisions(t, otherSigner, reconcileOutcomeApproved, "") + csrDecisions(t, otherSigner, reconcileOutcomeNotAuthorized, "")
+
+	// Not authorized without machines
+	_, _ = approver.reconcileCSR(context.Background(), csr, nil)
+	if got := csrDecisions(t, signer, reconcileOutcomeNotAuthorized, ""); got != notAuthorizedBefore+1 {
+		t.Errorf("expected %v not authorized decisions for %s, got %v", notAuthorizedBefore+1, signer, got)
+	}
+
+	if _, err := approver.reconcileCSR(context.Background(), csr, machines); err != nil {
+		t.Fatalf("failed to reconcile CSR: %v", err)
+	}
+	if got := csrDecisions(t, signer, reconcileOutcomeApproved, ""); got != approvedBefore+1 {
+		t.Errorf("expected %v approved decisions for %s, got %v", approvedBefore+1, signer, got)
+	}
+
+	// Decisions are only counted with the signer name of the CSR
+	if got := csrDecisions(t, otherSigner, reconcileOutcomeApproved, "") + csrDecisions(t, otherSigner, reconcileOutcomeNotAuthorized, ""); got != otherBefore {
+		t.Errorf("expected %v decisions for %s, got %v", otherBefore, otherSigner, got)
+	}
+}
+
+func TestReconcileCSRDecisionCorrelationID(t *testing.T) {
+	var approved certificatesv1.CertificateSigningRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Echo the updated CSR back
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &approved); err != nil {
+			t.Errorf("failed to decode approved CSR: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	// Without UID nor annotation, the correlation ID is generated.
+	csr := certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: "example.com/correlation-id",
+			Username:   "system:node:test",
+			Request:    []byte(goodCSR),
+		},
+	}
+
+	var logNames []string
+	authorized := func(_ ClusterMachineApproverConfig, req *certificatesv1.CertificateSigningRequest, _ *x509.CertificateRequest, _ *x509.CertPool) (authorizationResult, error) {
+		logNames = append(logNames, csrLogName(req), csrLogName(req))
+		return authorizationResult{Authorized: true, Method: authorizedByMachine}, nil
+	}
+
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewFakeClient(),
+		NodeRestCfg:    &rest.Config{Host: server.URL},
+	}
+	if _, err := approver.reconcileCSRWith(context.Background(), csr, authorized); err != nil {
+		t.Fatalf("failed to reconcile CSR: %v", err)
+	}
+
+	if csr.Annotations != nil {
+		t.Errorf("expected the annotations of the reconciled CSR to be left as is, got %v", csr.Annotations)
+	}
+
+	correlationID := approved.Annotations[correlationIDAnnotation]
+	if correlationID == "" {
+		t.Fatalf("expected the approved CSR to be annotated with a correlation ID")
+	}
+	expectedLogName := fmt.Sprintf("csr (correlation ID %s)", correlationID)
+	for _, logName := range logNames {
+		if logName != expectedLogName {
+			t.Errorf("expected authorization logs to identify the CSR as %q, got %q", expectedLogName, logName)
+		}
+	}
+
+	metric := &dto.Metric{}
+	if err := CSRDecisions.WithLabelValues(csr.Spec.SignerName, string(reconcileOutcomeApproved), "").Write(metric); err != nil {
+		t.Fatalf("failed to read decisions: %v", err)
+	}
+	exemplar := metric.GetCounter().GetExemplar()
+	if exemplar == nil {
+		t.Fatalf("expected the decision to be counted with an exemplar")
+	}
+	labels := map[string]string{}
+	for _, label := range exemplar.GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	if labels["correlation_id"] != correlationID {
+		t.Errorf("expected exemplar with correlation ID %q, got %v", correlationID, labels)
+	}
+}
+
+func TestReconcileCSRDecisionsMachineAPIGroup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Echo the updated CSR back
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	csr := certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeletServingSignerName,
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageKeyEncipherment,
+				certificatesv1.UsageServerAuth,
+			},
+			Username: "system:node:test",
+			Groups: []string{
+				"system:authenticated",
+				"system:nodes",
+			},
+			Request: []byte(goodCSR),
 		},
+	}
+	machine := func(apiVersion string) []machinehandlerpkg.Machine {
+		return []machinehandlerpkg.Machine{{
+			APIVersion: apiVersion,
+			Status: machinehandlerpkg.MachineStatus{
+				NodeRef: &corev1.ObjectReference{Name: "test"},
+				Addresses: []corev1.NodeAddress{
+					{Type: corev1.NodeInternalIP, Address: "127.0.0.1"},
+					{Type: corev1.NodeExternalIP, Address: "10.0.0.1"},
+					{Type: corev1.NodeInternalDNS, Address: "node1.local"},
+					{Type: corev1.NodeExternalDNS, Address: "node1"},
+				},
+			},
+		}}
+	}
+
+	approver := &CertificateApprover{
+		WorkloadClient: fake.NewFakeClient(&configv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}),
+		NodeRestCfg:    &rest.Config{Host: server.URL},
+		Config:         ClusterMachineApproverConfig{ServingRenewal: ServingRenewal{Disabled: true}},
+	}
+
+	signer := certificatesv1.KubeletServingSignerName
+	for _, apiGroup := range []string{"cluster.x-k8s.io", "machine.openshift.io"} {
+		before := csrDecisions(t, signer, reconcileOutcomeApproved, apiGroup)
+		if _, err := approver.reconcileCSR(context.Background(), csr, machine(apiGroup+"/v1beta1")); err != nil {
+			t.Fatalf("failed to reconcile CSR: %v", err)
+		}
+		if got := csrDecisions(t, signer, reconcileOutcomeApproved, apiGroup); got != before+1 {
+			t.Errorf("expected %v approved decisions by %s machines, got %v", before+1, apiGroup, got)
+		}
+	}
+}
+
+func TestReconcileCSRExternallyApproved(t *testing.T) {
+	approvedCSR := func(message string, approved time.Duration) certificatesv1.CertificateSigningRequest {
+		return certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: "csr"},
+			Spec: certificatesv1.CertificateSigningRequestSpec{
+				SignerName: certificatesv1.KubeletServingSignerName,
+			},
+			Status: certificatesv1.CertificateSigningRequestStatus{
+				Conditions: []certificatesv1.CertificateSigningRequestCondition{{
+					Type:               certificatesv1.CertificateApproved,
+					Message:            message,
+					LastTransitionTime: creationTimestamp(approved),
+				}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name string
+		csr  certificatesv1.CertificateSigningRequest
+		want uint32
+	}{
 		{
-			name:     "sub is a superset",
-			super:    []net.IP{tenDotOne, tenDotTwo},
-			sub:      []net.IP{tenDotOne, tenDotThree},
-			expected: false,
+			name: "recently approved with a foreign message",
+			csr:  approvedCSR("Approved by another approver", -10*time.Second),
+			want: 1,
 		},
 		{
-			name:     "sub is a subset with duplicates",
-			super:    []net.IP{tenDotOne, tenDotTwo},
-			sub:      []net.IP{tenDotOne, tenDotOne},
-			expected: true,
+			name: "recently approved by the machine approver",
+			csr:  approvedCSR(csrConditionApproveMessage, -10*time.Second),
 		},
 		{
-			name:     "sub is a subset when cidrs are included",
-			cidrs:    []*net.IPNet{tenNoughtSlash24},
-			super:    []net.IP{tenOneThree},
-			sub:      []net.IP{tenDotOne, tenOneThree},
-			expected: true,
+			name: "approved with a foreign message long ago",
+			csr:  approvedCSR("Approved by another approver", -time.Hour),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if equal := subsetIPAddresses(tt.cidrs, tt.super, tt.sub); equal != tt.expected {
-				t.Errorf("%v subset of %v :: wanted %v, got %v",
-					tt.sub, tt.super, tt.expected, equal)
+			approver := &CertificateApprover{WorkloadClient: fake.NewFakeClient()}
+
+			before := atomic.LoadUint32(&ExternallyApprovedCSRs)
+			// The CSR is reconciled again, e.g. on a resync, but only counted once.
+			for i := 0; i < 2; i++ {
+				if _, err := approver.reconcileCSR(context.Background(), tt.csr, nil); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if got := atomic.LoadUint32(&ExternallyApprovedCSRs) - before; got != tt.want {
+				t.Errorf("expected %d externally approved CSRs, got %d", tt.want, got)
 			}
 		})
 	}
 }
 
-func TestCsrSANs(t *testing.T) {
-	uri, _ := url.Parse("http://example.com")
-	cr := &x509.CertificateRequest{
-		DNSNames:       []string{"test.local", "localhost"},
-		EmailAddresses: []string{"exaple@test.com"},
-		IPAddresses:    []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("10.0.0.1")},
-		URIs:           []*url.URL{uri},
+func TestOnlyReconcileOutcomeChanged(t *testing.T) {
+	oldCSR := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "csr", ResourceVersion: "1"},
 	}
 
-	sans := sets.NewString(csrSANs(cr)...)
-	if !sans.HasAll(
-		"http://example.com", "test.local",
-		"localhost", "exaple@test.com",
-		"127.0.0.1", "10.0.0.1") {
-		t.Errorf("Not all SANs were collected")
+	annotated := oldCSR.DeepCopy()
+	annotated.ResourceVersion = "2"
+	annotated.Annotations = map[string]string{
+		reconcileOutcomeAnnotation: string(reconcileOutcomeNotAuthorized),
+		reconcileCountAnnotation:   "1",
 	}
 
-	if len(csrSANs(nil)) > 0 {
-		t.Errorf("No SANs are expected from nil")
-	}
-}
+	approved := annotated.DeepCopy()
+	approved.ResourceVersion = "3"
+	approved.Status.Conditions = []certificatesv1.CertificateSigningRequestCondition{{Type: certificatesv1.CertificateApproved}}
 
-func TestCertSANs(t *testing.T) {
-	uri, _ := url.Parse("http://example.com")
-	cert := &x509.Certificate{
-		DNSNames:       []string{"test.local", "localhost"},
-		EmailAddresses: []string{"exaple@test.com"},
-		IPAddresses:    []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("10.0.0.1")},
-		URIs:           []*url.URL{uri},
+	if !onlyReconcileOutcomeChanged(oldCSR, annotated) {
+		t.Error("expected an update of the reconcile outcome only to be ignored")
 	}
-
-	sans := sets.NewString(certSANs(cert)...)
-	if !sans.HasAll(
-		"http://example.com", "test.local",
-		"localhost", "exaple@test.com",
-		"127.0.0.1", "10.0.0.1") {
-		t.Errorf("Not all SANs were collected")
+	if onlyReconcileOutcomeChanged(annotated, approved) {
+		t.Error("expected an approval not to be ignored")
 	}
-
-	if len(certSANs(nil)) > 0 {
-		t.Errorf("No SANs are expected from nil")
+	if onlyReconcileOutcomeChanged(oldCSR, oldCSR.DeepCopy()) {
+		t.Error("expected a resync not to be ignored")
 	}
 }
-func assertNoChange(t *testing.T, a, b []string, f func(*testing.T)) {
-	aCopy := make([]string, len(a))
-	bCopy := make([]string, len(b))
 
-	copy(aCopy, a)
-	copy(bCopy, b)
+func assertNoChange(t *testing.T, a, b []string, f func(*testing.T)) {
+	// Copies keep nil slices nil, so that nil and empty slices compare as-is
+	var aCopy, bCopy []string
+	if a != nil {
+		aCopy = make([]string, len(a))
+		copy(aCopy, a)
+	}
+	if b != nil {
+		bCopy = make([]string, len(b))
+		copy(bCopy, b)
+	}
 
 	f(t)
 
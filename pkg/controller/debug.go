@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"k8s.io/klog/v2"
+)
+
+// DebugConfigPath is the path the effective approver configuration is served on.
+const DebugConfigPath = "/debug/config"
+
+// effectiveConfig is the configuration the approver is running with.
+type effectiveConfig struct {
+	ConfigPath                string                       `json:"configPath"`
+	Config                    ClusterMachineApproverConfig `json:"config"`
+	APIGroupVersions          []string                     `json:"apiGroupVersions"`
+	MachineNamespaces         []string                     `json:"machineNamespaces"`
+	StartupDelay              string                       `json:"startupDelay"`
+	StartupObservePeriod      string                       `json:"startupObservePeriod"`
+	MachineListTimeout        string                       `json:"machineListTimeout"`
+	MachineAddressWaitTimeout string                       `json:"machineAddressWaitTimeout"`
+}
+
+// ConfigHandler returns a read-only handler serving the effective approver
+// configuration as JSON, to help diagnosing how an approver was configured.
+func (m *CertificateApprover) ConfigHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		config := effectiveConfig{
+			ConfigPath:                m.ConfigPath,
+			Config:                    m.config(),
+			APIGroupVersions:          []string{},
+			MachineNamespaces:         []string{},
+			StartupDelay:              m.StartupDelay.String(),
+			StartupObservePeriod:      m.StartupObservePeriod.String(),
+			MachineListTimeout:        m.MachineListTimeout.String(),
+			MachineAddressWaitTimeout: m.MachineAddressWaitTimeout.String(),
+		}
+		for _, apiGroupVersion := range m.apiGroupVersions() {
+			// Same format as the --api-group-version option, the version is omitted when it is discovered
+			if apiGroupVersion.Version == "" {
+				config.APIGroupVersions = append(config.APIGroupVersions, apiGroupVersion.Group)
+			} else {
+				config.APIGroupVersions = append(config.APIGroupVersions, apiGroupVersion.String())
+			}
+		}
+		config.MachineNamespaces = append(config.MachineNamespaces, m.MachineNamespaces...)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(config); err != nil {
+			klog.Errorf("Failed to write effective config: %v", err)
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// externalApprovalCache remembers the recently approved CSRs already counted
+// as approved by another approver, as an approved CSR may be reconciled
+// several times, e.g. on every resync. Entries are dropped once the CSR is no
+// longer recently approved, as it is not counted anymore.
+type externalApprovalCache struct {
+	mu      sync.Mutex
+	counted map[types.UID]time.Time
+}
+
+// firstSeen returns whether the CSR is observed for the first time, recording
+// it otherwise.
+func (c *externalApprovalCache) firstSeen(csr certificatesv1.CertificateSigningRequest) bool {
+	key := csr.UID
+	if key == "" {
+		key = types.UID(csr.Name)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counted == nil {
+		c.counted = map[types.UID]time.Time{}
+	}
+
+	currentTime := now()
+	for uid, seen := range c.counted {
+		if currentTime.Sub(seen) > maxApprovedDelta+maxMachineClockSkew {
+			delete(c.counted, uid)
+		}
+	}
+
+	if _, ok := c.counted[key]; ok {
+		return false
+	}
+	c.counted[key] = currentTime
+	return true
+}
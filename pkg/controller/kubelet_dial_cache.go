@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// maxKubeletDialFailures bounds the nodes remembered by kubeletDialFailureCache.
+const maxKubeletDialFailures = 1024
+
+// kubeletDialFailureCache remembers the nodes whose kubelet recently could not
+// be dialed, so that their serving CSRs are authorized with machines right
+// away rather than waiting on the dial again, e.g. during a rolling reboot.
+// A nil cache remembers nothing.
+type kubeletDialFailureCache struct {
+	mu       sync.Mutex
+	failures map[string]time.Time
+}
+
+// failedWithin returns whether dialing the kubelet of the node failed within
+// ttl. The cache is disabled when ttl is not positive.
+func (c *kubeletDialFailureCache) failedWithin(nodeName string, ttl time.Duration) bool {
+	if c == nil || ttl <= 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	failed, ok := c.failures[nodeName]
+	if !ok {
+		return false
+	}
+	if now().Sub(failed) > ttl {
+		delete(c.failures, nodeName)
+		return false
+	}
+	return true
+}
+
+// add records a failure to dial the kubelet of the node. Once the cache is
+// full, failures older than ttl are dropped first, then the oldest failure.
+func (c *kubeletDialFailureCache) add(nodeName string, ttl time.Duration) {
+	if c == nil || ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.failures == nil {
+		c.failures = map[string]time.Time{}
+	}
+
+	currentTime := now()
+
+	if _, ok := c.failures[nodeName]; !ok && len(c.failures) >= maxKubeletDialFailures {
+		var oldestNode string
+		var oldest time.Time
+		for node, failed := range c.failures {
+			if currentTime.Sub(failed) > ttl {
+				delete(c.failures, node)
+			} else if oldestNode == "" || failed.Before(oldest) {
+				oldestNode, oldest = node, failed
+			}
+		}
+		if len(c.failures) >= maxKubeletDialFailures {
+			delete(c.failures, oldestNode)
+		}
+	}
+
+	c.failures[nodeName] = currentTime
+}
+
+// reset forgets all failures, e.g. once the kubelet CA changed.
+func (c *kubeletDialFailureCache) reset() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures = nil
+}
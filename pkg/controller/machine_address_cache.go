@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	machinehandlerpkg "github.com/openshift/cluster-machine-approver/pkg/machinehandler"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// machineAddressCache remembers the last non-empty addresses observed for each
+// machine so that a transient status update clearing them does not prevent
+// approvals.
+type machineAddressCache struct {
+	mu      sync.Mutex
+	entries map[types.NamespacedName]machineAddressEntry
+}
+
+type machineAddressEntry struct {
+	addresses []corev1.NodeAddress
+	observed  time.Time
+}
+
+// apply records the addresses of the given machines and fills in the last
+// observed addresses for machines currently reporting none, as long as they
+// were observed within ttl. The cache is disabled when ttl is not positive.
+func (c *machineAddressCache) apply(machines []machinehandlerpkg.Machine, ttl time.Duration) []machinehandlerpkg.Machine {
+	if ttl <= 0 {
+		return machines
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[types.NamespacedName]machineAddressEntry{}
+	}
+
+	currentTime := now()
+
+	for key, entry := range c.entries {
+		if currentTime.Sub(entry.observed) > ttl {
+			delete(c.entries, key)
+		}
+	}
+
+	result := make([]machinehandlerpkg.Machine, len(machines))
+	for i, machine := range machines {
+		key := types.NamespacedName{Namespace: machine.Namespace, Name: machine.Name}
+
+		if len(machine.Status.Addresses) > 0 {
+			c.entries[key] = machineAddressEntry{
+				addresses: machine.Status.Addresses,
+				observed:  currentTime,
+			}
+		} else if entry, ok := c.entries[key]; ok {
+			klog.Infof("Machine %s reports no addresses, using addresses observed at %s", key, entry.observed)
+			machine.Status.Addresses = entry.addresses
+		}
+
+		result[i] = machine
+	}
+
+	return result
+}
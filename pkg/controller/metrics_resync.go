@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// defaultMetricsResyncInterval is how often the metrics are refreshed when MetricsResyncInterval is unset.
+	defaultMetricsResyncInterval = time.Minute
+	// metricsResyncJitter spreads the refreshes, as a factor of the interval.
+	metricsResyncJitter = 0.1
+	// metricsResyncLogName identifies the refreshes in the logs shared with reconciles.
+	metricsResyncLogName = "metrics resync"
+)
+
+// resyncMetrics periodically refreshes the pending CSRs metrics until the
+// context is done. They are otherwise only updated by reconciles, and would go
+// stale while no CSR event fires, e.g. with a CSR stuck pending.
+func (m *CertificateApprover) resyncMetrics(ctx context.Context) error {
+	interval := m.MetricsResyncInterval
+	if interval <= 0 {
+		interval = defaultMetricsResyncInterval
+	}
+
+	wait.JitterUntilWithContext(ctx, m.recordMetrics, interval, metricsResyncJitter, false)
+	return nil
+}
+
+// recordMetrics updates the pending CSRs metrics from uncached CSRs, and the
+// current machines and nodes. Nothing is approved.
+func (m *CertificateApprover) recordMetrics(ctx context.Context) {
+	csrs, err := m.getCSRsUncached(ctx)
+	if err != nil {
+		klog.Errorf("%v: Failed to list CSRs: %v", metricsResyncLogName, err)
+		return
+	}
+
+	machines, err := m.listMachines(ctx, metricsResyncLogName)
+	if err != nil {
+		return
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := m.WorkloadClient.List(ctx, nodes); err != nil {
+		klog.Errorf("%v: Failed to list Nodes: %v", metricsResyncLogName, err)
+		return
+	}
+
+	recordLimits(m.config(), machines, nodes, csrs)
+}
+
+// getCSRsUncached lists the node CSRs considered, bypassing the cache.
+func (m *CertificateApprover) getCSRsUncached(ctx context.Context) ([]certificatesv1.CertificateSigningRequest, error) {
+	if m.listCSRsUncached != nil {
+		return m.listCSRsUncached(ctx)
+	}
+	return listNodeCSRsUncached(ctx, m.NodeRestCfg, m.csrSelector())
+}
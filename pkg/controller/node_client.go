@@ -40,6 +40,47 @@ var kubeletClientUsages = []certificatesv1.KeyUsage{
 	certificatesv1.UsageClientAuth,
 }
 
+// hasClientAndServerUsages returns whether the CSR requests both client auth
+// and server auth usages.
+func hasClientAndServerUsages(csr *certificatesv1.CertificateSigningRequest) bool {
+	var client, server bool
+	for _, u := range csr.Spec.Usages {
+		switch u {
+		case certificatesv1.UsageClientAuth:
+			client = true
+		case certificatesv1.UsageServerAuth:
+			server = true
+		}
+	}
+	return client && server
+}
+
+// signerUsagesMismatch returns why the usages requested by the CSR do not fit
+// its signer, or an empty string when they do. Kubelet serving certs must
+// request server auth, and kubelet client certs must request client auth.
+func signerUsagesMismatch(csr *certificatesv1.CertificateSigningRequest) string {
+	switch csr.Spec.SignerName {
+	case certificatesv1.KubeletServingSignerName:
+		if !hasUsage(csr, certificatesv1.UsageServerAuth) {
+			return reasonServingSignerWithoutServerAuth
+		}
+	case certificatesv1.KubeAPIServerClientKubeletSignerName:
+		if !hasUsage(csr, certificatesv1.UsageClientAuth) {
+			return reasonClientSignerWithoutClientAuth
+		}
+	}
+	return ""
+}
+
+func hasUsage(csr *certificatesv1.CertificateSigningRequest, usage certificatesv1.KeyUsage) bool {
+	for _, u := range csr.Spec.Usages {
+		if u == usage {
+			return true
+		}
+	}
+	return false
+}
+
 func isNodeClientCert(csr *certificatesv1.CertificateSigningRequest, x509cr *x509.CertificateRequest) bool {
 	if !reflect.DeepEqual([]string{"system:nodes"}, x509cr.Subject.Organization) {
 		return false
@@ -16,32 +16,75 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var (
 	ErrApiGroupNotFound = errors.New("failed to find API group")
+	// ErrApiVersionNotServed is returned when an explicit version of an API group is not served
+	ErrApiVersionNotServed = errors.New("API version is not served")
+	// ErrAmbiguousMachineMatch is returned when more than one machine matches a node
+	ErrAmbiguousMachineMatch = errors.New("multiple matching machines found")
+)
+
+const (
+	// machineAPIGroup is the API group of machine-api machines
+	machineAPIGroup = "machine.openshift.io"
+	// clusterAPIGroup is the API group of cluster-api machines
+	clusterAPIGroup = "cluster.x-k8s.io"
 )
 
 type MachineHandler struct {
-	Client    client.Client
-	Config    *rest.Config
-	Ctx       context.Context
-	Namespace string
+	Client client.Client
+	Config *rest.Config
+	Ctx    context.Context
+	// Namespaces restricts the listed machines, all namespaces are listed when empty
+	Namespaces []string
+	// ProviderAddressesPath is the field path of addresses reported by the
+	// provider of machine-api machines, e.g. in their provider status, before
+	// their status addresses reflect them. When set, these addresses are merged
+	// into the status addresses.
+	ProviderAddressesPath []string
+	// InfrastructureRefAddresses merges the status addresses of the
+	// infrastructure machine referenced by cluster-api machines, e.g. an
+	// AWSMachine, into their status addresses. This costs a get per machine.
+	InfrastructureRefAddresses bool
 }
 
 type Machine struct {
+	// APIVersion is the API group and version the machine was listed in
+	APIVersion        string `json:"apiVersion,omitempty"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              MachineSpec   `json:"spec,omitempty"`
 	Status            MachineStatus `json:"status,omitempty"`
 }
+type MachineSpec struct {
+	ProviderID *string `json:"providerID,omitempty"`
+	// InfrastructureRef references the infrastructure machine of cluster-api machines
+	InfrastructureRef *corev1.ObjectReference `json:"infrastructureRef,omitempty"`
+}
 type MachineStatus struct {
 	NodeRef   *corev1.ObjectReference `json:"nodeRef,omitempty"`
 	Addresses []corev1.NodeAddress    `json:"addresses,omitempty"`
+	// Phase is the lifecycle phase of the machine, e.g. Running or Deleting
+	Phase string `json:"phase,omitempty"`
+}
+
+// APIGroup returns the API group of the machine, e.g. machine.openshift.io
+func (m Machine) APIGroup() string {
+	gv, err := schema.ParseGroupVersion(m.APIVersion)
+	if err != nil {
+		return ""
+	}
+	return gv.Group
 }
 
 // ListMachines list all machines using given client
 func (m *MachineHandler) ListMachines(apiGroupVersion schema.GroupVersion) ([]Machine, error) {
-	apiVersion, err := m.getAPIGroupPreferredVersion(apiGroupVersion.Group)
+	// we keep the user provided version if it is served,
+	// if not set, the preferred version is set from discovery
+	apiGroupVersion, err := m.resolveAPIGroupVersion(apiGroupVersion)
 	if err != nil {
 		// when MachineAPI capability is disabled we ignore error
 		// that we can't find api version/group for given group
@@ -53,12 +96,6 @@ func (m *MachineHandler) ListMachines(apiGroupVersion schema.GroupVersion) ([]Ma
 		return nil, err
 	}
 
-	// we set group version to user provided one
-	// if not, set preffered version from discovery above
-	if apiGroupVersion.Version == "" {
-		apiGroupVersion.Version = apiVersion
-	}
-
 	// Detect if machine api present in the cluster
 	// If it's not then return empty array because
 	// there are no machines present
@@ -70,18 +107,63 @@ func (m *MachineHandler) ListMachines(apiGroupVersion schema.GroupVersion) ([]Ma
 		return nil, nil
 	}
 
+	namespaces := m.Namespaces
+	if len(namespaces) == 0 {
+		// empty namespace lists machines in all namespaces
+		namespaces = []string{""}
+	}
+
+	machines := []Machine{}
+
+	for _, namespace := range namespaces {
+		namespaceMachines, err := m.listMachinesInNamespace(apiGroupVersion, namespace)
+		if err != nil {
+			return nil, err
+		}
+		machines = append(machines, namespaceMachines...)
+	}
+
+	return machines, nil
+}
+
+// listMachinesInNamespace list machines in given namespace, or in all namespaces if it is empty
+func (m *MachineHandler) listMachinesInNamespace(apiGroupVersion schema.GroupVersion, namespace string) ([]Machine, error) {
 	unstructuredMachineList := &unstructured.UnstructuredList{}
 	unstructuredMachineList.SetGroupVersionKind(apiGroupVersion.WithKind("MachineList"))
 	listOpts := make([]client.ListOption, 0)
-	if m.Namespace != "" {
-		listOpts = append(listOpts, client.InNamespace(m.Namespace))
+	if namespace != "" {
+		listOpts = append(listOpts, client.InNamespace(namespace))
 	}
 	if err := m.Client.List(m.Ctx, unstructuredMachineList, listOpts...); err != nil {
 		return nil, err
 	}
 
+	var providerAddressesPath []string
+	if apiGroupVersion.Group == machineAPIGroup {
+		providerAddressesPath = m.ProviderAddressesPath
+	}
+
 	machines := []Machine{}
 
+	for _, obj := range unstructuredMachineList.Items {
+		machine, err := decodeMachine(obj.Object, providerAddressesPath)
+		if err != nil {
+			return nil, err
+		}
+		if m.InfrastructureRefAddresses && apiGroupVersion.Group == clusterAPIGroup {
+			m.mergeInfrastructureRefAddresses(&machine)
+		}
+		machines = append(machines, machine)
+	}
+
+	return machines, nil
+}
+
+// decodeMachine decodes an unstructured machine. When providerAddressesPath
+// is set, the addresses found at it are merged into the status addresses.
+// Invalid provider addresses are logged and ignored, keeping the status
+// addresses, so that a single malformed machine does not fail the list.
+func decodeMachine(obj map[string]interface{}, providerAddressesPath []string) (Machine, error) {
 	stringToTimeHook := func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
 		if f.Kind() == reflect.String && t == reflect.TypeOf(metav1.Time{}) {
 			t, err := time.Parse(time.RFC3339, data.(string))
@@ -90,49 +172,155 @@ func (m *MachineHandler) ListMachines(apiGroupVersion schema.GroupVersion) ([]Ma
 		return data, nil
 	}
 
-	for _, obj := range unstructuredMachineList.Items {
-		machine := Machine{}
-		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
-			TagName:    "json",
-			Result:     &machine,
-			DecodeHook: stringToTimeHook,
-		})
-		if err != nil {
-			return nil, err
-		}
-		err = decoder.Decode(obj.Object)
-		if err != nil {
-			return nil, err
+	machine := Machine{}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:    "json",
+		Result:     &machine,
+		DecodeHook: stringToTimeHook,
+	})
+	if err != nil {
+		return Machine{}, err
+	}
+	if err := decoder.Decode(obj); err != nil {
+		return Machine{}, err
+	}
+
+	if len(providerAddressesPath) == 0 {
+		return machine, nil
+	}
+
+	providerAddresses, err := decodeAddresses(obj, providerAddressesPath)
+	if err != nil {
+		klog.Errorf("Machine %s/%s: ignoring invalid provider addresses at %s: %v", machine.Namespace, machine.Name, strings.Join(providerAddressesPath, "."), err)
+		return machine, nil
+	}
+	machine.Status.Addresses = mergeAddresses(machine.Status.Addresses, providerAddresses)
+
+	return machine, nil
+}
+
+// mergeInfrastructureRefAddresses merges the status addresses of the
+// infrastructure machine referenced by the machine into its status addresses.
+// Machines without a reference, or whose infrastructure machine cannot be
+// read, e.g. as it does not exist yet or is forbidden, are left unchanged, so
+// that a single machine does not fail the list. The infrastructure machine is
+// always in the namespace of the machine, as cluster-api requires, a
+// reference to another namespace is ignored.
+func (m *MachineHandler) mergeInfrastructureRefAddresses(machine *Machine) {
+	ref := machine.Spec.InfrastructureRef
+	if ref == nil || ref.Name == "" {
+		return
+	}
+	if ref.Namespace != "" && ref.Namespace != machine.Namespace {
+		klog.Errorf("Machine %s/%s: ignoring infrastructure machine %s %s/%s outside of the namespace of the machine", machine.Namespace, machine.Name, ref.Kind, ref.Namespace, ref.Name)
+		return
+	}
+
+	infraMachine := &unstructured.Unstructured{}
+	infraMachine.SetGroupVersionKind(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+	if err := m.Client.Get(m.Ctx, client.ObjectKey{Namespace: machine.Namespace, Name: ref.Name}, infraMachine); err != nil {
+		if !k8serror.IsNotFound(err) {
+			klog.Errorf("Machine %s/%s: ignoring addresses of infrastructure machine %s %s/%s: %v", machine.Namespace, machine.Name, ref.Kind, machine.Namespace, ref.Name, err)
 		}
-		machines = append(machines, machine)
+		return
 	}
 
-	return machines, nil
+	infraAddresses, err := decodeAddresses(infraMachine.Object, []string{"status", "addresses"})
+	if err != nil {
+		klog.Errorf("Machine %s/%s: ignoring invalid addresses of infrastructure machine %s %s/%s: %v", machine.Namespace, machine.Name, ref.Kind, machine.Namespace, ref.Name, err)
+		return
+	}
+	machine.Status.Addresses = mergeAddresses(machine.Status.Addresses, infraAddresses)
+}
+
+// decodeAddresses decodes the addresses found at the field path of obj, nil
+// when there are none.
+func decodeAddresses(obj map[string]interface{}, path []string) ([]corev1.NodeAddress, error) {
+	rawAddresses, found, err := unstructured.NestedSlice(obj, path...)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	var addresses []corev1.NodeAddress
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName: "json",
+		Result:  &addresses,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(rawAddresses); err != nil {
+		return nil, err
+	}
+	return addresses, nil
 }
 
-// getAPIGroupPreferredVersion get preferred API version using API group
-func (m *MachineHandler) getAPIGroupPreferredVersion(apiGroup string) (string, error) {
+// mergeAddresses returns the addresses with the extra addresses not already
+// among them appended.
+func mergeAddresses(addresses, extra []corev1.NodeAddress) []corev1.NodeAddress {
+	for _, address := range extra {
+		if address.Address == "" {
+			continue
+		}
+		found := false
+		for _, existing := range addresses {
+			if existing == address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// resolveAPIGroupVersion resolve API group version using discovery
+func (m *MachineHandler) resolveAPIGroupVersion(apiGroupVersion schema.GroupVersion) (schema.GroupVersion, error) {
 	if m.Config == nil {
-		return "", fmt.Errorf("machine handler config can't be nil")
+		return schema.GroupVersion{}, fmt.Errorf("machine handler config can't be nil")
 	}
 
 	managementDiscoveryClient, err := discovery.NewDiscoveryClientForConfig(m.Config)
 	if err != nil {
-		return "", fmt.Errorf("create discovery client failed: %v", err)
+		return schema.GroupVersion{}, fmt.Errorf("create discovery client failed: %v", err)
 	}
 
-	groupList, err := managementDiscoveryClient.ServerGroups()
+	return ResolveAPIGroupVersion(managementDiscoveryClient, apiGroupVersion)
+}
+
+// ResolveAPIGroupVersion resolves the version of an API group using discovery.
+// When the version is omitted, the preferred version served for the group is
+// used. When given, an error is returned if the version is not served.
+func ResolveAPIGroupVersion(discoveryClient discovery.ServerGroupsInterface, apiGroupVersion schema.GroupVersion) (schema.GroupVersion, error) {
+	groupList, err := discoveryClient.ServerGroups()
 	if err != nil {
-		return "", fmt.Errorf("failed to get ServerGroups: %v", err)
+		return schema.GroupVersion{}, fmt.Errorf("failed to get ServerGroups: %v", err)
 	}
 
 	for _, group := range groupList.Groups {
-		if group.Name == apiGroup {
-			return group.PreferredVersion.Version, nil
+		if group.Name != apiGroupVersion.Group {
+			continue
+		}
+
+		if apiGroupVersion.Version == "" {
+			apiGroupVersion.Version = group.PreferredVersion.Version
+			return apiGroupVersion, nil
+		}
+
+		servedVersions := make([]string, 0, len(group.Versions))
+		for _, version := range group.Versions {
+			if version.Version == apiGroupVersion.Version {
+				return apiGroupVersion, nil
+			}
+			servedVersions = append(servedVersions, version.Version)
 		}
+
+		return schema.GroupVersion{}, fmt.Errorf("%w: %s, served versions are: %s", ErrApiVersionNotServed, apiGroupVersion, strings.Join(servedVersions, ", "))
 	}
 
-	return "", ErrApiGroupNotFound
+	return schema.GroupVersion{}, ErrApiGroupNotFound
 }
 
 func isMachineCRDPresent(cfg *rest.Config, groupVersion schema.GroupVersion) (bool, error) {
@@ -159,23 +347,96 @@ func isMachineCRDPresent(cfg *rest.Config, groupVersion schema.GroupVersion) (bo
 
 // FindMatchingMachineFromInternalDNS find matching machine for node using internal DNS
 func FindMatchingMachineFromInternalDNS(machines []Machine, nodeName string) (*Machine, error) {
+	return FindMatchingMachineFromInternalDNSWithDomainSuffix(machines, nodeName, "")
+}
+
+// FindMatchingMachineFromInternalDNSWithDomainSuffix find matching machine for node using internal DNS.
+// When domainSuffix is set, names match whether or not either of them is qualified with the domain,
+// e.g. node name node1.example.com matches internal DNS node1 with domain suffix example.com.
+func FindMatchingMachineFromInternalDNSWithDomainSuffix(machines []Machine, nodeName, domainSuffix string) (*Machine, error) {
+	nodeName = trimDomainSuffix(nodeName, domainSuffix)
+
+	var matches []Machine
 	for _, machine := range machines {
 		for _, address := range machine.Status.Addresses {
-			if corev1.NodeAddressType(address.Type) == corev1.NodeInternalDNS && strings.EqualFold(strings.TrimSuffix(address.Address, "."), nodeName) {
-				return &machine, nil
+			if corev1.NodeAddressType(address.Type) == corev1.NodeInternalDNS && strings.EqualFold(trimDomainSuffix(strings.TrimSuffix(address.Address, "."), domainSuffix), nodeName) {
+				matches = append(matches, machine)
+				break
 			}
 		}
 	}
-	return nil, fmt.Errorf("matching machine not found")
+	return singleMatchingMachine(matches)
+}
+
+// trimDomainSuffix removes the domain suffix from the name, ignoring case.
+// The name is returned unchanged when the suffix is empty or does not match.
+func trimDomainSuffix(name, domainSuffix string) string {
+	domainSuffix = strings.TrimSuffix(strings.TrimPrefix(domainSuffix, "."), ".")
+	if domainSuffix == "" {
+		return name
+	}
+
+	suffix := "." + domainSuffix
+	if len(name) > len(suffix) && strings.EqualFold(name[len(name)-len(suffix):], suffix) {
+		return name[:len(name)-len(suffix)]
+	}
+	return name
 }
 
 // FindMatchingMachineFromNodeRef find matching machine for node using node ref
 func FindMatchingMachineFromNodeRef(machines []Machine, nodeName string) (*Machine, error) {
+	var matches []Machine
 	for _, machine := range machines {
 		if machine.Status.NodeRef != nil && machine.Status.NodeRef.Name == nodeName {
-			return &machine, nil
+			matches = append(matches, machine)
 		}
+	}
+	return singleMatchingMachine(matches)
+}
+
+// FindMatchingMachineFromProviderID find matching machine for node using the provider ID of the node
+func FindMatchingMachineFromProviderID(machines []Machine, providerID string) (*Machine, error) {
+	if providerID == "" {
+		return nil, fmt.Errorf("node has no provider ID")
+	}
+
+	var matches []Machine
+	for _, machine := range machines {
+		if machine.Spec.ProviderID != nil && *machine.Spec.ProviderID == providerID {
+			matches = append(matches, machine)
+		}
+	}
+	return singleMatchingMachine(matches)
+}
+
+// FindMatchingMachineFromLabel find matching machine for node using the value of the label key
+func FindMatchingMachineFromLabel(machines []Machine, labelKey, nodeName string) (*Machine, error) {
+	if labelKey == "" || nodeName == "" {
+		return nil, fmt.Errorf("matching machine not found")
+	}
+
+	var matches []Machine
+	for _, machine := range machines {
+		if value, ok := machine.Labels[labelKey]; ok && value == nodeName {
+			matches = append(matches, machine)
+		}
+	}
+	return singleMatchingMachine(matches)
+}
+
+// singleMatchingMachine returns the only machine in matches, or an error if
+// there is none or the match is ambiguous
+func singleMatchingMachine(matches []Machine) (*Machine, error) {
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("matching machine not found")
+	case 1:
+		return &matches[0], nil
+	}
 
+	names := make([]string, 0, len(matches))
+	for _, machine := range matches {
+		names = append(names, machine.Namespace+"/"+machine.Name)
 	}
-	return nil, fmt.Errorf("matching machine not found")
+	return nil, fmt.Errorf("%w: %s", ErrAmbiguousMachineMatch, strings.Join(names, ", "))
 }
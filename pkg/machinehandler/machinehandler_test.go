@@ -3,16 +3,22 @@ package machinehandler
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"net/http"
+	"reflect"
 	"strings"
 	"testing"
 
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 // fakeMachineRoundTripper helps to construct fake rest client to handle /api & /apis requests
@@ -98,7 +104,9 @@ func createUnstructuredMachine(apiVersion, name, namespace, ip, nodeName string)
 				"name":      name,
 				"namespace": namespace,
 			},
-			"spec": map[string]interface{}{},
+			"spec": map[string]interface{}{
+				"providerID": "aws:///" + name,
+			},
 			"status": map[string]interface{}{
 				"addresses": []interface{}{
 					map[string]interface{}{
@@ -126,11 +134,11 @@ func Test_authorizeCSR(t *testing.T) {
 	ocpMachine2 := createUnstructuredMachine("machine.openshift.io/v1beta1", "ocp-machine2", "ocp-machine2", "10.0.172.124", "ip-10-0-172-124.ec2.internal")
 	cl := fake.NewClientBuilder().WithObjects(capiMachine1, capiMachine2, ocpMachine1, ocpMachine2).Build()
 	type args struct {
-		apiGroup  string
-		client    client.Client
-		config    *rest.Config
-		ctx       context.Context
-		namespace string
+		apiGroup   string
+		client     client.Client
+		config     *rest.Config
+		ctx        context.Context
+		namespaces []string
 	}
 
 	tests := []struct {
@@ -147,8 +155,8 @@ func Test_authorizeCSR(t *testing.T) {
 				config: &rest.Config{
 					Transport: fakeMachineRoundTripper{},
 				},
-				ctx:       context.TODO(),
-				namespace: "capi-machine1",
+				ctx:        context.TODO(),
+				namespaces: []string{"capi-machine1"},
 			},
 			wantErr:          false,
 			wantMachineNames: []string{"capi-machine1"},
@@ -161,20 +169,34 @@ func Test_authorizeCSR(t *testing.T) {
 				config: &rest.Config{
 					Transport: fakeMachineRoundTripper{},
 				},
-				ctx:       context.TODO(),
-				namespace: "",
+				ctx:        context.TODO(),
+				namespaces: nil,
 			},
 			wantErr:          false,
 			wantMachineNames: []string{"ocp-machine1", "ocp-machine2"},
 		},
+		{
+			name: "should list cluster-api machines in multiple namespaces",
+			args: args{
+				apiGroup: "cluster.x-k8s.io",
+				client:   cl,
+				config: &rest.Config{
+					Transport: fakeMachineRoundTripper{},
+				},
+				ctx:        context.TODO(),
+				namespaces: []string{"capi-machine2", "capi-machine1", "ocp-machine1"},
+			},
+			wantErr:          false,
+			wantMachineNames: []string{"capi-machine2", "capi-machine1"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			handler := MachineHandler{
-				Client:    tt.args.client,
-				Config:    tt.args.config,
-				Ctx:       tt.args.ctx,
-				Namespace: tt.args.namespace,
+				Client:     tt.args.client,
+				Config:     tt.args.config,
+				Ctx:        tt.args.ctx,
+				Namespaces: tt.args.namespaces,
 			}
 			machines, err := handler.ListMachines(schema.GroupVersion{Group: tt.args.apiGroup})
 			if (err != nil) != tt.wantErr {
@@ -189,6 +211,631 @@ func Test_authorizeCSR(t *testing.T) {
 					t.Errorf("unexpected machines returned. want machine names: %v, got machines: %v.", tt.wantMachineNames, machines)
 					break
 				}
+				if m.Spec.ProviderID == nil || *m.Spec.ProviderID != "aws:///"+m.Name {
+					t.Errorf("unexpected provider ID for machine %s: %v", m.Name, m.Spec.ProviderID)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeMachine(t *testing.T) {
+	newMachine := func() map[string]interface{} {
+		obj := createUnstructuredMachine("machine.openshift.io/v1beta1", "machine1", "ns1", "10.0.0.1", "node1").Object
+		obj["status"].(map[string]interface{})["providerStatus"] = map[string]interface{}{
+			"addresses": []interface{}{
+				map[string]interface{}{
+					"address": "10.0.0.1",
+					"type":    "InternalIP",
+				},
+				map[string]interface{}{
+					"address": "192.168.0.1",
+					"type":    "InternalIP",
+				},
+				map[string]interface{}{
+					"address": "node1.example.com",
+					"type":    "InternalDNS",
+				},
+			},
+		}
+		obj["status"].(map[string]interface{})["phase"] = "Running"
+		return obj
+	}
+	statusAddresses := []corev1.NodeAddress{
+		{Type: corev1.NodeInternalDNS, Address: "node1"},
+		{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+	}
+
+	tests := []struct {
+		name          string
+		obj           map[string]interface{}
+		path          []string
+		wantAddresses []corev1.NodeAddress
+		wantErr       bool
+	}{
+		{
+			name:          "without path",
+			obj:           newMachine(),
+			wantAddresses: statusAddresses,
+		},
+		{
+			name: "with provider status addresses",
+			obj:  newMachine(),
+			path: []string{"status", "providerStatus", "addresses"},
+			wantAddresses: append(append([]corev1.NodeAddress{}, statusAddresses...),
+				corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "192.168.0.1"},
+				corev1.NodeAddress{Type: corev1.NodeInternalDNS, Address: "node1.example.com"},
+			),
+		},
+		{
+			name:          "with missing path",
+			obj:           newMachine(),
+			path:          []string{"status", "providerStatus", "ips"},
+			wantAddresses: statusAddresses,
+		},
+		{
+			name:          "with path not pointing at a list",
+			obj:           newMachine(),
+			path:          []string{"status", "providerStatus"},
+			wantAddresses: statusAddresses,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machine, err := decodeMachine(tt.obj, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error returned. wantErr: %t. err: %v.", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if machine.Name != "machine1" {
+				t.Errorf("unexpected machine name: %s", machine.Name)
+			}
+			if machine.APIGroup() != "machine.openshift.io" {
+				t.Errorf("unexpected machine API group: %s", machine.APIGroup())
+			}
+			if machine.Status.Phase != "Running" {
+				t.Errorf("unexpected machine phase: %s", machine.Status.Phase)
+			}
+			if !reflect.DeepEqual(machine.Status.Addresses, tt.wantAddresses) {
+				t.Errorf("unexpected addresses. want: %v, got: %v", tt.wantAddresses, machine.Status.Addresses)
+			}
+		})
+	}
+}
+
+func TestListMachinesInvalidProviderAddresses(t *testing.T) {
+	good := createUnstructuredMachine("machine.openshift.io/v1beta1", "good", "ns1", "10.0.0.1", "good")
+	good.Object["status"].(map[string]interface{})["providerStatus"] = map[string]interface{}{
+		"addresses": []interface{}{
+			map[string]interface{}{
+				"address": "192.168.0.1",
+				"type":    "InternalIP",
+			},
+		},
+	}
+	bad := createUnstructuredMachine("machine.openshift.io/v1beta1", "bad", "ns1", "10.0.0.2", "bad")
+	bad.Object["status"].(map[string]interface{})["providerStatus"] = map[string]interface{}{
+		"addresses": "192.168.0.2",
+	}
+
+	handler := MachineHandler{
+		Client:                fake.NewClientBuilder().WithObjects(good, bad).Build(),
+		Config:                &rest.Config{Transport: fakeMachineRoundTripper{}},
+		Ctx:                   context.TODO(),
+		ProviderAddressesPath: []string{"status", "providerStatus", "addresses"},
+	}
+	machines, err := handler.ListMachines(schema.GroupVersion{Group: "machine.openshift.io"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantAddresses := map[string][]corev1.NodeAddress{
+		"good": {
+			{Type: corev1.NodeInternalDNS, Address: "good"},
+			{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+			{Type: corev1.NodeInternalIP, Address: "192.168.0.1"},
+		},
+		"bad": {
+			{Type: corev1.NodeInternalDNS, Address: "bad"},
+			{Type: corev1.NodeInternalIP, Address: "10.0.0.2"},
+		},
+	}
+	if len(machines) != len(wantAddresses) {
+		t.Fatalf("expected %d machines, got %v", len(wantAddresses), machines)
+	}
+	for _, machine := range machines {
+		if !reflect.DeepEqual(machine.Status.Addresses, wantAddresses[machine.Name]) {
+			t.Errorf("unexpected addresses of machine %s. want: %v, got: %v", machine.Name, wantAddresses[machine.Name], machine.Status.Addresses)
+		}
+	}
+}
+
+func TestListMachinesInfrastructureRefAddresses(t *testing.T) {
+	newCAPIMachine := func(name, infraName string) *unstructured.Unstructured {
+		machine := createUnstructuredMachine("cluster.x-k8s.io/v1alpha4", name, "ns1", "10.0.0.1", name)
+		machine.Object["spec"].(map[string]interface{})["infrastructureRef"] = map[string]interface{}{
+			"apiVersion": "infrastructure.cluster.x-k8s.io/v1alpha4",
+			"kind":       "AWSMachine",
+			"name":       infraName,
+		}
+		return machine
+	}
+	newInfraMachine := func(name, namespace string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "infrastructure.cluster.x-k8s.io/v1alpha4",
+				"kind":       "AWSMachine",
+				"metadata": map[string]interface{}{
+					"name":      name,
+					"namespace": namespace,
+				},
+				"status": map[string]interface{}{
+					"addresses": []interface{}{
+						map[string]interface{}{
+							"address": "10.0.0.1",
+							"type":    "InternalIP",
+						},
+						map[string]interface{}{
+							"address": "ip-10-0-0-1.ec2.internal",
+							"type":    "InternalDNS",
+						},
+					},
+				},
+			},
+		}
+	}
+	// The infrastructure machine must be in the namespace of the machine
+	otherNamespaceMachine := newCAPIMachine("machine4", "aws-machine4")
+	otherNamespaceMachine.Object["spec"].(map[string]interface{})["infrastructureRef"].(map[string]interface{})["namespace"] = "ns2"
+	cl := fake.NewClientBuilder().WithObjects(
+		newCAPIMachine("machine1", "aws-machine1"),
+		newCAPIMachine("machine2", "missing-aws-machine"),
+		newCAPIMachine("machine3", "forbidden-aws-machine"),
+		otherNamespaceMachine,
+		newInfraMachine("aws-machine1", "ns1"),
+		newInfraMachine("forbidden-aws-machine", "ns1"),
+		newInfraMachine("aws-machine4", "ns2"),
+	).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if key.Name == "forbidden-aws-machine" {
+				return k8serror.NewForbidden(schema.GroupResource{Group: "infrastructure.cluster.x-k8s.io", Resource: "awsmachines"}, key.Name, errors.New("no RBAC"))
+			}
+			return c.Get(ctx, key, obj, opts...)
+		},
+	}).Build()
+
+	statusAddresses := func(name string) []corev1.NodeAddress {
+		return []corev1.NodeAddress{
+			{Type: corev1.NodeInternalDNS, Address: name},
+			{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+		}
+	}
+
+	tests := []struct {
+		name                       string
+		infrastructureRefAddresses bool
+		wantAddresses              map[string][]corev1.NodeAddress
+	}{
+		{
+			name: "disabled",
+			wantAddresses: map[string][]corev1.NodeAddress{
+				"machine1": statusAddresses("machine1"),
+				"machine2": statusAddresses("machine2"),
+				"machine3": statusAddresses("machine3"),
+				"machine4": statusAddresses("machine4"),
+			},
+		},
+		{
+			name:                       "enabled",
+			infrastructureRefAddresses: true,
+			wantAddresses: map[string][]corev1.NodeAddress{
+				"machine1": append(statusAddresses("machine1"),
+					corev1.NodeAddress{Type: corev1.NodeInternalDNS, Address: "ip-10-0-0-1.ec2.internal"},
+				),
+				"machine2": statusAddresses("machine2"),
+				"machine3": statusAddresses("machine3"),
+				"machine4": statusAddresses("machine4"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := MachineHandler{
+				Client:                     cl,
+				Config:                     &rest.Config{Transport: fakeMachineRoundTripper{}},
+				Ctx:                        context.TODO(),
+				InfrastructureRefAddresses: tt.infrastructureRefAddresses,
+			}
+			machines, err := handler.ListMachines(schema.GroupVersion{Group: "cluster.x-k8s.io"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(machines) != len(tt.wantAddresses) {
+				t.Fatalf("expected %d machines, got %v", len(tt.wantAddresses), machines)
+			}
+			for _, machine := range machines {
+				if !reflect.DeepEqual(machine.Status.Addresses, tt.wantAddresses[machine.Name]) {
+					t.Errorf("unexpected addresses of machine %s. want: %v, got: %v", machine.Name, tt.wantAddresses[machine.Name], machine.Status.Addresses)
+				}
+			}
+		})
+	}
+}
+
+func TestFindMatchingMachine(t *testing.T) {
+	newMachine := func(namespace, name, nodeName, internalDNS string) Machine {
+		machine := Machine{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Status: MachineStatus{
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalDNS, Address: internalDNS}},
+			},
+		}
+		if nodeName != "" {
+			machine.Status.NodeRef = &corev1.ObjectReference{Name: nodeName}
+		}
+		return machine
+	}
+
+	tests := []struct {
+		name            string
+		machines        []Machine
+		nodeName        string
+		wantNodeRef     string
+		wantNodeRefErr  error
+		wantInternalDNS string
+		wantDNSErr      error
+	}{
+		{
+			name: "single match",
+			machines: []Machine{
+				newMachine("ns1", "machine1", "node1", "node1.internal"),
+				newMachine("ns1", "machine2", "node2", "node2.internal"),
+			},
+			nodeName:    "node1",
+			wantNodeRef: "machine1",
+			wantDNSErr:  errNotFound,
+		},
+		{
+			name: "single match by internal DNS",
+			machines: []Machine{
+				newMachine("ns1", "machine1", "", "node1"),
+				newMachine("ns1", "machine2", "node2", "node2"),
+			},
+			nodeName:        "node1",
+			wantNodeRefErr:  errNotFound,
+			wantInternalDNS: "machine1",
+		},
+		{
+			name: "upper case internal DNS",
+			machines: []Machine{
+				newMachine("ns1", "machine1", "", "NODE1.EC2.Internal"),
+				newMachine("ns1", "machine2", "", "node2.ec2.internal"),
+			},
+			nodeName:        "node1.ec2.internal",
+			wantNodeRefErr:  errNotFound,
+			wantInternalDNS: "machine1",
+		},
+		{
+			name: "upper case node name",
+			machines: []Machine{
+				newMachine("ns1", "machine1", "", "node1.ec2.internal."),
+				newMachine("ns1", "machine2", "", "node2.ec2.internal."),
+			},
+			nodeName:        "Node1.EC2.internal",
+			wantNodeRefErr:  errNotFound,
+			wantInternalDNS: "machine1",
+		},
+		{
+			name:           "no match",
+			machines:       []Machine{newMachine("ns1", "machine1", "node1", "node1")},
+			nodeName:       "node2",
+			wantNodeRefErr: errNotFound,
+			wantDNSErr:     errNotFound,
+		},
+		{
+			name: "duplicate names across namespaces",
+			machines: []Machine{
+				newMachine("ns1", "machine1", "node1", "node1"),
+				newMachine("ns2", "machine1", "node1", "node1"),
+			},
+			nodeName:       "node1",
+			wantNodeRefErr: ErrAmbiguousMachineMatch,
+			wantDNSErr:     ErrAmbiguousMachineMatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machine, err := FindMatchingMachineFromNodeRef(tt.machines, tt.nodeName)
+			checkMatchingMachine(t, machine, err, tt.wantNodeRef, tt.wantNodeRefErr)
+
+			machine, err = FindMatchingMachineFromInternalDNS(tt.machines, tt.nodeName)
+			checkMatchingMachine(t, machine, err, tt.wantInternalDNS, tt.wantDNSErr)
+		})
+	}
+}
+
+func TestFindMatchingMachineFromInternalDNSWithDomainSuffix(t *testing.T) {
+	newMachine := func(name, internalDNS string) Machine {
+		return Machine{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: name},
+			Status: MachineStatus{
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalDNS, Address: internalDNS}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		machines     []Machine
+		nodeName     string
+		domainSuffix string
+		want         string
+		wantErr      error
+	}{
+		{
+			name:     "FQDN node name without suffix",
+			machines: []Machine{newMachine("machine1", "node1")},
+			nodeName: "node1.example.com",
+			wantErr:  errNotFound,
+		},
+		{
+			name:         "FQDN node name with suffix",
+			machines:     []Machine{newMachine("machine1", "node1")},
+			nodeName:     "node1.example.com",
+			domainSuffix: "example.com",
+			want:         "machine1",
+		},
+		{
+			name:     "short node name without suffix",
+			machines: []Machine{newMachine("machine1", "node1.example.com.")},
+			nodeName: "node1",
+			wantErr:  errNotFound,
+		},
+		{
+			name:         "short node name with suffix",
+			machines:     []Machine{newMachine("machine1", "node1.example.com.")},
+			nodeName:     "node1",
+			domainSuffix: ".Example.com",
+			want:         "machine1",
+		},
+		{
+			name:         "same names with suffix",
+			machines:     []Machine{newMachine("machine1", "node1.example.com")},
+			nodeName:     "node1.example.com",
+			domainSuffix: "example.com",
+			want:         "machine1",
+		},
+		{
+			name:         "other domain with suffix",
+			machines:     []Machine{newMachine("machine1", "node1")},
+			nodeName:     "node1.example.org",
+			domainSuffix: "example.com",
+			wantErr:      errNotFound,
+		},
+		{
+			name: "short and FQDN machines with suffix",
+			machines: []Machine{
+				newMachine("machine1", "node1"),
+				newMachine("machine2", "node1.example.com"),
+			},
+			nodeName:     "node1",
+			domainSuffix: "example.com",
+			wantErr:      ErrAmbiguousMachineMatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machine, err := FindMatchingMachineFromInternalDNSWithDomainSuffix(tt.machines, tt.nodeName, tt.domainSuffix)
+			checkMatchingMachine(t, machine, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestFindMatchingMachineFromProviderID(t *testing.T) {
+	newMachine := func(namespace, name, providerID string) Machine {
+		machine := Machine{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		}
+		if providerID != "" {
+			machine.Spec.ProviderID = &providerID
+		}
+		return machine
+	}
+
+	tests := []struct {
+		name       string
+		machines   []Machine
+		providerID string
+		want       string
+		wantErr    error
+	}{
+		{
+			name: "single match",
+			machines: []Machine{
+				newMachine("ns1", "machine1", "aws:///us-east-1a/i-1"),
+				newMachine("ns1", "machine2", "aws:///us-east-1a/i-2"),
+				newMachine("ns1", "machine3", ""),
+			},
+			providerID: "aws:///us-east-1a/i-2",
+			want:       "machine2",
+		},
+		{
+			name:       "no match",
+			machines:   []Machine{newMachine("ns1", "machine1", "aws:///us-east-1a/i-1")},
+			providerID: "aws:///us-east-1a/i-2",
+			wantErr:    errNotFound,
+		},
+		{
+			name:     "node without provider ID",
+			machines: []Machine{newMachine("ns1", "machine1", "")},
+			wantErr:  errNotFound,
+		},
+		{
+			name: "duplicate provider IDs",
+			machines: []Machine{
+				newMachine("ns1", "machine1", "aws:///us-east-1a/i-1"),
+				newMachine("ns2", "machine1", "aws:///us-east-1a/i-1"),
+			},
+			providerID: "aws:///us-east-1a/i-1",
+			wantErr:    ErrAmbiguousMachineMatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machine, err := FindMatchingMachineFromProviderID(tt.machines, tt.providerID)
+			checkMatchingMachine(t, machine, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestFindMatchingMachineFromLabel(t *testing.T) {
+	newMachine := func(namespace, name, nodeName string) Machine {
+		machine := Machine{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		}
+		if nodeName != "" {
+			machine.Labels = map[string]string{"node-name": nodeName}
+		}
+		return machine
+	}
+
+	tests := []struct {
+		name     string
+		machines []Machine
+		labelKey string
+		nodeName string
+		want     string
+		wantErr  error
+	}{
+		{
+			name: "single match",
+			machines: []Machine{
+				newMachine("ns1", "machine1", "node1"),
+				newMachine("ns1", "machine2", "node2"),
+				newMachine("ns1", "machine3", ""),
+			},
+			labelKey: "node-name",
+			nodeName: "node2",
+			want:     "machine2",
+		},
+		{
+			name:     "no match",
+			machines: []Machine{newMachine("ns1", "machine1", "node1")},
+			labelKey: "node-name",
+			nodeName: "node2",
+			wantErr:  errNotFound,
+		},
+		{
+			name:     "other label key",
+			machines: []Machine{newMachine("ns1", "machine1", "node1")},
+			labelKey: "example.com/node",
+			nodeName: "node1",
+			wantErr:  errNotFound,
+		},
+		{
+			name:     "no label key",
+			machines: []Machine{newMachine("ns1", "machine1", "node1")},
+			nodeName: "node1",
+			wantErr:  errNotFound,
+		},
+		{
+			name: "duplicate labels",
+			machines: []Machine{
+				newMachine("ns1", "machine1", "node1"),
+				newMachine("ns2", "machine1", "node1"),
+			},
+			labelKey: "node-name",
+			nodeName: "node1",
+			wantErr:  ErrAmbiguousMachineMatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machine, err := FindMatchingMachineFromLabel(tt.machines, tt.labelKey, tt.nodeName)
+			checkMatchingMachine(t, machine, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+// errNotFound marks test cases where no machine is expected to match
+var errNotFound = errors.New("not found")
+
+func checkMatchingMachine(t *testing.T, machine *Machine, err error, wantName string, wantErr error) {
+	t.Helper()
+	switch {
+	case wantErr == errNotFound:
+		if err == nil || errors.Is(err, ErrAmbiguousMachineMatch) {
+			t.Errorf("expected not found error, got machine: %v, err: %v", machine, err)
+		}
+	case wantErr != nil:
+		if !errors.Is(err, wantErr) {
+			t.Errorf("expected error %v, got machine: %v, err: %v", wantErr, machine, err)
+		}
+	case err != nil:
+		t.Errorf("unexpected error: %v", err)
+	case machine.Name != wantName:
+		t.Errorf("expected machine %s, got %s", wantName, machine.Name)
+	}
+}
+
+// fakeServerGroups is a fake discovery client serving the given API groups
+type fakeServerGroups struct {
+	groups []metav1.APIGroup
+}
+
+func (f fakeServerGroups) ServerGroups() (*metav1.APIGroupList, error) {
+	return &metav1.APIGroupList{Groups: f.groups}, nil
+}
+
+func TestResolveAPIGroupVersion(t *testing.T) {
+	discoveryClient := fakeServerGroups{
+		groups: []metav1.APIGroup{{
+			Name: "cluster.x-k8s.io",
+			Versions: []metav1.GroupVersionForDiscovery{
+				{GroupVersion: "cluster.x-k8s.io/v1beta1", Version: "v1beta1"},
+				{GroupVersion: "cluster.x-k8s.io/v1alpha4", Version: "v1alpha4"},
+			},
+			PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "cluster.x-k8s.io/v1beta1", Version: "v1beta1"},
+		}},
+	}
+
+	tests := []struct {
+		name            string
+		apiGroupVersion schema.GroupVersion
+		want            schema.GroupVersion
+		wantErr         error
+	}{
+		{
+			name:            "resolves preferred version",
+			apiGroupVersion: schema.GroupVersion{Group: "cluster.x-k8s.io"},
+			want:            schema.GroupVersion{Group: "cluster.x-k8s.io", Version: "v1beta1"},
+		},
+		{
+			name:            "keeps served version",
+			apiGroupVersion: schema.GroupVersion{Group: "cluster.x-k8s.io", Version: "v1alpha4"},
+			want:            schema.GroupVersion{Group: "cluster.x-k8s.io", Version: "v1alpha4"},
+		},
+		{
+			name:            "invalid version",
+			apiGroupVersion: schema.GroupVersion{Group: "cluster.x-k8s.io", Version: "v1alpha1"},
+			wantErr:         ErrApiVersionNotServed,
+		},
+		{
+			name:            "group not found",
+			apiGroupVersion: schema.GroupVersion{Group: "machine.openshift.io"},
+			wantErr:         ErrApiGroupNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveAPIGroupVersion(discoveryClient, tt.apiGroupVersion)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
 			}
 		})
 	}
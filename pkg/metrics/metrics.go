@@ -1,10 +1,12 @@
 package metrics
 
 import (
+	"net/http"
 	"sync/atomic"
 
 	"github.com/openshift/cluster-machine-approver/pkg/controller"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
@@ -12,15 +14,62 @@ import (
 // defaultMetricsPort is the default port to expose metrics.
 const DefaultMetricsPort = "127.0.0.1:9191"
 
+// OpenMetricsPath is the path the metrics are served on with their exemplars,
+// when the OpenMetrics format is requested.
+const OpenMetricsPath = "/metrics/openmetrics"
+
 var (
 	// CurrentPendingCSRCountDesc is a metric to report count of pending node CSRs in the cluster
 	CurrentPendingCSRCountDesc = prometheus.NewDesc("mapi_current_pending_csr", "Count of recently pending node CSRs at the cluster level", nil, nil)
 	// MaxPendingCSRDesc is a metric to report threshold value of the pending node CSRs beyond which all CSR will be ignored by machine approver
 	MaxPendingCSRDesc = prometheus.NewDesc("mapi_max_pending_csr", "Threshold value of the pending node CSRs beyond which all CSR will be ignored by machine approver", nil, nil)
+	// OldestPendingCSRAgeDesc is a metric to report the age of the oldest recently pending node CSR
+	OldestPendingCSRAgeDesc = prometheus.NewDesc("machineapprover_oldest_pending_csr_age_seconds", "Age in seconds of the oldest recently pending node CSR, 0 when there are none", nil, nil)
+	// MachinesTotalDesc is a metric to report the count of machines seen in the last reconcile
+	MachinesTotalDesc = prometheus.NewDesc("machineapprover_machines_total", "Count of machines seen by the machine approver in the last reconcile", nil, nil)
+	// MachinesWithoutNodeRefDesc is a metric to report the count of machines without a node ref seen in the last reconcile
+	MachinesWithoutNodeRefDesc = prometheus.NewDesc("machineapprover_machines_without_noderef", "Count of machines without a node ref seen by the machine approver in the last reconcile", nil, nil)
+	// NodesTotalDesc is a metric to report the count of nodes seen in the last reconcile
+	NodesTotalDesc = prometheus.NewDesc("machineapprover_nodes_total", "Count of nodes seen by the machine approver in the last reconcile", nil, nil)
+	// ExternallyApprovedCSRsDesc is a metric to report the count of recently approved CSRs approved by another approver
+	ExternallyApprovedCSRsDesc = prometheus.NewDesc("machineapprover_externally_approved_total", "Count of recently approved CSRs that were approved by another approver", nil, nil)
+	// AmbiguousUsageCSRsDesc is a metric to report the count of CSRs rejected for requesting both client and server auth usages
+	AmbiguousUsageCSRsDesc = prometheus.NewDesc("machineapprover_ambiguous_usage_csrs_total", "Count of CSRs rejected for requesting both client auth and server auth usages", nil, nil)
+	// FreshIssuanceWhenRenewalPossibleDesc is a metric to report the count of serving CSRs authorized by the machine-api although the node had a valid serving cert
+	FreshIssuanceWhenRenewalPossibleDesc = prometheus.NewDesc("machineapprover_fresh_issuance_when_renewal_possible_total", "Count of serving CSRs authorized by the machine-api although the node presented a valid serving cert it could have renewed", nil, nil)
+	// SuppressedCSRsDesc is a metric to report the count of CSR reconciles suppressed by the pending CSRs limit
+	SuppressedCSRsDesc = prometheus.NewDesc("machineapprover_suppressed_csrs_total", "Count of CSR reconciles suppressed because too many CSRs were pending", nil, nil)
+	// UnparseableCSRsDesc is a metric to report the count of CSRs which could not be parsed
+	UnparseableCSRsDesc = prometheus.NewDesc("machineapprover_unparseable_csr_total", "Count of CSRs which could not be parsed, and are never approved", nil, nil)
+	// KubeletConnectFailuresDesc is a metric to report failures to retrieve the serving cert of a kubelet, by category
+	KubeletConnectFailuresDesc = prometheus.NewDesc("machineapprover_kubelet_connect_failures_total", "Count of failures to retrieve the serving cert of a kubelet, by category", []string{"category"}, nil)
+	// LeaderDesc is a metric to report whether this approver is the active leader
+	LeaderDesc = prometheus.NewDesc("machineapprover_leader", "Whether this approver is the active leader, 1 on the leader and 0 on standbys", []string{"pod"}, nil)
+)
+
+var (
+	// Leader is 1 while this approver is the elected leader, 0 otherwise.
+	Leader uint32
+	// LeaderPod is the pod name LeaderDesc is reported for. It must be set
+	// before metrics are collected.
+	LeaderPod string
 )
 
 func init() {
 	metrics.Registry.MustRegister(&MetricsCollector{})
+	metrics.Registry.MustRegister(controller.ReconcileDuration)
+	metrics.Registry.MustRegister(controller.CSRDecisions)
+	metrics.Registry.MustRegister(controller.ClientCSRMachineAge)
+}
+
+// OpenMetricsHandler returns a handler serving the registered metrics like the
+// metrics endpoint, but with exemplars when the OpenMetrics format is
+// requested, as the metrics endpoint never serves them.
+func OpenMetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{
+		ErrorHandling:     promhttp.HTTPErrorOnError,
+		EnableOpenMetrics: true,
+	})
 }
 
 // MetricsCollector is implementing prometheus.Collector interface.
@@ -35,11 +84,35 @@ func (mc *MetricsCollector) Collect(ch chan<- prometheus.Metric) {
 func (mc MetricsCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- CurrentPendingCSRCountDesc
 	ch <- MaxPendingCSRDesc
+	ch <- OldestPendingCSRAgeDesc
+	ch <- MachinesTotalDesc
+	ch <- MachinesWithoutNodeRefDesc
+	ch <- NodesTotalDesc
+	ch <- SuppressedCSRsDesc
+	ch <- ExternallyApprovedCSRsDesc
+	ch <- AmbiguousUsageCSRsDesc
+	ch <- FreshIssuanceWhenRenewalPossibleDesc
+	ch <- UnparseableCSRsDesc
+	ch <- KubeletConnectFailuresDesc
+	ch <- LeaderDesc
 }
 
 // Collect implements the prometheus.Collector interface.
 func (mc MetricsCollector) collectMetrics(ch chan<- prometheus.Metric) {
 	ch <- prometheus.MustNewConstMetric(CurrentPendingCSRCountDesc, prometheus.GaugeValue, float64(atomic.LoadUint32(&controller.PendingCSRs)))
 	ch <- prometheus.MustNewConstMetric(MaxPendingCSRDesc, prometheus.GaugeValue, float64(atomic.LoadUint32(&controller.MaxPendingCSRs)))
+	ch <- prometheus.MustNewConstMetric(OldestPendingCSRAgeDesc, prometheus.GaugeValue, float64(atomic.LoadUint32(&controller.OldestPendingCSRAgeSeconds)))
+	ch <- prometheus.MustNewConstMetric(MachinesTotalDesc, prometheus.GaugeValue, float64(atomic.LoadUint32(&controller.MachinesCount)))
+	ch <- prometheus.MustNewConstMetric(MachinesWithoutNodeRefDesc, prometheus.GaugeValue, float64(atomic.LoadUint32(&controller.MachinesWithoutNodeRefCount)))
+	ch <- prometheus.MustNewConstMetric(NodesTotalDesc, prometheus.GaugeValue, float64(atomic.LoadUint32(&controller.NodesCount)))
+	ch <- prometheus.MustNewConstMetric(SuppressedCSRsDesc, prometheus.CounterValue, float64(atomic.LoadUint32(&controller.SuppressedCSRs)))
+	ch <- prometheus.MustNewConstMetric(ExternallyApprovedCSRsDesc, prometheus.CounterValue, float64(atomic.LoadUint32(&controller.ExternallyApprovedCSRs)))
+	ch <- prometheus.MustNewConstMetric(AmbiguousUsageCSRsDesc, prometheus.CounterValue, float64(atomic.LoadUint32(&controller.AmbiguousUsageCSRs)))
+	ch <- prometheus.MustNewConstMetric(FreshIssuanceWhenRenewalPossibleDesc, prometheus.CounterValue, float64(atomic.LoadUint32(&controller.FreshIssuanceWhenRenewalPossible)))
+	ch <- prometheus.MustNewConstMetric(UnparseableCSRsDesc, prometheus.CounterValue, float64(atomic.LoadUint32(&controller.UnparseableCSRs)))
+	for category, count := range controller.KubeletConnectFailures {
+		ch <- prometheus.MustNewConstMetric(KubeletConnectFailuresDesc, prometheus.CounterValue, float64(atomic.LoadUint32(count)), category)
+	}
+	ch <- prometheus.MustNewConstMetric(LeaderDesc, prometheus.GaugeValue, float64(atomic.LoadUint32(&Leader)), LeaderPod)
 	klog.V(4).Infof("collectMetrics exit")
 }
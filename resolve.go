@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	goflag "flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/openshift/cluster-machine-approver/pkg/controller"
+	"github.com/openshift/cluster-machine-approver/pkg/machinehandler"
+	flag "github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// resolveNodeCommand is the subcommand printing the machines matching a node name
+const resolveNodeCommand = "resolve-node"
+
+// runResolveNode lists the machines as the controller does and prints which
+// of them match a node name, by node ref and by internal DNS. It returns the
+// exit code.
+func runResolveNode(args []string) int {
+	var cliConfig string
+	var apiGroupVersions []string
+	var managementKubeConfigPath string
+	var machineNamespaces []string
+	var timeout time.Duration
+
+	flagSet := flag.NewFlagSet("cluster-machine-approver resolve-node", flag.ExitOnError)
+	flagSet.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: cluster-machine-approver %s <node name> [flags]\n", resolveNodeCommand)
+		flagSet.PrintDefaults()
+	}
+
+	klogFlags := goflag.NewFlagSet("klog", goflag.ExitOnError)
+	klog.InitFlags(klogFlags)
+	flagSet.AddGoFlagSet(klogFlags)
+
+	flagSet.StringVar(&cliConfig, "config", "", "CLI config")
+	flagSet.StringSliceVar(&apiGroupVersions, "api-group-version", []string{mapiGroup}, "API group and version for machines in format '<group>/<version' or just '<group>'. This option can be given multiple times.")
+	flagSet.StringVar(&managementKubeConfigPath, "management-cluster-kubeconfig", "", "management kubeconfig path,")
+	flagSet.StringSliceVar(&machineNamespaces, "machine-namespace", nil, "restrict machines to specific namespaces, given as a comma-separated list or multiple times")
+	flagSet.DurationVar(&timeout, "timeout", time.Minute, "maximum duration to list machines")
+
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 || flagSet.Arg(0) == "" {
+		flagSet.Usage()
+		return 2
+	}
+	nodeName := flagSet.Arg(0)
+
+	var parsedAPIGroupVersions []schema.GroupVersion
+	for _, apiGroupVersion := range apiGroupVersions {
+		parsedAPIGroupVersion, err := parseGroupVersion(apiGroupVersion)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid API Group Version value: %s\n", apiGroupVersion)
+			return 2
+		}
+		if err := validateAPIGroup(parsedAPIGroupVersion.Group); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		parsedAPIGroupVersions = append(parsedAPIGroupVersions, parsedAPIGroupVersion)
+	}
+
+	// Only machines are listed, the workload cluster is not needed
+	managementConfig, err := clientcmd.BuildConfigFromFlags("", managementKubeConfigPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't set client config: %v\n", err)
+		return 1
+	}
+
+	managementClient, err := client.New(managementConfig, client.Options{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create client: %v\n", err)
+		return 1
+	}
+
+	config := controller.LoadConfig(cliConfig)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	machineHandler := &machinehandler.MachineHandler{
+		Client:                     managementClient,
+		Config:                     managementConfig,
+		Ctx:                        ctx,
+		Namespaces:                 parseMachineNamespaces(machineNamespaces),
+		ProviderAddressesPath:      config.MachineAddresses.ProviderAddressesFields(),
+		InfrastructureRefAddresses: config.MachineAddresses.InfrastructureRefAddresses,
+	}
+
+	var machines []machinehandler.Machine
+	for _, apiGroupVersion := range parsedAPIGroupVersions {
+		newMachines, err := machineHandler.ListMachines(apiGroupVersion)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list machines in API group %v: %v\n", apiGroupVersion, err)
+			return 1
+		}
+		machines = append(machines, newMachines...)
+	}
+
+	if !printResolvedMachines(os.Stdout, machines, nodeName, config.NodeClientCert.NodeNameDomainSuffix) {
+		return 1
+	}
+	return 0
+}
+
+// printResolvedMachines prints the machine matching the node name by node ref
+// and by internal DNS, the way client CSRs are authorized, or why there is
+// none. It returns whether any machine matched.
+func printResolvedMachines(w io.Writer, machines []machinehandler.Machine, nodeName, domainSuffix string) bool {
+	fmt.Fprintf(w, "Node %s, %d machines listed:\n", nodeName, len(machines))
+
+	byNodeRef, nodeRefErr := machinehandler.FindMatchingMachineFromNodeRef(machines, nodeName)
+	printResolvedMachine(w, "node ref", byNodeRef, nodeRefErr)
+
+	byInternalDNS, internalDNSErr := machinehandler.FindMatchingMachineFromInternalDNSWithDomainSuffix(machines, nodeName, domainSuffix)
+	printResolvedMachine(w, "internal DNS", byInternalDNS, internalDNSErr)
+
+	return byNodeRef != nil || byInternalDNS != nil
+}
+
+// printResolvedMachine prints a single match of printResolvedMachines.
+func printResolvedMachine(w io.Writer, by string, machine *machinehandler.Machine, err error) {
+	if err != nil {
+		fmt.Fprintf(w, "  by %s: %v\n", by, err)
+		return
+	}
+	fmt.Fprintf(w, "  by %s: %s/%s (%s)\n", by, machine.Namespace, machine.Name, machine.APIGroup())
+}
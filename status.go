@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync/atomic"
 	"time"
 
 	osconfigv1 "github.com/openshift/api/config/v1"
 	osclientset "github.com/openshift/client-go/config/clientset/versioned"
 	osv1client "github.com/openshift/client-go/config/clientset/versioned/typed/config/v1"
+	"github.com/openshift/cluster-machine-approver/pkg/controller"
 	"github.com/openshift/library-go/pkg/config/clusteroperator/v1helpers"
 	"github.com/openshift/library-go/pkg/operator/status"
 	v1 "k8s.io/api/core/v1"
@@ -32,6 +34,14 @@ const (
 	operatorVersionKey            = "operator"
 	reasonAsExpected              = "AsExpected"
 	releaseVersionEnvVariableName = "RELEASE_VERSION"
+	// statusSummaryWindow is the window approvals are summarized over in the status.
+	statusSummaryWindow = time.Hour
+	// statusResyncInterval is how often the status summary is refreshed.
+	statusResyncInterval = time.Minute
+	// defaultPendingLimitDegradedAfter is how long the pending CSRs limit can
+	// be exceeded before the operator is reported Degraded.
+	defaultPendingLimitDegradedAfter = 10 * time.Minute
+	reasonPendingCSRsLimitExceeded   = "PendingCSRsLimitExceeded"
 )
 
 var relatedObjects = []osconfigv1.ObjectReference{
@@ -53,6 +63,37 @@ type statusController struct {
 	versionGetter           status.VersionGetter
 	versionCh               <-chan struct{}
 	clusterOperatorInformer cache.Controller
+	// pendingLimitDegradedAfter is how long the pending CSRs limit can be
+	// exceeded, suppressing all approvals, before reporting Degraded.
+	pendingLimitDegradedAfter time.Duration
+
+	// approvals and pendingLimitExceededSince are only used by the worker
+	// syncing the single queue key.
+	approvals                 approvalHistory
+	pendingLimitExceededSince time.Time
+}
+
+// approvalSample is the count of approved CSRs at a point in time.
+type approvalSample struct {
+	time     time.Time
+	approved uint32
+}
+
+// approvalHistory keeps samples of the count of approved CSRs, to tell how
+// many CSRs were approved within the summary window.
+type approvalHistory struct {
+	samples []approvalSample
+}
+
+// record adds a sample of the count of approved CSRs, and returns how many
+// CSRs were approved since the newest sample at least a window old, or since
+// the oldest sample.
+func (h *approvalHistory) record(now time.Time, approved uint32) uint32 {
+	h.samples = append(h.samples, approvalSample{time: now, approved: approved})
+	for len(h.samples) > 1 && now.Sub(h.samples[1].time) >= statusSummaryWindow {
+		h.samples = h.samples[1:]
+	}
+	return approved - h.samples[0].approved
 }
 
 func NewStatusController(config *restclient.Config) *statusController {
@@ -75,13 +116,16 @@ func NewStatusController(config *restclient.Config) *statusController {
 		DeleteFunc: func(obj interface{}) { queue.Add(queueKey) },
 	}, cache.Indexers{})
 
-	return &statusController{
-		clusterOperators:        osClient.ConfigV1().ClusterOperators(),
-		queue:                   queue,
-		versionGetter:           versionGetter,
-		versionCh:               versionGetter.VersionChangedChannel(),
-		clusterOperatorInformer: informer,
+	c := &statusController{
+		clusterOperators:          osClient.ConfigV1().ClusterOperators(),
+		queue:                     queue,
+		versionGetter:             versionGetter,
+		versionCh:                 versionGetter.VersionChangedChannel(),
+		clusterOperatorInformer:   informer,
+		pendingLimitDegradedAfter: defaultPendingLimitDegradedAfter,
 	}
+	c.approvals.record(time.Now(), atomic.LoadUint32(&controller.ApprovedCSRs))
+	return c
 }
 
 func (c *statusController) runWorker() {
@@ -146,6 +190,10 @@ func (c *statusController) processNextItem() bool {
 
 	// Handle the error if something went wrong during the execution of the business logic
 	c.handleErr(err, key)
+	if err == nil {
+		// Keep the approver activity summary up to date
+		c.queue.AddAfter(key, statusResyncInterval)
+	}
 	return true
 }
 
@@ -175,28 +223,25 @@ func (c *statusController) handleErr(err error, key interface{}) {
 }
 
 // statusAvailable sets the Available condition to True, with the given reason
-// and message, and sets both the Progressing and Degraded conditions to False.
+// and message, and sets the Progressing condition to False. The Degraded
+// condition is only True while the pending CSRs limit has been exceeded for
+// too long.
 func (c *statusController) statusAvailable() error {
 	co, err := c.getOrCreateClusterOperator()
 	if err != nil {
 		return err
 	}
 
+	now := time.Now()
 	conds := []osconfigv1.ClusterOperatorStatusCondition{
 		{
 			Type:               osconfigv1.OperatorAvailable,
 			Status:             osconfigv1.ConditionTrue,
 			LastTransitionTime: metav1.Now(),
 			Reason:             reasonAsExpected,
-			Message:            fmt.Sprintf("Cluster Machine Approver is available at %s", c.versionGetter.GetVersions()["operator"]),
-		},
-		{
-			Type:               osconfigv1.OperatorDegraded,
-			Status:             osconfigv1.ConditionFalse,
-			LastTransitionTime: metav1.Now(),
-			Reason:             reasonAsExpected,
-			Message:            "",
+			Message:            fmt.Sprintf("Cluster Machine Approver is available at %s: %s", c.versionGetter.GetVersions()["operator"], c.activitySummary(now)),
 		},
+		c.degradedCondition(now),
 		{
 			Type:               osconfigv1.OperatorProgressing,
 			Status:             osconfigv1.ConditionFalse,
@@ -217,6 +262,45 @@ func (c *statusController) statusAvailable() error {
 	return c.syncStatus(co, conds)
 }
 
+// degradedCondition returns the Degraded condition, True once the pending CSRs
+// limit has been exceeded for longer than pendingLimitDegradedAfter, as no CSR
+// is approved meanwhile.
+func (c *statusController) degradedCondition(now time.Time) osconfigv1.ClusterOperatorStatusCondition {
+	condition := osconfigv1.ClusterOperatorStatusCondition{
+		Type:               osconfigv1.OperatorDegraded,
+		Status:             osconfigv1.ConditionFalse,
+		LastTransitionTime: metav1.NewTime(now),
+		Reason:             reasonAsExpected,
+		Message:            "",
+	}
+
+	pending := atomic.LoadUint32(&controller.PendingCSRs)
+	maxPending := atomic.LoadUint32(&controller.MaxPendingCSRs)
+	if pending <= maxPending {
+		c.pendingLimitExceededSince = time.Time{}
+		return condition
+	}
+
+	if c.pendingLimitExceededSince.IsZero() {
+		c.pendingLimitExceededSince = now
+	}
+	if now.Sub(c.pendingLimitExceededSince) < c.pendingLimitDegradedAfter {
+		return condition
+	}
+
+	condition.Status = osconfigv1.ConditionTrue
+	condition.Reason = reasonPendingCSRsLimitExceeded
+	condition.Message = fmt.Sprintf("%d pending CSRs exceed the limit of %d since %s, no CSRs are approved", pending, maxPending, c.pendingLimitExceededSince.UTC().Format(time.RFC3339))
+	return condition
+}
+
+// activitySummary summarizes the approver activity from its metrics.
+func (c *statusController) activitySummary(now time.Time) string {
+	approved := c.approvals.record(now, atomic.LoadUint32(&controller.ApprovedCSRs))
+	pending := atomic.LoadUint32(&controller.PendingCSRs)
+	return fmt.Sprintf("approved %d CSRs in the last hour, %d pending", approved, pending)
+}
+
 func (c *statusController) getOrCreateClusterOperator() (*osconfigv1.ClusterOperator, error) {
 	var co *osconfigv1.ClusterOperator
 
@@ -19,12 +19,15 @@ package main
 import (
 	"context"
 	"os"
+	"sync/atomic"
+	"testing"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	osconfigv1 "github.com/openshift/api/config/v1"
 	osclientset "github.com/openshift/client-go/config/clientset/versioned"
+	"github.com/openshift/cluster-machine-approver/pkg/controller"
 	"github.com/openshift/library-go/pkg/config/clusteroperator/v1helpers"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -46,6 +49,9 @@ var _ = Describe("Cluster Operator status controller", func() {
 		osClient, err = osclientset.NewForConfig(cfg)
 		Expect(err).NotTo(HaveOccurred())
 
+		atomic.StoreUint32(&controller.PendingCSRs, 3)
+		atomic.StoreUint32(&controller.MaxPendingCSRs, 100)
+
 		stop = make(chan struct{})
 		statusController = NewStatusController(cfg)
 		go func() {
@@ -110,6 +116,7 @@ var _ = Describe("Cluster Operator status controller", func() {
 			// check conditions.
 			Expect(v1helpers.IsStatusConditionTrue(co.Status.Conditions, osconfigv1.OperatorAvailable)).To(BeTrue())
 			Expect(v1helpers.FindStatusCondition(co.Status.Conditions, osconfigv1.OperatorAvailable).Reason).To(Equal(reasonAsExpected))
+			Expect(v1helpers.FindStatusCondition(co.Status.Conditions, osconfigv1.OperatorAvailable).Message).To(ContainSubstring("approved 0 CSRs in the last hour, 3 pending"))
 			Expect(v1helpers.IsStatusConditionTrue(co.Status.Conditions, osconfigv1.OperatorUpgradeable)).To(BeTrue())
 			Expect(v1helpers.FindStatusCondition(co.Status.Conditions, osconfigv1.OperatorUpgradeable).Reason).To(Equal(reasonAsExpected))
 			Expect(v1helpers.IsStatusConditionFalse(co.Status.Conditions, osconfigv1.OperatorDegraded)).To(BeTrue())
@@ -183,3 +190,91 @@ var _ = Describe("Cluster Operator status controller", func() {
 		}),
 	)
 })
+
+func TestApprovalHistory(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := approvalHistory{}
+
+	steps := []struct {
+		elapsed  time.Duration
+		approved uint32
+		want     uint32
+	}{
+		{elapsed: 0, approved: 5, want: 0},
+		{elapsed: 10 * time.Minute, approved: 8, want: 3},
+		{elapsed: 50 * time.Minute, approved: 10, want: 5},
+		// The approvals before the last hour are no longer counted
+		{elapsed: 70 * time.Minute, approved: 11, want: 3},
+		{elapsed: 3 * time.Hour, approved: 11, want: 0},
+	}
+
+	for _, step := range steps {
+		if got := history.record(start.Add(step.elapsed), step.approved); got != step.want {
+			t.Errorf("after %v, expected %d approvals in the last hour, got %d", step.elapsed, step.want, got)
+		}
+	}
+}
+
+func TestDegradedCondition(t *testing.T) {
+	defer func(pending, maxPending uint32) {
+		atomic.StoreUint32(&controller.PendingCSRs, pending)
+		atomic.StoreUint32(&controller.MaxPendingCSRs, maxPending)
+	}(atomic.LoadUint32(&controller.PendingCSRs), atomic.LoadUint32(&controller.MaxPendingCSRs))
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &statusController{pendingLimitDegradedAfter: 10 * time.Minute}
+	atomic.StoreUint32(&controller.MaxPendingCSRs, 100)
+
+	steps := []struct {
+		name        string
+		elapsed     time.Duration
+		pending     uint32
+		wantStatus  osconfigv1.ConditionStatus
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name:       "below the limit",
+			pending:    50,
+			wantStatus: osconfigv1.ConditionFalse,
+			wantReason: reasonAsExpected,
+		},
+		{
+			name:       "limit just exceeded",
+			elapsed:    time.Minute,
+			pending:    101,
+			wantStatus: osconfigv1.ConditionFalse,
+			wantReason: reasonAsExpected,
+		},
+		{
+			name:        "limit exceeded for too long",
+			elapsed:     11 * time.Minute,
+			pending:     120,
+			wantStatus:  osconfigv1.ConditionTrue,
+			wantReason:  reasonPendingCSRsLimitExceeded,
+			wantMessage: "120 pending CSRs exceed the limit of 100 since 2024-01-01T00:01:00Z, no CSRs are approved",
+		},
+		{
+			name:       "cleared below the limit",
+			elapsed:    12 * time.Minute,
+			pending:    100,
+			wantStatus: osconfigv1.ConditionFalse,
+			wantReason: reasonAsExpected,
+		},
+		{
+			name:       "limit exceeded again",
+			elapsed:    20 * time.Minute,
+			pending:    101,
+			wantStatus: osconfigv1.ConditionFalse,
+			wantReason: reasonAsExpected,
+		},
+	}
+
+	for _, step := range steps {
+		atomic.StoreUint32(&controller.PendingCSRs, step.pending)
+		condition := c.degradedCondition(start.Add(step.elapsed))
+		if condition.Type != osconfigv1.OperatorDegraded || condition.Status != step.wantStatus || condition.Reason != step.wantReason || condition.Message != step.wantMessage {
+			t.Errorf("%s: expected Degraded=%s with reason %q and message %q, got %+v", step.name, step.wantStatus, step.wantReason, step.wantMessage, condition)
+		}
+	}
+}